@@ -1,9 +1,9 @@
 import { Component, OnInit } from '@angular/core';
-import { Accommodation, AmenityEnum } from '../model/accommodation';
+import { Accommodation, AccommodationStatus, AmenityDefinition } from '../model/accommodation';
 import { AccommodationService } from '../services/accommodation.service';
 import { Router } from '@angular/router';
 import { Image } from '../model/image';
-import { DomSanitizer, SafeResourceUrl } from '@angular/platform-browser';
+import { DomSanitizer } from '@angular/platform-browser';
 import { MatDialog } from '@angular/material/dialog';
 import { RatingsPopupComponent } from '../ratings/ratings-popup/ratings-popup.component';
 import { AuthService } from '../services/auth.service';
@@ -17,6 +17,7 @@ export class AccommodationDetailsComponent implements OnInit {
   accommodation: Accommodation | null = null;
   images: Image[] = [];
   role: string = "";
+  amenities: { [key: string]: AmenityDefinition } = {};
 
   constructor(
     private accommodationService: AccommodationService, 
@@ -28,6 +29,14 @@ export class AccommodationDetailsComponent implements OnInit {
 
   ngOnInit(): void {
     this.role = this.authService.getRoleFromTokenNoRedirect() || "";
+    this.accommodationService.getAmenityCatalog().subscribe(
+      (amenities: AmenityDefinition[]) => {
+        amenities.forEach(amenity => this.amenities[amenity.key] = amenity);
+      },
+      (error: Error) => {
+        console.log(error);
+      }
+    );
     this.accommodationService.getAccommodation().subscribe(
       data => {
         this.accommodation = data;
@@ -49,8 +58,13 @@ export class AccommodationDetailsComponent implements OnInit {
     );
   }
 
-  getAmenityName(amenity: number): string {
-    return AmenityEnum[amenity];
+  getAmenityName(amenity: string): string {
+    return this.amenities[amenity]?.label || amenity;
+  }
+
+  getAmenityIcon(amenity: string): string {
+    const icon = this.amenities[amenity]?.icon;
+    return icon ? "../../assets/images/" + icon : "";
   }
 
   navigateToUpdateAccommodation(id: string): void{
@@ -63,6 +77,17 @@ export class AccommodationDetailsComponent implements OnInit {
     this.router.navigateByUrl('/update-accommodation');
   }
 
+  changeStatus(id: string, status: AccommodationStatus): void {
+    this.accommodationService.changeAccommodationStatus(id, status).subscribe(
+      (accommodation: Accommodation) => {
+        this.accommodation = accommodation;
+      },
+      error => {
+        console.error('Error while changing accommodation status:', error);
+      }
+    );
+  }
+
   navigateToDeleteAccommodation(id: string): void {
     this.accommodationService.deleteAccommodation(id).subscribe(
       () => {
@@ -75,48 +100,6 @@ export class AccommodationDetailsComponent implements OnInit {
     );
   }
 
-  amenityIcons: { [key: number]: string } = {
-    0: "../../assets/images/essentials.png",
-    1: "../../assets/images/wifi.png",
-    2: "../../assets/images/parking.png",
-    3: "../../assets/images/air-condition.png",
-    4: "../../assets/images/kitchen.png",
-    5: "../../assets/images/tv.png",
-    6: "../../assets/images/pool.png",
-    7: "../../assets/images/petFriendly.png",
-    8: "../../assets/images/hairDryer.png",
-    9: "../../assets/images/iron.png",
-    10: "../../assets/images/indoorFireplace.png",
-    11: "../../assets/images/heating.png",
-    12: "../../assets/images/washer.png",
-    13: "../../assets/images/hanger.png",
-    14: "../../assets/images/hotWater.png",
-    15: "../../assets/images/privateBathroom.png",
-    16: "../../assets/images/gym.png",
-    17: "../../assets/images/smokingAllowed.png"
-  };
-
-  getSafeImage(base64Image: string): SafeResourceUrl {
-    // Determine the image type based on the content
-    const isPng = base64Image.startsWith('/9j/') || base64Image.startsWith('iVBOR');
-    const isJpeg = base64Image.startsWith('/8A') || base64Image.startsWith('/9A') || base64Image.startsWith('R0lGOD');
-  
-    let imageType: string;
-    if (isPng) {
-      imageType = 'image/png';
-    } else if (isJpeg) {
-      imageType = 'image/jpeg';
-    } else {
-      imageType = 'image/png'; // Default to PNG if neither PNG nor JPEG is detected
-    }
-  
-    // Construct the data URL with the detected image type
-    const imageUrl = `data:${imageType};base64,${base64Image}`;
-  
-    // Return the sanitized URL
-    return this.sanitizer.bypassSecurityTrustResourceUrl(imageUrl);
-  }
-
   showHostRatings(hostId: string): void {
     const dialogRef = this.dialog.open(RatingsPopupComponent, {
       width: '600px',
@@ -1,6 +1,6 @@
 import { Component, OnDestroy, OnInit } from '@angular/core';
 import { AccommodationService } from 'src/app/services/accommodation.service';
-import { Accommodation, AmenityEnum } from 'src/app/model/accommodation';
+import { Accommodation } from 'src/app/model/accommodation';
 import { Router } from '@angular/router';
 import { Subscription } from 'rxjs';
 import { AuthService } from '../services/auth.service';
@@ -65,8 +65,4 @@ export class AccommodationsComponent implements OnInit, OnDestroy {
     this.accommodationService.sendAccommodation(accommodation);
     this.router.navigate(['/accommodation-details']);
   }
-  
-  getAmenityName(amenity: number): string {
-    return AmenityEnum[amenity];
-  }
 }
@@ -1,5 +1,5 @@
-import { Component, ElementRef, ViewChild } from '@angular/core';
-import { Accommodation, AmenityEnum } from '../model/accommodation';
+import { Component, ElementRef, OnInit, ViewChild } from '@angular/core';
+import { Accommodation, AmenityDefinition } from '../model/accommodation';
 import { AccommodationService } from '../services/accommodation.service';
 import { ToastrService } from 'ngx-toastr';
 import { Router } from '@angular/router';
@@ -10,7 +10,7 @@ import { Image } from '../model/image';
   templateUrl: './create-accommodation.component.html',
   styleUrls: ['./create-accommodation.component.css']
 })
-export class CreateAccommodationComponent {
+export class CreateAccommodationComponent implements OnInit {
 
   @ViewChild('imageInput')
   imageInput!: ElementRef;
@@ -23,18 +23,25 @@ export class CreateAccommodationComponent {
     maxGuests: 1 
   };
 
-  amenityValues = Object.values(AmenityEnum).filter(value => typeof value === 'number');
+  amenityValues: AmenityDefinition[] = [];
 
   imageCounter: number = 0;
   images: Image[] = [];
 
   constructor(private accommodationService: AccommodationService, private toastr: ToastrService, private router: Router) {}
 
+  ngOnInit(): void {
+    this.accommodationService.getAmenityCatalog().subscribe(
+      (amenities) => this.amenityValues = amenities,
+      (error) => console.error('Error loading amenities:', error)
+    );
+  }
+
   createAccommodation(): void {
     if (this.newAccommodation) {
       this.newAccommodation.amenities = this.amenityValues
         .filter((_, index) => this.newAccommodation.amenities[index])
-        .map((amenity, _) => amenity as AmenityEnum);
+        .map((amenity, _) => amenity.key);
     }
 
     console.log('Data to be sent:', this.newAccommodation);
@@ -105,7 +112,7 @@ export class CreateAccommodationComponent {
     }
   } 
 
-  getAmenityName(amenity: number): string {
-    return AmenityEnum[amenity];
+  getAmenityName(amenity: AmenityDefinition): string {
+    return amenity.label || amenity.key;
   }
 }
\ No newline at end of file
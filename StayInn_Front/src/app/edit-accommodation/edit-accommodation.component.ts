@@ -1,10 +1,10 @@
 import { Component, OnDestroy, OnInit } from '@angular/core';
-import { Accommodation, AmenityEnum } from '../model/accommodation';
+import { Accommodation, AmenityDefinition } from '../model/accommodation';
 import { ActivatedRoute, Router } from '@angular/router';
 import { AccommodationService } from '../services/accommodation.service';
 import { ToastrService } from 'ngx-toastr';
 import { HttpErrorResponse } from '@angular/common/http';
-import { Subject, takeUntil } from 'rxjs';
+import { Subject, combineLatest, takeUntil } from 'rxjs';
 
 @Component({
   selector: 'app-edit-accommodation',
@@ -23,10 +23,10 @@ export class EditAccommodationComponent implements OnInit, OnDestroy {
     maxGuests: 1 
   };
   
-  amenityValues = Object.values(AmenityEnum).filter(value => typeof value === 'number');
+  amenityValues: AmenityDefinition[] = [];
   
-  getAmenityName(amenity: number): string {
-    return AmenityEnum[amenity];
+  getAmenityName(amenity: AmenityDefinition): string {
+    return amenity.label || amenity.key;
   }
   constructor(
     private accommodationService: AccommodationService,
@@ -45,27 +45,29 @@ export class EditAccommodationComponent implements OnInit, OnDestroy {
   }
 
   getAccommodation(): void {
-    this.accommodationService.getAccommodation()
+    combineLatest([this.accommodationService.getAmenityCatalog(), this.accommodationService.getAccommodation()])
       .pipe(takeUntil(this.unsubscribe$))
-      .subscribe((data) => {
+      .subscribe(([amenities, data]) => {
+        this.amenityValues = amenities;
         this.currentAccommodation = data;
         this.accommodation = { ...this.currentAccommodation };
 
         // Initialize checkboxes with default values
         this.amenityValues.forEach((amenity, index) => {
-          this.accommodation.amenities[index] = this.currentAccommodation.amenities.includes(amenity);
+          this.accommodation.amenities[index] = (this.currentAccommodation.amenityKeys || []).includes(amenity.key);
         });
       });
   }
 
-  getAmenityValue(amenity: AmenityEnum): AmenityEnum {
-    return amenity;
+  getAmenityValue(amenity: AmenityDefinition): string {
+    return amenity.key;
   }
   
   updateAccommodation(): void {
-    this.accommodation.amenities = this.amenityValues
+    this.accommodation.amenityKeys = this.amenityValues
         .filter((_, index) => this.accommodation.amenities[index])
-        .map((amenity, ) => amenity as AmenityEnum);
+        .map((amenity, ) => amenity.key);
+    this.accommodation.amenities = this.accommodation.amenityKeys;
     this.accommodationService.getAccommodationID().subscribe(accommodationId => {
       if (accommodationId) {
         this.accommodationService.updateAccommodation(this.accommodation, accommodationId).subscribe(
@@ -5,31 +5,71 @@ export interface Accommodation {
     hostID?: string;
     name?: string;
     location?: string;
-    amenities: AmenityEnum[];
+    // Legacy numbers of amenities, use amenityKeys
+    amenities: any[];
+    amenityKeys?: string[];
     minGuests?: number;
     maxGuests?: number;
     image?: string;
+    status?: AccommodationStatus;
+    propertyType?: PropertyType;
+    bedrooms?: number;
+    beds?: number;
+    bathrooms?: number;
+    rooms?: Room[];
+    units?: number;
+    remainingUnits?: number;
+    bookingMode?: BookingMode;
+    requestHoldHours?: number;
+}
+
+export type BookingMode = 'instant' | 'request';
+
+export type AccommodationStatus = 'draft' | 'published' | 'paused' | 'archived';
+
+export type PropertyType = 'apartment' | 'house' | 'villa' | 'cabin' | 'guesthouse' | 'room' | 'sharedRoom';
+
+export type BedType = 'single' | 'double' | 'queen' | 'king' | 'sofaBed' | 'bunkBed' | 'crib';
+
+export interface Room {
+  name: string;
+  beds: { type: BedType; count: number }[];
 }
   
-export enum AmenityEnum {
-  Essentials = 0,
-  WiFi = 1,
-  Parking = 2,
-  AirConditioning = 3,
-  Kitchen = 4,
-  TV = 5,
-  Pool = 6,
-  PetFriendly = 7,
-  HairDryer = 8,
-  Iron = 9,
-  IndoorFireplace = 10,
-  Heating = 11,
-  Washer = 12,
-  Hangers = 13,
-  HotWater = 14,
-  PrivateBathroom = 15,
-  Gym = 16,
-  SmokingAllowed = 17,
+export interface AmenityDefinition {
+  key: string;
+  code?: number;
+  category: string;
+  icon: string;
+  labels: { [language: string]: string };
+  label?: string;
+}
+
+export interface Page<T> {
+  items: T[];
+  totalCount: number;
+  nextPageToken?: string;
+}
+
+export interface LocationSuggestion {
+  location: string;
+  count: number;
+}
+
+export interface FacetCount {
+  value: string;
+  count: number;
+}
+
+export interface Facets {
+  locations: FacetCount[];
+  amenities: FacetCount[];
+  propertyTypes: FacetCount[];
+  capacity: FacetCount[];
+}
+
+export interface SearchPage<T> extends Page<T> {
+  facets: Facets;
 }
 
 export interface DisplayedAccommodation {
@@ -1,5 +1,13 @@
 export interface Image {
     id: string;
     acc_id: string;
-    data: [];
-}
\ No newline at end of file
+    data?: [];
+    url?: string;
+    variants?: { [variant: string]: string };
+    contentType?: string;
+    width?: number;
+    height?: number;
+    caption?: string;
+    cover?: boolean;
+    order?: number;
+}
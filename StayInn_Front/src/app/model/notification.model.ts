@@ -5,4 +5,7 @@ export interface Notification {
     hostEmail: string;
     text: string;
     time: string;
+    recipientID?: string;
+    recipientUsername?: string;
+    recipientEmail?: string;
 }
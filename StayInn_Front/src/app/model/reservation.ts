@@ -27,4 +27,58 @@ export interface ReservationByAvailablePeriod {
   EndDate: string;
   GuestNumber: number;
   Price: number;
+  Status?: ReservationStatus;
+  StatusChangedAt?: string;
+  StatusChangedBy?: string;
+  ExpiresAt?: string;
+  HoldID?: string;
+  QuoteToken?: string;
+  PriceBreakdown?: PriceBreakdown;
 }
+
+export interface PriceQuote {
+  username: string;
+  accommodationId: string;
+  availablePeriodId: string;
+  startDate: string;
+  endDate: string;
+  guests: number;
+  breakdown: PriceBreakdown;
+  expiresAt: string;
+  token: string;
+}
+
+export type PricingRuleKind = 'weekday' | 'season' | 'holiday' | 'length_of_stay' | 'last_minute' | 'early_bird' | 'fee';
+
+export interface PricingRule {
+  kind: PricingRuleKind;
+  name?: string;
+  price?: number;
+  percent?: number;
+  weekdays?: number[];
+  startDate?: string;
+  endDate?: string;
+  minNights?: number;
+  daysBefore?: number;
+}
+
+export interface PriceBreakdown {
+  nights: { night: string; price: number; rules?: string[] }[];
+  subtotal: number;
+  adjustments?: { rule: string; kind: PricingRuleKind; percent: number; amount: number }[];
+  fees?: { name: string; amount: number }[];
+  total: number;
+}
+
+export interface DateHold {
+  ID: string;
+  IDAccommodation: string;
+  IDAvailablePeriod: string;
+  IDUser: string;
+  StartDate: string;
+  EndDate: string;
+  GuestNumber: number;
+  ExpiresAt: string;
+}
+
+export type ReservationStatus = 'pending' | 'confirmed' | 'cancelled_by_guest' | 'cancelled_by_host' | 'completed' | 'no_show' | 'declined' | 'expired';
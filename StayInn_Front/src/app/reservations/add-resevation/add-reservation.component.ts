@@ -2,7 +2,7 @@ import { HttpErrorResponse } from '@angular/common/http';
 import { Component, OnInit } from '@angular/core';
 import { Router } from '@angular/router';
 import { Toast, ToastrService } from 'ngx-toastr';
-import { AvailablePeriodByAccommodation, ReservationByAvailablePeriod } from 'src/app/model/reservation';
+import { AvailablePeriodByAccommodation, DateHold, PriceQuote, ReservationByAvailablePeriod } from 'src/app/model/reservation';
 import { ReservationService } from 'src/app/services/reservation.service';
 
 
@@ -13,6 +13,8 @@ import { ReservationService } from 'src/app/services/reservation.service';
 })
 export class AddReservationComponent implements OnInit {
   availablePeriod: any;
+  hold: DateHold | null = null;
+  quote: PriceQuote | null = null;
   
   formData: ReservationByAvailablePeriod = {
     StartDate: '',
@@ -35,6 +37,45 @@ export class AddReservationComponent implements OnInit {
     this.getAvailablePeriod();
   }
 
+  getQuote() {
+    this.formData.IDAccommodation = this.availablePeriod.IDAccommodation;
+    this.formData.IDAvailablePeriod = this.availablePeriod.ID;
+
+    this.reservationService.quoteReservation(this.formData)
+      .subscribe(quote => {
+        this.quote = quote;
+      }, error => {
+        if (error instanceof HttpErrorResponse) {
+          this.toastr.error(`${error.error}`, 'Price Quote Error');
+        } else {
+          this.toastr.error('An unexpected error occurred', 'Price Quote Error');
+        }
+      });
+  }
+
+  holdDates() {
+    this.formData.IDAccommodation = this.availablePeriod.IDAccommodation;
+    this.formData.IDAvailablePeriod = this.availablePeriod.ID;
+
+    this.reservationService.holdDates(this.formData)
+      .subscribe(hold => {
+        this.hold = hold;
+        this.toastr.success('Dates are held for you until ' + new Date(hold.ExpiresAt).toLocaleTimeString(), 'Dates Held');
+      }, error => {
+        if (error instanceof HttpErrorResponse) {
+          this.toastr.error(`${error.error}`, 'Hold Dates Error');
+        } else {
+          this.toastr.error('An unexpected error occurred', 'Hold Dates Error');
+        }
+      });
+  }
+
+  // Held dates and quoted price are kept only for the stay they were made for
+  resetCheckout() {
+    this.hold = null;
+    this.quote = null;
+  }
+
   submitForm() {
     const userId = '655e33ae4b3f315471824211';
     const id = '123e4567-e89b-12d3-a456-426614174022';
@@ -44,12 +85,19 @@ export class AddReservationComponent implements OnInit {
     this.formData.IDAvailablePeriod = this.availablePeriod.ID;
     this.formData.Price = this.availablePeriod.Price;
     this.formData.ID = id;
+    if (this.hold) {
+      this.formData.HoldID = this.hold.ID;
+    }
+    if (this.quote) {
+      this.formData.QuoteToken = this.quote.token;
+    }
 
     console.log(this.formData);
 
     this.reservationService.createReservationByAccommodation(this.formData)
       .subscribe(response => {
         console.log('Reservation created successfully:', response);
+        this.resetCheckout();
         this.formData = {
           StartDate: '',
           EndDate: '',
@@ -39,16 +39,20 @@ export class ReservationsComponent {
   deleteReservation(reservation: ReservationByAvailablePeriod) {
     this.reservationService.deleteReservation(reservation.IDAvailablePeriod, reservation.ID).subscribe(
       (result) => {
-        this.toastr.success('Reservation deleted successfully!', 'Success');
+        this.toastr.success('Reservation cancelled successfully!', 'Success');
         this.router.navigate(['']);
       },
       (error) => {
-        this.toastr.error('Failed to delete reservation!', 'Error');
-        console.error('Error deleting reservation: ', error);
+        this.toastr.error('Failed to cancel reservation!', 'Error');
+        console.error('Error cancelling reservation: ', error);
       }
     );
   }
 
+  isCancellable(reservation: ReservationByAvailablePeriod): boolean {
+    return !reservation.Status || reservation.Status === 'pending' || reservation.Status === 'confirmed';
+  }
+
   navigateToAddReservation(): void {
     this.router.navigate(['/addReservation']);
   }
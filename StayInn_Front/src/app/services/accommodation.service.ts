@@ -1,7 +1,8 @@
 import { Injectable } from '@angular/core';
 import { HttpClient, HttpHeaders, HttpResponse } from '@angular/common/http';
 import { BehaviorSubject, Observable, Subject, of } from 'rxjs';
-import { Accommodation } from 'src/app/model/accommodation';
+import { map } from 'rxjs/operators';
+import { Accommodation, AccommodationStatus, AmenityDefinition, LocationSuggestion, Page } from 'src/app/model/accommodation';
 import { environment } from 'src/environments/environment';
 import { Image } from '../model/image';
 
@@ -13,18 +14,26 @@ export class AccommodationService {
   private currentAccommodation = new BehaviorSubject<Accommodation | null>(null);
   private idAccommodation = new BehaviorSubject<string | null>(null);
   private searchedAccommodationsSubject = new Subject<Accommodation[]>();
+  private pageSize = 100;
 
   constructor(
     private http: HttpClient
   ) { }
 
   getAccommodations(): Observable<Accommodation[]> {
-    return this.http.get<Accommodation[]>(this.apiUrl + '/accommodation');
+    return this.http.get<Page<Accommodation>>(this.apiUrl + `/accommodation?pageSize=${this.pageSize}`)
+      .pipe(map(page => page.items));
   }
 
   //accommodation rating
   getAccommodationById(id: string): Observable<Accommodation> {
-    return this.http.get<Accommodation>(this.apiUrl + `/accommodation/${id}`);
+    return this.http.get<Accommodation>(this.apiUrl + `/accommodation/${id}`, { headers: this.optionalAuthHeaders() });
+  }
+
+  // Listings that are not published are visible only to their host, so the token is sent when logged in
+  private optionalAuthHeaders(): HttpHeaders {
+    const token = localStorage.getItem('token');
+    return token ? new HttpHeaders({ 'Authorization': `Bearer ${token}` }) : new HttpHeaders();
   }
 
   getAccommodationsByUser(username: string): Observable<Accommodation[]> {
@@ -33,7 +42,8 @@ export class AccommodationService {
       'Content-Type': 'application/json',
       'Authorization': `Bearer ${token}`
     });
-    return this.http.get<Accommodation[]>(this.apiUrl + `/user/${username}/accommodations`, { headers });
+    return this.http.get<Page<Accommodation>>(this.apiUrl + `/user/${username}/accommodations?pageSize=${this.pageSize}`, { headers })
+      .pipe(map(page => page.items));
   }
 
   updateAccommodation(accommodation: Accommodation, id: string): Observable<any> {
@@ -63,16 +73,24 @@ export class AccommodationService {
   }
 
   searchAccommodations(location: string, numberOfGuests: number, startDate: string, endDate: string): Observable<Accommodation[]> {
-    let apiUrl = this.apiUrl + `/search?location=${location}&numberOfGuests=${numberOfGuests}`;
+    let apiUrl = this.apiUrl + `/search?location=${location}&numberOfGuests=${numberOfGuests}&pageSize=${this.pageSize}`;
   
     // Provera da li postoje vrednosti za startDate i endDate
     if (startDate && endDate) {
       apiUrl += `&startDate=${startDate}&endDate=${endDate}`;
     }
   
-    return this.http.get<Accommodation[]>(apiUrl);
+    return this.http.get<Page<Accommodation>>(apiUrl).pipe(map(page => page.items));
   } 
 
+  suggestLocations(query: string, limit: number = 10): Observable<LocationSuggestion[]> {
+    return this.http.get<LocationSuggestion[]>(this.apiUrl + `/locations/suggest?q=${encodeURIComponent(query)}&limit=${limit}`);
+  }
+
+  getAmenityCatalog(lang: string = 'en'): Observable<AmenityDefinition[]> {
+    return this.http.get<AmenityDefinition[]>(this.apiUrl + `/amenities?lang=${encodeURIComponent(lang)}`);
+  }
+
   sendSearchedAccommodations(accommodations: Accommodation[]): void {
     this.searchedAccommodationsSubject.next(accommodations);
   }
@@ -102,7 +120,17 @@ export class AccommodationService {
   }
 
   getAccommodationImages(accID: string): Observable<Image[]> {
-    return this.http.get<Image[]>(this.apiUrl + '/accommodation/' + accID + '/images');
+    return this.http.get<Image[]>(this.apiUrl + '/accommodation/' + accID + '/images', { headers: this.optionalAuthHeaders() });
+  }
+
+  changeAccommodationStatus(id: string, status: AccommodationStatus): Observable<Accommodation> {
+    const token = localStorage.getItem('token');
+    const headers = new HttpHeaders({
+      'Content-Type': 'application/json',
+      'Authorization': `Bearer ${token}`
+    });
+
+    return this.http.put<Accommodation>(this.apiUrl + `/accommodation/${id}/status`, { status }, { headers });
   }
 
   deleteAccommodation(id: string): Observable<any> {
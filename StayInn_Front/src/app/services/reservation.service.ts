@@ -1,7 +1,7 @@
 import { HttpClient, HttpHeaders } from '@angular/common/http';
 import { Injectable } from '@angular/core';
 import { BehaviorSubject, Observable, Subject } from 'rxjs';
-import { AvailablePeriodByAccommodation, ReservationByAvailablePeriod, ReservationFormData } from '../model/reservation';
+import { AvailablePeriodByAccommodation, DateHold, PriceQuote, PricingRule, ReservationByAvailablePeriod, ReservationFormData } from '../model/reservation';
 import { DatePipe } from '@angular/common';
 import { environment } from 'src/environments/environment';
 
@@ -81,6 +81,39 @@ export class ReservationService {
     return this.http.post(this.baseUrl + '/reservation', JSON.stringify(reservationData), { headers });
   }
 
+  quoteReservation(reservationData: ReservationByAvailablePeriod): Observable<PriceQuote> {
+    const token = localStorage.getItem('token');
+    const headers = new HttpHeaders({
+      'Content-Type': 'application/json',
+      'Authorization': `Bearer ${token}`
+    });
+
+    const quoteData = {
+      ...reservationData,
+      StartDate: this.formatDate(reservationData.StartDate),
+      EndDate: this.formatDate(reservationData.EndDate)
+    };
+
+    return this.http.post<PriceQuote>(this.baseUrl + '/quote', JSON.stringify(quoteData), { headers });
+  }
+
+  holdDates(reservationData: ReservationByAvailablePeriod): Observable<DateHold> {
+    const token = localStorage.getItem('token');
+    const headers = new HttpHeaders({
+      'Content-Type': 'application/json',
+      'Authorization': `Bearer ${token}`
+    });
+
+    // Form dates are formatted again when the reservation is made, so they're left untouched here
+    const holdData = {
+      ...reservationData,
+      StartDate: this.formatDate(reservationData.StartDate),
+      EndDate: this.formatDate(reservationData.EndDate)
+    };
+
+    return this.http.post<DateHold>(this.baseUrl + '/hold', JSON.stringify(holdData), { headers });
+  }
+
   updateAvailablePeriod(periodData: AvailablePeriodByAccommodation): Observable<any> {
     const token = localStorage.getItem('token');
     const headers = new HttpHeaders({
@@ -104,6 +137,46 @@ export class ReservationService {
     return this.http.delete(this.baseUrl + `/${idPeriod}/${idReservations}`, { headers });
   }
 
+  acceptReservationRequest(idPeriod: string, idReservation: string): Observable<ReservationByAvailablePeriod> {
+    const token = localStorage.getItem('token');
+    const headers = new HttpHeaders({
+      'Content-Type': 'application/json',
+      'Authorization': `Bearer ${token}`
+    });
+
+    return this.http.post<ReservationByAvailablePeriod>(this.baseUrl + `/${idPeriod}/${idReservation}/accept`, null, { headers });
+  }
+
+  declineReservationRequest(idPeriod: string, idReservation: string): Observable<ReservationByAvailablePeriod> {
+    const token = localStorage.getItem('token');
+    const headers = new HttpHeaders({
+      'Content-Type': 'application/json',
+      'Authorization': `Bearer ${token}`
+    });
+
+    return this.http.post<ReservationByAvailablePeriod>(this.baseUrl + `/${idPeriod}/${idReservation}/decline`, null, { headers });
+  }
+
+  getPricingRules(accommodationId: string): Observable<PricingRule[]> {
+    const token = localStorage.getItem('token');
+    const headers = new HttpHeaders({
+      'Content-Type': 'application/json',
+      'Authorization': `Bearer ${token}`
+    });
+
+    return this.http.get<PricingRule[]>(`${this.baseUrl}/${accommodationId}/pricing-rules`, { headers });
+  }
+
+  updatePricingRules(accommodationId: string, rules: PricingRule[]): Observable<PricingRule[]> {
+    const token = localStorage.getItem('token');
+    const headers = new HttpHeaders({
+      'Content-Type': 'application/json',
+      'Authorization': `Bearer ${token}`
+    });
+
+    return this.http.put<PricingRule[]>(`${this.baseUrl}/${accommodationId}/pricing-rules`, JSON.stringify(rules), { headers });
+  }
+
   sendAvailablePeriod(data: AvailablePeriodByAccommodation) {
     this.dataSubject.next(data);
   }
@@ -0,0 +1,192 @@
+package cache
+
+import (
+	"accommodation/data"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/go-redis/redis"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// Bumped whenever cached structures change, so entries written by older versions are never decoded
+	schemaVersion = "v1"
+
+	accommodationExpiration = 5 * time.Minute
+	// Up to this fraction of expiration is added to it, so entries filled together don't expire together
+	expirationJitter = 0.2
+	// Generations must outlive every entry written under them
+	generationExpiration = 24 * time.Hour
+
+	// Only one instance rebuilds an expired entry, others wait for it up to lockWait
+	lockExpiration = 5 * time.Second
+	lockWait       = 1 * time.Second
+	lockPoll       = 50 * time.Millisecond
+
+	// Limits loading of a missed entry, which doesn't end with the request that started it
+	loadTimeout = 10 * time.Second
+)
+
+// AccommodationCache is a cache-aside layer for accommodation documents and host listings.
+//
+// Every accommodation and every host has a generation counter which is part of their entry keys.
+// Invalidation increments the generation, so entries written by requests which loaded data
+// before the change land under the old generation and are never read again.
+type AccommodationCache struct {
+	cli   *redis.Client
+	group singleflight.Group
+}
+
+func NewAccommodationCache() *AccommodationCache {
+	redisHost := os.Getenv("REDIS_HOST")
+	redistPort := os.Getenv("REDIS_PORT")
+	redisAddress := fmt.Sprintf("%s:%s", redisHost, redistPort)
+
+	client := redis.NewClient(&redis.Options{
+		Addr: redisAddress,
+	})
+
+	return &AccommodationCache{
+		cli: client,
+	}
+}
+
+// Returns accommodation from cache, loading and caching it on miss
+func (ac *AccommodationCache) GetAccommodation(id string, load func(ctx context.Context) (*data.Accommodation, error)) (*data.Accommodation, error) {
+	generation := ac.generation(accommodationGenerationKey(id))
+	key := fmt.Sprintf("accommodation:%s:%s:%d", schemaVersion, id, generation)
+	return getOrLoad(ac, key, load)
+}
+
+// Returns page of host accommodations from cache, loading and caching it on miss.
+// Page key identifies the requested page (size, order and continuation token).
+func (ac *AccommodationCache) GetHostAccommodations(hostID, pageKey string, load func(ctx context.Context) (*data.Page[*data.Accommodation], error)) (*data.Page[*data.Accommodation], error) {
+	generation := ac.generation(hostGenerationKey(hostID))
+	key := fmt.Sprintf("host:%s:%s:accommodations:%d:%s", schemaVersion, hostID, generation, pageKey)
+	return getOrLoad(ac, key, load)
+}
+
+// Makes cached accommodation stale, has to be called after every change of the accommodation
+func (ac *AccommodationCache) InvalidateAccommodation(id string) error {
+	return ac.nextGeneration(accommodationGenerationKey(id))
+}
+
+// Makes cached listings of host stale, has to be called after any of host accommodations is created, changed or deleted
+func (ac *AccommodationCache) InvalidateHost(hostID string) error {
+	return ac.nextGeneration(hostGenerationKey(hostID))
+}
+
+// Cache-aside read. Concurrent misses of the same key in this instance share one load,
+// concurrent misses across instances are serialized by a short lived lock in Redis.
+// Cache failures are logged and never fail the read, data is then loaded directly.
+// The shared load gets its own context, so a caller giving up doesn't fail the others waiting for it.
+func getOrLoad[T any](ac *AccommodationCache, key string, load func(ctx context.Context) (T, error)) (T, error) {
+	var value T
+	if ac.read(key, &value) {
+		return value, nil
+	}
+
+	result, err, _ := ac.group.Do(key, func() (interface{}, error) {
+		var value T
+		lockKey := key + ":lock"
+		locked, err := ac.cli.SetNX(lockKey, 1, lockExpiration).Result()
+		if err != nil {
+			log.Error(fmt.Sprintf("[acco-cache]acc#10 Failed to acquire lock '%s': %v", lockKey, err))
+		}
+
+		if err == nil && !locked {
+			// Another instance is rebuilding the entry
+			for deadline := time.Now().Add(lockWait); time.Now().Before(deadline); {
+				time.Sleep(lockPoll)
+				if ac.read(key, &value) {
+					return value, nil
+				}
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), loadTimeout)
+		defer cancel()
+		value, err = load(ctx)
+		if err != nil {
+			if locked {
+				ac.cli.Del(lockKey)
+			}
+			return value, err
+		}
+
+		ac.write(key, value)
+		if locked {
+			ac.cli.Del(lockKey)
+		}
+		return value, nil
+	})
+	if err != nil {
+		return value, err
+	}
+	return result.(T), nil
+}
+
+func (ac *AccommodationCache) read(key string, dest interface{}) bool {
+	val, err := ac.cli.Get(key).Bytes()
+	if err != nil {
+		if err != redis.Nil {
+			log.Error(fmt.Sprintf("[acco-cache]acc#11 Failed to read '%s': %v", key, err))
+		}
+		return false
+	}
+
+	if err := json.Unmarshal(val, dest); err != nil {
+		log.Error(fmt.Sprintf("[acco-cache]acc#12 Failed to decode '%s': %v", key, err))
+		return false
+	}
+
+	log.Info(fmt.Sprintf("[acco-cache]acc#13 Cache hit '%s'", key))
+	return true
+}
+
+func (ac *AccommodationCache) write(key string, value interface{}) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-cache]acc#14 Failed to encode '%s': %v", key, err))
+		return
+	}
+
+	expiration := accommodationExpiration + time.Duration(rand.Float64()*expirationJitter*float64(accommodationExpiration))
+	if err := ac.cli.Set(key, encoded, expiration).Err(); err != nil {
+		log.Error(fmt.Sprintf("[acco-cache]acc#15 Failed to write '%s': %v", key, err))
+	}
+}
+
+// Returns current generation, 0 if it was never incremented or has expired
+func (ac *AccommodationCache) generation(key string) int64 {
+	generation, err := ac.cli.Get(key).Int64()
+	if err != nil && err != redis.Nil {
+		log.Error(fmt.Sprintf("[acco-cache]acc#16 Failed to read generation '%s': %v", key, err))
+	}
+	return generation
+}
+
+func (ac *AccommodationCache) nextGeneration(key string) error {
+	pipe := ac.cli.TxPipeline()
+	pipe.Incr(key)
+	pipe.Expire(key, generationExpiration)
+	if _, err := pipe.Exec(); err != nil {
+		log.Error(fmt.Sprintf("[acco-cache]acc#17 Failed to invalidate '%s': %v", key, err))
+		return err
+	}
+	return nil
+}
+
+func accommodationGenerationKey(id string) string {
+	return fmt.Sprintf("accommodation:%s:%s:generation", schemaVersion, id)
+}
+
+func hostGenerationKey(hostID string) string {
+	return fmt.Sprintf("host:%s:%s:generation", schemaVersion, hostID)
+}
@@ -5,10 +5,20 @@ import (
 	"io"
 )
 
+// Image listed with URLs of its content and resized variants.
+// Data is only set by clients uploading base64 encoded images.
 type Image struct {
-	ID    string `json:"id"`
-	AccID string `json:"acc_id"`
-	Data  []byte `json:"data"`
+	ID          string            `json:"id"`
+	AccID       string            `json:"acc_id"`
+	Data        []byte            `json:"data,omitempty"`
+	URL         string            `json:"url,omitempty"`
+	Variants    map[string]string `json:"variants,omitempty"`
+	ContentType string            `json:"contentType,omitempty"`
+	Width       int               `json:"width,omitempty"`
+	Height      int               `json:"height,omitempty"`
+	Caption     string            `json:"caption,omitempty"`
+	Cover       bool              `json:"cover,omitempty"`
+	Order       int               `json:"order"`
 }
 
 type Images []*Image
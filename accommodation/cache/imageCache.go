@@ -122,3 +122,16 @@ func (ic *ImageCache) Exists(accID, imageID string) (bool, error) {
 
 	return exists > 0, nil
 }
+
+// Remove cached images for accommodation, so they are read from storage on next request
+func (ic *ImageCache) DeleteAll(accID string) error {
+	key := constructKey(accID, "")
+
+	err := ic.cli.Del(key).Err()
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-cache]acc#9 Failed to delete cached images: %v", err))
+		return err
+	}
+
+	return nil
+}
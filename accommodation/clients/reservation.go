@@ -29,14 +29,23 @@ func NewReservationClient(client *http.Client, address string, cb *gobreaker.Cir
 	}
 }
 
+// Sends unit counts of accommodations to reservation service and returns those which are
+// available for the whole stay, with the number of units still free
 func (rc ReservationClient) PassDatesToReservationService(ctx context.Context,
-	accommodationIds []primitive.ObjectID, startDate, endDate time.Time,
-	token string) ([]primitive.ObjectID, error) {
+	accommodationUnits map[primitive.ObjectID]int, startDate, endDate time.Time,
+	token string) (map[primitive.ObjectID]int, error) {
 
 	dates := data.Dates{
-		AccommodationIds: accommodationIds,
+		AccommodationIds: make([]primitive.ObjectID, 0, len(accommodationUnits)),
 		StartDate:        startDate,
 		EndDate:          endDate,
+		Units:            make(map[string]int),
+	}
+	for id, units := range accommodationUnits {
+		dates.AccommodationIds = append(dates.AccommodationIds, id)
+		if units > 1 {
+			dates.Units[id.Hex()] = units
+		}
 	}
 
 	requestBody, err := json.Marshal(dates)
@@ -77,7 +86,16 @@ func (rc ReservationClient) PassDatesToReservationService(ctx context.Context,
 		return nil, fmt.Errorf("failed to decode JSON response: %v", err)
 	}
 
-	return serviceResponse.ObjectIds, nil
+	available := make(map[primitive.ObjectID]int, len(serviceResponse.ObjectIds))
+	for _, id := range serviceResponse.ObjectIds {
+		remaining, ok := serviceResponse.RemainingUnits[id.Hex()]
+		if !ok {
+			// Reservation service which doesn't report units treats every accommodation as one unit
+			remaining = 1
+		}
+		available[id] = remaining
+	}
+	return available, nil
 }
 
 func (rc ReservationClient) CheckAndDeletePeriods(ctx context.Context, accIDs []primitive.ObjectID, token string) (interface{}, error) {
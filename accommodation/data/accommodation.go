@@ -9,69 +9,105 @@ import (
 )
 
 type Accommodation struct {
-	ID        primitive.ObjectID `json:"id" bson:"_id"`
-	HostID    primitive.ObjectID `json:"hostID" bson:"hostID"`
-	Name      string             `json:"name" bson:"name"`
-	Location  string             `json:"location" bson:"location"`
-	Amenities []AmenityEnum      `json:"amenities" bson:"amenities"`
-	MinGuests int                `json:"minGuests" bson:"minGuests"`
-	MaxGuests int                `json:"maxGuests" bson:"maxGuests"`
+	ID          primitive.ObjectID `json:"id" bson:"_id"`
+	HostID      primitive.ObjectID `json:"hostID" bson:"hostID"`
+	Name        string             `json:"name" bson:"name"`
+	Location    string             `json:"location" bson:"location"`
+	Description string             `json:"description" bson:"description"`
+	Amenities   []AmenityKey       `json:"amenities" bson:"amenities"`
+	MinGuests   int                `json:"minGuests" bson:"minGuests"`
+	MaxGuests   int                `json:"maxGuests" bson:"maxGuests"`
+	Position    *GeoPoint          `json:"position,omitempty" bson:"position,omitempty"`
+	// Layout of the place, all optional for accommodations created before they were introduced
+	PropertyType PropertyType `json:"propertyType,omitempty" bson:"propertyType,omitempty"`
+	Bedrooms     int          `json:"bedrooms" bson:"bedrooms"`
+	Beds         int          `json:"beds" bson:"beds"`
+	Bathrooms    int          `json:"bathrooms" bson:"bathrooms"`
+	Rooms        []Room       `json:"rooms,omitempty" bson:"rooms"`
+	// Number of identical bookable units, e.g. rooms of a hotel listed together. Zero counts as one.
+	Units int `json:"units,omitempty" bson:"units,omitempty"`
+	// Instant booking when empty. In request mode a reservation holds the dates for RequestHoldHours
+	// while waiting for the host, DefaultRequestHoldHours when zero.
+	BookingMode      BookingMode         `json:"bookingMode,omitempty" bson:"bookingMode,omitempty"`
+	RequestHoldHours int                 `json:"requestHoldHours,omitempty" bson:"requestHoldHours,omitempty"`
+	Status           AccommodationStatus `json:"status,omitempty" bson:"status,omitempty"`
+	// Host's own reference of the accommodation, used to match it on import
+	ExternalRef string `json:"externalRef,omitempty" bson:"externalRef,omitempty"`
 }
 
 type Dates struct {
 	AccommodationIds []primitive.ObjectID
 	StartDate        time.Time `json:"startDate"`
 	EndDate          time.Time `json:"endDate"`
+	// Units of multi-unit accommodations by hex ID, accommodations left out have one unit
+	Units map[string]int `json:"units,omitempty"`
 }
 
 type ListOfObjectIds struct {
 	ObjectIds []primitive.ObjectID `json:"objectIds"`
+	// Units still free for the whole stay, by hex ID of accommodation
+	RemainingUnits map[string]int `json:"remainingUnits,omitempty"`
 }
 
-type AmenityEnum int
-
-const (
-	Essentials      AmenityEnum = iota //0
-	WiFi                               //1
-	Parking                            //2
-	AirConditioning                    //3
-	Kitchen                            //4
-	TV                                 //5
-	Pool                               //6
-	PetFriendly                        //7
-	HairDryer                          //8
-	Iron                               //9
-	IndoorFireplace                    //10
-	Heating                            //11
-	Washer                             //12
-	Hangers                            //13
-	HotWater                           //14
-	PrivateBathroom                    //15
-	Gym                                //16
-	SmokingAllowed                     //17
-)
+func (a *Accommodation) UnitCount() int {
+	if a.Units < 1 {
+		return 1
+	}
+	return a.Units
+}
 
-func (a *Accommodation) ToJSON(w io.Writer) error {
-	e := json.NewEncoder(w)
-	return e.Encode(a)
+// Amenities are encoded as keys in "amenityKeys" and, for clients which predate the amenity catalog,
+// as legacy numbers in "amenities". Amenities added to the catalog later have no number and are left out there.
+func (a Accommodation) MarshalJSON() ([]byte, error) {
+	type accommodation Accommodation
+	return json.Marshal(struct {
+		accommodation
+		Amenities   []int        `json:"amenities"`
+		AmenityKeys []AmenityKey `json:"amenityKeys"`
+	}{accommodation(a), a.GetAmenitiesAsInt(), a.Amenities})
 }
 
-func (a *Accommodation) FromJSON(r io.Reader) error {
-	d := json.NewDecoder(r)
-	return d.Decode(a)
+// Accepts amenities as keys in "amenityKeys", or as keys or legacy numbers in "amenities".
+// When both are given, as in accommodations encoded by MarshalJSON, "amenityKeys" is used.
+func (a *Accommodation) UnmarshalJSON(b []byte) error {
+	type accommodation Accommodation
+	decoded := struct {
+		*accommodation
+		AmenityKeys *[]AmenityKey `json:"amenityKeys"`
+	}{accommodation: (*accommodation)(a)}
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		return err
+	}
+	if decoded.AmenityKeys != nil {
+		a.Amenities = *decoded.AmenityKeys
+	}
+	return nil
 }
 
+// Returns legacy numbers of amenities, amenities without one are left out
 func (a *Accommodation) GetAmenitiesAsInt() []int {
-	amenitiesAsInt := make([]int, len(a.Amenities))
-	for i, amenity := range a.Amenities {
-		amenitiesAsInt[i] = int(amenity)
+	amenitiesAsInt := make([]int, 0, len(a.Amenities))
+	for _, amenity := range a.Amenities {
+		if number, ok := LegacyAmenityNumber(amenity); ok {
+			amenitiesAsInt = append(amenitiesAsInt, number)
+		}
 	}
 	return amenitiesAsInt
 }
 
 func (a *Accommodation) SetAmenitiesFromInt(amenitiesAsInt []int) {
-	a.Amenities = make([]AmenityEnum, len(amenitiesAsInt))
+	a.Amenities = make([]AmenityKey, len(amenitiesAsInt))
 	for i, val := range amenitiesAsInt {
-		a.Amenities[i] = AmenityEnum(val)
+		a.Amenities[i] = amenityKeyFromNumber(val)
 	}
 }
+
+func (a *Accommodation) ToJSON(w io.Writer) error {
+	e := json.NewEncoder(w)
+	return e.Encode(a)
+}
+
+func (a *Accommodation) FromJSON(r io.Reader) error {
+	d := json.NewDecoder(r)
+	return d.Decode(a)
+}
@@ -64,6 +64,87 @@ func (ar *AccommodationRepository) Ping() {
 	fmt.Println(databases)
 }
 
+// Creates indexes needed by search, image, location suggestion and wishlist queries. Existing indexes are left untouched.
+func (ar *AccommodationRepository) CreateIndexes(ctx context.Context) error {
+	collection := ar.getAccommodationCollection()
+
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "position", Value: "2dsphere"}},
+			Options: options.Index().SetName("position_2dsphere"),
+		},
+		{
+			Keys: bson.D{{Key: "hostID", Value: 1}, {Key: "externalRef", Value: 1}},
+			Options: options.Index().
+				SetName("host_external_ref").
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"externalRef": bson.M{"$type": "string"}}),
+		},
+		{
+			Keys:    bson.D{{Key: "propertyType", Value: 1}, {Key: "bedrooms", Value: 1}},
+			Options: options.Index().SetName("property_type_bedrooms"),
+		},
+		{
+			Keys: bson.D{
+				{Key: "name", Value: "text"},
+				{Key: "location", Value: "text"},
+				{Key: "description", Value: "text"},
+			},
+			Options: options.Index().
+				SetName("accommodation_text").
+				SetWeights(textSearchWeights),
+		},
+	}
+
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#18 Failed to create indexes: %v", err))
+		return err
+	}
+
+	_, err = ar.getImageCollection().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "accommodationId", Value: 1}, {Key: "order", Value: 1}},
+			Options: options.Index().SetName("accommodation_order"),
+		},
+		{
+			// Keeps legacy images from being adopted twice by replicas collecting garbage at once
+			Keys:    bson.D{{Key: "path", Value: 1}},
+			Options: options.Index().SetName("path").SetUnique(true),
+		},
+	})
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#39 Failed to create image indexes: %v", err))
+		return err
+	}
+
+	_, err = ar.getSuggestionCollection().Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "terms", Value: 1}},
+		Options: options.Index().SetName("suggestion_terms"),
+	})
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#49 Failed to create location suggestion indexes: %v", err))
+		return err
+	}
+
+	_, err = ar.getWishlistCollection().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "guestId", Value: 1}},
+			Options: options.Index().SetName("guest"),
+		},
+		{
+			Keys:    bson.D{{Key: "accommodationIds", Value: 1}},
+			Options: options.Index().SetName("accommodations"),
+		},
+	})
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#66 Failed to create wishlist indexes: %v", err))
+		return err
+	}
+
+	return nil
+}
+
 func (ar *AccommodationRepository) CreateAccommodation(ctx context.Context, accommodation *Accommodation) error {
 	collection := ar.getAccommodationCollection()
 
@@ -76,42 +157,132 @@ func (ar *AccommodationRepository) CreateAccommodation(ctx context.Context, acco
 	return nil
 }
 
-func (ar *AccommodationRepository) GetAllAccommodations(ctx context.Context) ([]*Accommodation, error) {
+func (ar *AccommodationRepository) GetAllAccommodations(ctx context.Context, page *PageRequest) (*Page[*Accommodation], error) {
 	collection := ar.getAccommodationCollection()
 
-	cursor, err := collection.Find(ctx, bson.M{})
+	filter := PublishedFilter()
+	total, err := collection.CountDocuments(ctx, filter)
 	if err != nil {
-		log.Error(fmt.Sprintf("[acco-repo]acr#5 Failed to get all accommodations: %v", err))
+		log.Error(fmt.Sprintf("[acco-repo]acr#5 Failed to count all accommodations: %v", err))
+		return nil, err
+	}
+
+	accommodations, err := findPage[*Accommodation](ctx, collection, filter, page, options.Find())
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#6 Failed to get all accommodations: %v", err))
+		return nil, err
+	}
+
+	return newPage(accommodations, total, page, func(a *Accommodation) *Accommodation { return a }), nil
+}
+
+// Returns accommodations of host, all of them or only published ones when listed for others
+func (ar *AccommodationRepository) GetAccommodationsForUser(ctx context.Context, userID primitive.ObjectID, page *PageRequest, onlyPublished bool) (*Page[*Accommodation], error) {
+	collection := ar.getAccommodationCollection()
+
+	filter := bson.M{"hostID": userID}
+	if onlyPublished {
+		filter = andFilters(filter, PublishedFilter())
+	}
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#7 Failed to count accommodations for user '%v': %v", userID, err))
+		return nil, err
+	}
+
+	accommodations, err := findPage[*Accommodation](ctx, collection, filter, page, options.Find())
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#8 Failed to get accommodations for user '%v': %v", userID, err))
+		return nil, err
+	}
+
+	return newPage(accommodations, total, page, func(a *Accommodation) *Accommodation { return a }), nil
+}
+
+func (ar *AccommodationRepository) GetAccommodationIDsForUser(ctx context.Context, userID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	ids, err := ar.findIDs(ctx, bson.M{"hostID": userID})
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#25 Failed to get accommodation IDs for user '%v': %v", userID, err))
+		return nil, err
+	}
+	return ids, nil
+}
+
+// Returns every accommodation of host, oldest first
+func (ar *AccommodationRepository) GetAllAccommodationsForHost(ctx context.Context, hostID primitive.ObjectID) ([]*Accommodation, error) {
+	collection := ar.getAccommodationCollection()
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	cursor, err := collection.Find(ctx, bson.M{"hostID": hostID}, findOptions)
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#50 Failed to get accommodations of host '%v': %v", hostID, err))
 		return nil, err
 	}
 	defer cursor.Close(ctx)
 
-	var accommodations []*Accommodation
+	accommodations := []*Accommodation{}
 	if err := cursor.All(ctx, &accommodations); err != nil {
-		log.Error(fmt.Sprintf("[acco-repo]acr#6 Failed to iterate over all accommodations: %v", err))
+		log.Error(fmt.Sprintf("[acco-repo]acr#51 Failed to iterate over accommodations: %v", err))
 		return nil, err
 	}
 
 	return accommodations, nil
 }
 
-func (ar *AccommodationRepository) GetAccommodationsForUser(ctx context.Context, userID primitive.ObjectID) ([]*Accommodation, error) {
+// Returns accommodations of host with given external references, indexed by the reference
+func (ar *AccommodationRepository) GetAccommodationsByExternalRefs(ctx context.Context, hostID primitive.ObjectID, refs []string) (map[string]*Accommodation, error) {
 	collection := ar.getAccommodationCollection()
 
-	cursor, err := collection.Find(ctx, bson.M{"hostID": userID})
+	found := make(map[string]*Accommodation)
+	if len(refs) == 0 {
+		return found, nil
+	}
+
+	cursor, err := collection.Find(ctx, bson.M{"hostID": hostID, "externalRef": bson.M{"$in": refs}})
 	if err != nil {
-		log.Error(fmt.Sprintf("[acco-repo]acr#7 Failed to get accommodations for user '%v': %v", userID, err))
+		log.Error(fmt.Sprintf("[acco-repo]acr#52 Failed to get accommodations by external references: %v", err))
 		return nil, err
 	}
 	defer cursor.Close(ctx)
 
 	var accommodations []*Accommodation
 	if err := cursor.All(ctx, &accommodations); err != nil {
-		log.Error(fmt.Sprintf("[acco-repo]acr#8 Failed to iterate over all accommodations: %v", err))
+		log.Error(fmt.Sprintf("[acco-repo]acr#53 Failed to iterate over accommodations: %v", err))
 		return nil, err
 	}
+	for _, accommodation := range accommodations {
+		found[accommodation.ExternalRef] = accommodation
+	}
 
-	return accommodations, nil
+	return found, nil
+}
+
+// Returns accommodations of host with given IDs, indexed by the ID. Accommodations of other hosts are left out.
+func (ar *AccommodationRepository) GetAccommodationsForHostByIDs(ctx context.Context, hostID primitive.ObjectID, ids []primitive.ObjectID) (map[primitive.ObjectID]*Accommodation, error) {
+	collection := ar.getAccommodationCollection()
+
+	found := make(map[primitive.ObjectID]*Accommodation)
+	if len(ids) == 0 {
+		return found, nil
+	}
+
+	cursor, err := collection.Find(ctx, bson.M{"hostID": hostID, "_id": bson.M{"$in": ids}})
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#75 Failed to get accommodations of host by IDs: %v", err))
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var accommodations []*Accommodation
+	if err := cursor.All(ctx, &accommodations); err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#76 Failed to iterate over accommodations: %v", err))
+		return nil, err
+	}
+	for _, accommodation := range accommodations {
+		found[accommodation.ID] = accommodation
+	}
+
+	return found, nil
 }
 
 func (ar *AccommodationRepository) GetAccommodation(ctx context.Context, id primitive.ObjectID) (*Accommodation, error) {
@@ -132,6 +303,10 @@ func (ar *AccommodationRepository) UpdateAccommodation(ctx context.Context, acco
 
 	filter := bson.M{"_id": accommodation.ID}
 	update := bson.M{"$set": accommodation}
+	// Optional fields left empty are omitted from $set, so they are removed to replace the previous values
+	if unset := emptyOptionalFields(accommodation); len(unset) > 0 {
+		update["$unset"] = unset
+	}
 
 	_, err := collection.UpdateOne(ctx, filter, update)
 	if err != nil {
@@ -142,6 +317,48 @@ func (ar *AccommodationRepository) UpdateAccommodation(ctx context.Context, acco
 	return nil
 }
 
+// Optional fields of accommodation that are empty, without status and external reference which
+// are changed separately.
+func emptyOptionalFields(accommodation *Accommodation) bson.M {
+	unset := bson.M{}
+	if accommodation.Position == nil {
+		unset["position"] = ""
+	}
+	if accommodation.PropertyType == "" {
+		unset["propertyType"] = ""
+	}
+	if accommodation.Units == 0 {
+		unset["units"] = ""
+	}
+	if accommodation.BookingMode == "" {
+		unset["bookingMode"] = ""
+	}
+	if accommodation.RequestHoldHours == 0 {
+		unset["requestHoldHours"] = ""
+	}
+	return unset
+}
+
+// Moves accommodation from one status to another.
+// Returns ErrStatusChanged if accommodation is no longer in the from status.
+func (ar *AccommodationRepository) UpdateAccommodationStatus(ctx context.Context, id primitive.ObjectID, from, to AccommodationStatus) error {
+	collection := ar.getAccommodationCollection()
+
+	filter := andFilters(bson.M{"_id": id}, statusFilter(from))
+	update := bson.M{"$set": bson.M{"status": to}}
+
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#40 Failed to update status of accommodation '%v': %v", id, err))
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrStatusChanged
+	}
+
+	return nil
+}
+
 func (ar *AccommodationRepository) DeleteAccommodation(ctx context.Context, id primitive.ObjectID) error {
 	collection := ar.getAccommodationCollection()
 
@@ -192,26 +409,244 @@ func (ar *AccommodationRepository) FindAccommodationsByIDs(ctx context.Context,
 
 // Search part
 
-func (ar *AccommodationRepository) GetFilteredAccommodations(ctx context.Context, filters bson.M) ([]*Accommodation, error) {
+// Returns requested page of accommodations matching search query.
+// Results are ordered by the page sort order; with relevance order every result carries its score,
+// with geo queries its distance in km. Results matching text query carry highlighted snippets.
+// The filter is the one BuildSearchFilter built for the query.
+func (ar *AccommodationRepository) GetFilteredAccommodations(ctx context.Context, query *SearchQuery, searchFilter *SearchFilter) (*Page[*SearchResult], error) {
 	collection := ar.getAccommodationCollection()
+	filter, textIndex := searchFilter.Filter, searchFilter.TextIndex
 
 	// Log parameters
-	log.Info(fmt.Sprintf("[acco-repo]acr#15 Filter parameters: %v", filters))
+	log.Info(fmt.Sprintf("[acco-repo]acr#15 Filter parameters: %v, sort: %s", filter, query.Page.Sort))
 
-	cursor, err := collection.Find(ctx, filters)
+	total, err := collection.CountDocuments(ctx, filter)
 	if err != nil {
-		log.Error(fmt.Sprintf("[acco-repo]acr#16 Failed to get accommodations: %v", err))
+		log.Error(fmt.Sprintf("[acco-repo]acr#16 Failed to count accommodations: %v", err))
+		return nil, err
+	}
+
+	terms := SearchTerms(query.Text)
+	var results []*SearchResult
+	switch {
+	case query.Near != nil:
+		results, err = ar.findNear(ctx, filter, query)
+	case len(terms) > 0 && !textIndex && query.Page.Sort == SortByRelevance:
+		results, err = ar.findPartialByRelevance(ctx, filter, terms, query.Page)
+	default:
+		findOptions := options.Find()
+		if textIndex {
+			score := bson.M{"score": bson.M{"$meta": "textScore"}}
+			findOptions.SetProjection(score)
+			if query.Page.Sort == SortByRelevance {
+				// Equal scores are ordered by ID, so offset pages don't repeat or skip results
+				findOptions.SetSort(bson.D{{Key: "score", Value: bson.M{"$meta": "textScore"}}, {Key: "_id", Value: 1}})
+			}
+		}
+		results, err = findPage[*SearchResult](ctx, collection, filter, query.Page, findOptions)
+	}
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#17 Failed to get accommodations: %v", err))
+		return nil, err
+	}
+
+	for _, result := range results {
+		result.Highlight(terms)
+	}
+
+	return newPage(results, total, query.Page, func(r *SearchResult) *Accommodation { return &r.Accommodation }), nil
+}
+
+// Counts all accommodations matching search filter per location, amenity and capacity bucket
+func (ar *AccommodationRepository) GetSearchFacets(ctx context.Context, searchFilter *SearchFilter) (*Facets, error) {
+	collection := ar.getAccommodationCollection()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: searchFilter.Filter}},
+		facetStage(),
+	}
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#41 Failed to aggregate search facets: %v", err))
 		return nil, err
 	}
 	defer cursor.Close(ctx)
 
-	var accommodations []*Accommodation
+	var results []facetResult
+	if err := cursor.All(ctx, &results); err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#42 Failed to decode search facets: %v", err))
+		return nil, err
+	}
+	if len(results) == 0 {
+		return (&facetResult{}).facets(), nil
+	}
+	return results[0].facets(), nil
+}
+
+// Returns unit counts of all accommodations matching search filter, by accommodation ID
+func (ar *AccommodationRepository) FindAccommodationUnits(ctx context.Context, searchFilter *SearchFilter) (map[primitive.ObjectID]int, error) {
+	collection := ar.getAccommodationCollection()
+
+	cursor, err := collection.Find(ctx, searchFilter.Filter, options.Find().SetProjection(bson.M{"_id": 1, "units": 1}))
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#26 Failed to get accommodation IDs: %v", err))
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var accommodations []Accommodation
 	if err := cursor.All(ctx, &accommodations); err != nil {
-		log.Error(fmt.Sprintf("[acco-repo]acr#17 Failed to iterate over all accommodations: %v", err))
+		log.Error(fmt.Sprintf("[acco-repo]acr#67 Failed to iterate over accommodation IDs: %v", err))
 		return nil, err
 	}
 
-	return accommodations, nil
+	units := make(map[primitive.ObjectID]int, len(accommodations))
+	for _, accommodation := range accommodations {
+		units[accommodation.ID] = accommodation.UnitCount()
+	}
+	return units, nil
+}
+
+// Builds filter matching search query, once per search since deciding on the text index takes a query.
+// Text index is used when it finds anything; when it finds nothing (e.g. query is only a part of a word)
+// and always with geo queries, which can't be combined with it, text is matched partially.
+func (ar *AccommodationRepository) BuildSearchFilter(ctx context.Context, query *SearchQuery) (*SearchFilter, error) {
+	filters := []bson.M{query.Filter, PublishedFilter()}
+	if query.Near != nil && query.MaxDistanceKm > 0 {
+		radius := bson.A{query.Near.Coordinates, query.MaxDistanceKm / earthRadiusKm}
+		filters = append(filters, bson.M{"position": bson.M{"$geoWithin": bson.M{"$centerSphere": radius}}})
+	}
+
+	terms := SearchTerms(query.Text)
+	if len(terms) == 0 {
+		return &SearchFilter{Filter: andFilters(filters...)}, nil
+	}
+
+	if query.Near == nil {
+		textFilter := andFilters(append(filters, bson.M{"$text": bson.M{"$search": query.Text}})...)
+		matches, err := ar.getAccommodationCollection().CountDocuments(ctx, textFilter)
+		if err != nil {
+			log.Error(fmt.Sprintf("[acco-repo]acr#22 Failed to run text search: %v", err))
+			return nil, err
+		}
+		if matches > 0 {
+			return &SearchFilter{Filter: textFilter, TextIndex: true}, nil
+		}
+	}
+
+	return &SearchFilter{Filter: andFilters(append(filters, PartialTextFilter(terms))...)}, nil
+}
+
+// Runs $geoNear so results carry their distance in km from the query point
+func (ar *AccommodationRepository) findNear(ctx context.Context, filter bson.M, query *SearchQuery) ([]*SearchResult, error) {
+	collection := ar.getAccommodationCollection()
+	page := query.Page
+
+	pipeline := mongo.Pipeline{{{Key: "$geoNear", Value: bson.M{
+		"near":          query.Near,
+		"distanceField": "distance",
+		// GeoJSON distances are in meters, results are returned in km
+		"distanceMultiplier": 0.001,
+		"spherical":          true,
+		"query":              andFilters(filter, page.keysetFilter()),
+	}}}}
+	if sort := page.sortSpec(); sort != nil {
+		pipeline = append(pipeline, bson.D{{Key: "$sort", Value: sort}})
+	}
+	if page.isOffsetBased() {
+		pipeline = append(pipeline, bson.D{{Key: "$skip", Value: page.offset()}})
+	}
+	pipeline = append(pipeline, bson.D{{Key: "$limit", Value: page.Size + 1}})
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#20 Failed to get accommodations near point: %v", err))
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []*SearchResult
+	if err := cursor.All(ctx, &results); err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#21 Failed to iterate over accommodations near point: %v", err))
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// Partial matches have no text score in Mongo, so they are scored in the aggregation, which keeps only
+// the requested page of them while sorting. Equal scores are ordered by ID, so offset pages don't repeat or skip results.
+func (ar *AccommodationRepository) findPartialByRelevance(ctx context.Context, filter bson.M, terms []string, page *PageRequest) ([]*SearchResult, error) {
+	collection := ar.getAccommodationCollection()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$addFields", Value: bson.M{"score": PartialMatchScore(terms)}}},
+		{{Key: "$sort", Value: bson.D{{Key: "score", Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$skip", Value: page.offset()}},
+		{{Key: "$limit", Value: int64(page.Size + 1)}},
+	}
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#23 Failed to run partial text search: %v", err))
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []*SearchResult
+	if err := cursor.All(ctx, &results); err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#24 Failed to iterate over partial text search results: %v", err))
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (ar *AccommodationRepository) findIDs(ctx context.Context, filter bson.M) ([]primitive.ObjectID, error) {
+	collection := ar.getAccommodationCollection()
+
+	cursor, err := collection.Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var documents []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &documents); err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, len(documents))
+	for i, document := range documents {
+		ids[i] = document.ID
+	}
+	return ids, nil
+}
+
+// Finds one page of documents matching filter; one document more than the page size is
+// fetched so the caller knows if there is a next page
+func findPage[T any](ctx context.Context, collection *mongo.Collection, filter bson.M, page *PageRequest, findOptions *options.FindOptions) ([]T, error) {
+	if sort := page.sortSpec(); sort != nil {
+		findOptions.SetSort(sort)
+	}
+	if page.isOffsetBased() {
+		findOptions.SetSkip(page.offset())
+	}
+	findOptions.SetLimit(int64(page.Size + 1))
+
+	cursor, err := collection.Find(ctx, andFilters(filter, page.keysetFilter()), findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var items []T
+	if err := cursor.All(ctx, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 func (ar *AccommodationRepository) getAccommodationCollection() *mongo.Collection {
@@ -0,0 +1,227 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+const DefaultLanguage = "en"
+
+// AmenityKey is the stable key of an amenity in the amenity catalog, e.g. "wifi"
+type AmenityKey string
+
+// Keys of amenities which clients used to send as numbers, indexed by that number.
+// The list is frozen, new amenities are added only to the catalog.
+var legacyAmenityKeys = []AmenityKey{
+	"essentials",
+	"wifi",
+	"parking",
+	"airConditioning",
+	"kitchen",
+	"tv",
+	"pool",
+	"petFriendly",
+	"hairDryer",
+	"iron",
+	"indoorFireplace",
+	"heating",
+	"washer",
+	"hangers",
+	"hotWater",
+	"privateBathroom",
+	"gym",
+	"smokingAllowed",
+}
+
+// Returns key of amenity which legacy clients send as number
+func LegacyAmenityKey(number int) (AmenityKey, bool) {
+	if number < 0 || number >= len(legacyAmenityKeys) {
+		return "", false
+	}
+	return legacyAmenityKeys[number], true
+}
+
+// Returns number legacy clients know amenity by
+func LegacyAmenityNumber(key AmenityKey) (int, bool) {
+	for number, legacyKey := range legacyAmenityKeys {
+		if legacyKey == key {
+			return number, true
+		}
+	}
+	return 0, false
+}
+
+// Accepts both keys and legacy numbers. Unknown numbers are kept as they are,
+// so validation against the catalog can report them.
+func (k *AmenityKey) UnmarshalJSON(b []byte) error {
+	var number int
+	if err := json.Unmarshal(b, &number); err == nil {
+		*k = amenityKeyFromNumber(number)
+		return nil
+	}
+
+	var key string
+	if err := json.Unmarshal(b, &key); err != nil {
+		return fmt.Errorf("amenity must be a key or a number: %w", err)
+	}
+	*k = AmenityKey(strings.TrimSpace(key))
+	return nil
+}
+
+// Accommodations stored before the catalog was introduced keep amenities as numbers
+// until they are migrated, those are decoded to keys as well
+func (k *AmenityKey) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	raw := bson.RawValue{Type: t, Value: data}
+	switch t {
+	case bsontype.String:
+		*k = AmenityKey(raw.StringValue())
+	case bsontype.Int32, bsontype.Int64, bsontype.Double:
+		number, ok := raw.AsInt64OK()
+		if !ok {
+			return fmt.Errorf("cannot decode amenity from BSON %s", t)
+		}
+		*k = amenityKeyFromNumber(int(number))
+	default:
+		return fmt.Errorf("cannot decode amenity from BSON %s", t)
+	}
+	return nil
+}
+
+func amenityKeyFromNumber(number int) AmenityKey {
+	if key, ok := LegacyAmenityKey(number); ok {
+		return key
+	}
+	return AmenityKey(strconv.Itoa(number))
+}
+
+// Amenity as defined in the amenity catalog.
+// Code is the number legacy clients use for it, amenities added later have none.
+type AmenityDefinition struct {
+	Key      AmenityKey        `json:"key" bson:"_id"`
+	Code     *int              `json:"code,omitempty" bson:"code,omitempty"`
+	Category string            `json:"category" bson:"category"`
+	Icon     string            `json:"icon" bson:"icon"`
+	Labels   map[string]string `json:"labels" bson:"labels"`
+	// Label in the language requested by the client
+	Label string `json:"label,omitempty" bson:"-"`
+}
+
+// Returns label in requested language, falling back to the default language and then to the key
+func (ad *AmenityDefinition) LabelFor(language string) string {
+	if label, ok := ad.Labels[language]; ok && label != "" {
+		return label
+	}
+	if label, ok := ad.Labels[DefaultLanguage]; ok && label != "" {
+		return label
+	}
+	return string(ad.Key)
+}
+
+// Snapshot of the amenity catalog, used to validate and resolve amenities without querying the database
+type AmenityCatalog struct {
+	amenities []*AmenityDefinition
+	byKey     map[AmenityKey]*AmenityDefinition
+}
+
+func NewAmenityCatalog(amenities []*AmenityDefinition) *AmenityCatalog {
+	ac := &AmenityCatalog{
+		amenities: amenities,
+		byKey:     make(map[AmenityKey]*AmenityDefinition, len(amenities)),
+	}
+	for _, amenity := range amenities {
+		ac.byKey[amenity.Key] = amenity
+	}
+	sort.SliceStable(ac.amenities, func(i, j int) bool {
+		if ac.amenities[i].Category != ac.amenities[j].Category {
+			return ac.amenities[i].Category < ac.amenities[j].Category
+		}
+		return ac.amenities[i].Key < ac.amenities[j].Key
+	})
+	return ac
+}
+
+func (ac *AmenityCatalog) Lookup(key AmenityKey) (*AmenityDefinition, bool) {
+	amenity, ok := ac.byKey[key]
+	return amenity, ok
+}
+
+// Resolves amenity given by key, legacy number or English label, all case-insensitive
+func (ac *AmenityCatalog) Resolve(value string) (AmenityKey, error) {
+	value = strings.TrimSpace(value)
+	if number, err := strconv.Atoi(value); err == nil {
+		key := amenityKeyFromNumber(number)
+		if _, ok := ac.byKey[key]; !ok {
+			return "", fmt.Errorf("unknown amenity %d", number)
+		}
+		return key, nil
+	}
+
+	for _, amenity := range ac.amenities {
+		if strings.EqualFold(string(amenity.Key), value) || strings.EqualFold(amenity.LabelFor(DefaultLanguage), value) {
+			return amenity.Key, nil
+		}
+	}
+	return "", fmt.Errorf("unknown amenity '%s'", value)
+}
+
+// Reports amenities which are not in the catalog
+func (ac *AmenityCatalog) Validate(amenities []AmenityKey) ValidationErrors {
+	var errs ValidationErrors
+	for i, amenity := range amenities {
+		if amenity == "" {
+			continue
+		}
+		if _, ok := ac.byKey[amenity]; !ok {
+			errs.Add(fmt.Sprintf("amenities[%d]", i), "unknown amenity '%s'", amenity)
+		}
+	}
+	return errs
+}
+
+// Returns copies of all amenities ordered by category and key, with labels in requested language
+func (ac *AmenityCatalog) Localized(language string) []*AmenityDefinition {
+	amenities := make([]*AmenityDefinition, len(ac.amenities))
+	for i, amenity := range ac.amenities {
+		localized := *amenity
+		localized.Label = amenity.LabelFor(language)
+		amenities[i] = &localized
+	}
+	return amenities
+}
+
+func legacyCode(number int) *int {
+	return &number
+}
+
+// Amenities the catalog is seeded with: all amenities legacy clients know by number, and newer ones
+func DefaultAmenities() []*AmenityDefinition {
+	return []*AmenityDefinition{
+		{Key: "essentials", Code: legacyCode(0), Category: "basics", Icon: "essentials.png", Labels: map[string]string{"en": "Essentials", "sr": "Osnovne potrepštine"}},
+		{Key: "wifi", Code: legacyCode(1), Category: "basics", Icon: "wifi.png", Labels: map[string]string{"en": "WiFi", "sr": "WiFi"}},
+		{Key: "parking", Code: legacyCode(2), Category: "parking", Icon: "parking.png", Labels: map[string]string{"en": "Parking", "sr": "Parking"}},
+		{Key: "airConditioning", Code: legacyCode(3), Category: "heatingAndCooling", Icon: "air-condition.png", Labels: map[string]string{"en": "Air conditioning", "sr": "Klima uređaj"}},
+		{Key: "kitchen", Code: legacyCode(4), Category: "kitchen", Icon: "kitchen.png", Labels: map[string]string{"en": "Kitchen", "sr": "Kuhinja"}},
+		{Key: "tv", Code: legacyCode(5), Category: "entertainment", Icon: "tv.png", Labels: map[string]string{"en": "TV", "sr": "Televizor"}},
+		{Key: "pool", Code: legacyCode(6), Category: "outdoor", Icon: "pool.png", Labels: map[string]string{"en": "Pool", "sr": "Bazen"}},
+		{Key: "petFriendly", Code: legacyCode(7), Category: "policies", Icon: "petFriendly.png", Labels: map[string]string{"en": "Pet friendly", "sr": "Kućni ljubimci dozvoljeni"}},
+		{Key: "hairDryer", Code: legacyCode(8), Category: "bathroom", Icon: "hairDryer.png", Labels: map[string]string{"en": "Hair dryer", "sr": "Fen za kosu"}},
+		{Key: "iron", Code: legacyCode(9), Category: "laundry", Icon: "iron.png", Labels: map[string]string{"en": "Iron", "sr": "Pegla"}},
+		{Key: "indoorFireplace", Code: legacyCode(10), Category: "heatingAndCooling", Icon: "indoorFireplace.png", Labels: map[string]string{"en": "Indoor fireplace", "sr": "Kamin"}},
+		{Key: "heating", Code: legacyCode(11), Category: "heatingAndCooling", Icon: "heating.png", Labels: map[string]string{"en": "Heating", "sr": "Grejanje"}},
+		{Key: "washer", Code: legacyCode(12), Category: "laundry", Icon: "washer.png", Labels: map[string]string{"en": "Washer", "sr": "Veš mašina"}},
+		{Key: "hangers", Code: legacyCode(13), Category: "laundry", Icon: "hanger.png", Labels: map[string]string{"en": "Hangers", "sr": "Vešalice"}},
+		{Key: "hotWater", Code: legacyCode(14), Category: "bathroom", Icon: "hotWater.png", Labels: map[string]string{"en": "Hot water", "sr": "Topla voda"}},
+		{Key: "privateBathroom", Code: legacyCode(15), Category: "bathroom", Icon: "privateBathroom.png", Labels: map[string]string{"en": "Private bathroom", "sr": "Privatno kupatilo"}},
+		{Key: "gym", Code: legacyCode(16), Category: "facilities", Icon: "gym.png", Labels: map[string]string{"en": "Gym", "sr": "Teretana"}},
+		{Key: "smokingAllowed", Code: legacyCode(17), Category: "policies", Icon: "smokingAllowed.png", Labels: map[string]string{"en": "Smoking allowed", "sr": "Pušenje dozvoljeno"}},
+		{Key: "evCharger", Category: "parking", Icon: "evCharger.png", Labels: map[string]string{"en": "EV charger", "sr": "Punjač za električna vozila"}},
+		{Key: "smokeAlarm", Category: "safety", Icon: "smokeAlarm.png", Labels: map[string]string{"en": "Smoke alarm", "sr": "Detektor dima"}},
+		{Key: "stepFreeEntrance", Category: "accessibility", Icon: "stepFreeEntrance.png", Labels: map[string]string{"en": "Step-free entrance", "sr": "Ulaz bez stepenika"}},
+	}
+}
@@ -0,0 +1,91 @@
+package data
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (ar *AccommodationRepository) GetAmenityCatalog(ctx context.Context) (*AmenityCatalog, error) {
+	collection := ar.getAmenityCollection()
+
+	cursor, err := collection.Find(ctx, bson.M{})
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#68 Failed to get amenity catalog: %v", err))
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var amenities []*AmenityDefinition
+	if err := cursor.All(ctx, &amenities); err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#69 Failed to iterate over amenity catalog: %v", err))
+		return nil, err
+	}
+
+	return NewAmenityCatalog(amenities), nil
+}
+
+// Adds default amenities missing from the catalog.
+// Amenities already in the catalog are left as they are, so changes made in the database are kept.
+func (ar *AccommodationRepository) SeedAmenityCatalog(ctx context.Context) error {
+	collection := ar.getAmenityCollection()
+
+	var models []mongo.WriteModel
+	for _, amenity := range DefaultAmenities() {
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": amenity.Key}).
+			SetUpdate(bson.M{"$setOnInsert": amenity}).
+			SetUpsert(true))
+	}
+
+	result, err := collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#70 Failed to seed amenity catalog: %v", err))
+		return err
+	}
+
+	log.Info(fmt.Sprintf("[acco-repo]acr#71 Added %d default amenities to catalog", result.UpsertedCount))
+	return nil
+}
+
+// Replaces amenity numbers stored before the catalog was introduced with amenity keys.
+// Returns number of migrated accommodations.
+func (ar *AccommodationRepository) MigrateLegacyAmenities(ctx context.Context) (int, error) {
+	collection := ar.getAccommodationCollection()
+
+	filter := bson.M{"amenities": bson.M{"$elemMatch": bson.M{"$type": "number"}}}
+	cursor, err := collection.Find(ctx, filter, options.Find().SetProjection(bson.M{"amenities": 1}))
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#72 Failed to find accommodations with legacy amenities: %v", err))
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	migrated := 0
+	for cursor.Next(ctx) {
+		// Numbers are decoded to keys by AmenityKey
+		var accommodation Accommodation
+		if err := cursor.Decode(&accommodation); err != nil {
+			log.Error(fmt.Sprintf("[acco-repo]acr#73 Failed to decode legacy amenities: %v", err))
+			return migrated, err
+		}
+
+		_, err := collection.UpdateOne(ctx, bson.M{"_id": accommodation.ID}, bson.M{"$set": bson.M{"amenities": accommodation.Amenities}})
+		if err != nil {
+			log.Error(fmt.Sprintf("[acco-repo]acr#74 Failed to migrate amenities of accommodation '%v': %v", accommodation.ID, err))
+			return migrated, err
+		}
+		migrated++
+	}
+
+	return migrated, cursor.Err()
+}
+
+func (ar *AccommodationRepository) getAmenityCollection() *mongo.Collection {
+	return ar.cli.Database("mongoDemo").Collection("amenities")
+}
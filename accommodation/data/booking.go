@@ -0,0 +1,19 @@
+package data
+
+// BookingMode decides whether guests book instantly or send requests the host accepts or declines
+type BookingMode string
+
+const (
+	BookingInstant BookingMode = "instant"
+	BookingRequest BookingMode = "request"
+)
+
+const (
+	DefaultRequestHoldHours = 24
+	MaxRequestHoldHours     = 72
+)
+
+// Empty mode is kept by accommodations created before booking modes were introduced and means instant booking
+func (m BookingMode) IsValid() bool {
+	return m == "" || m == BookingInstant || m == BookingRequest
+}
@@ -0,0 +1,147 @@
+package data
+
+import (
+	"fmt"
+	"math"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Locations with most matches are returned, the rest is left out
+const maxLocationFacets = 20
+
+// Lower bounds of guest capacity buckets, the last bucket is open-ended
+var capacityBuckets = []int{1, 3, 5, 7, 11}
+
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// Counts of search matches per location, amenity, property type and guest capacity (maxGuests) bucket.
+// Counts are computed over all matches of the query, not only the returned page.
+type Facets struct {
+	Locations     []FacetCount `json:"locations"`
+	Amenities     []FacetCount `json:"amenities"`
+	PropertyTypes []FacetCount `json:"propertyTypes"`
+	Capacity      []FacetCount `json:"capacity"`
+}
+
+// Search results page together with facet counts
+type SearchPage struct {
+	*Page[*SearchResult]
+	Facets *Facets `json:"facets"`
+}
+
+type facetGroup struct {
+	ID    interface{} `bson:"_id"`
+	Count int64       `bson:"count"`
+}
+
+type facetResult struct {
+	Locations     []facetGroup `bson:"locations"`
+	Amenities     []facetGroup `bson:"amenities"`
+	PropertyTypes []facetGroup `bson:"propertyTypes"`
+	Capacity      []facetGroup `bson:"capacity"`
+}
+
+// Builds $facet stage counting documents per location, amenity, property type and capacity bucket
+func facetStage() bson.D {
+	boundaries := bson.A{}
+	for _, bound := range capacityBuckets {
+		boundaries = append(boundaries, bound)
+	}
+	boundaries = append(boundaries, math.MaxInt32)
+
+	return bson.D{{Key: "$facet", Value: bson.M{
+		"locations": bson.A{
+			bson.M{"$group": bson.M{"_id": "$location", "count": bson.M{"$sum": 1}}},
+			bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+			bson.M{"$limit": maxLocationFacets},
+		},
+		"amenities": bson.A{
+			bson.M{"$unwind": "$amenities"},
+			bson.M{"$group": bson.M{"_id": "$amenities", "count": bson.M{"$sum": 1}}},
+			bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+		},
+		"propertyTypes": bson.A{
+			bson.M{"$group": bson.M{"_id": "$propertyType", "count": bson.M{"$sum": 1}}},
+			bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+		},
+		"capacity": bson.A{
+			bson.M{"$bucket": bson.M{
+				"groupBy":    "$maxGuests",
+				"boundaries": boundaries,
+				"default":    "other",
+				"output":     bson.M{"count": bson.M{"$sum": 1}},
+			}},
+		},
+	}}}
+}
+
+func (fr *facetResult) facets() *Facets {
+	facets := &Facets{
+		Locations:     []FacetCount{},
+		Amenities:     []FacetCount{},
+		PropertyTypes: []FacetCount{},
+		Capacity:      []FacetCount{},
+	}
+
+	for _, group := range fr.Locations {
+		location, _ := group.ID.(string)
+		if location == "" {
+			continue
+		}
+		facets.Locations = append(facets.Locations, FacetCount{Value: location, Count: group.Count})
+	}
+
+	for _, group := range fr.Amenities {
+		amenity, _ := group.ID.(string)
+		if amenity == "" {
+			continue
+		}
+		facets.Amenities = append(facets.Amenities, FacetCount{Value: amenity, Count: group.Count})
+	}
+
+	for _, group := range fr.PropertyTypes {
+		// Accommodations without property type are not shown
+		propertyType, _ := group.ID.(string)
+		if propertyType == "" {
+			continue
+		}
+		facets.PropertyTypes = append(facets.PropertyTypes, FacetCount{Value: propertyType, Count: group.Count})
+	}
+
+	for _, group := range fr.Capacity {
+		lower, ok := toInt(group.ID)
+		if !ok {
+			// Capacities outside of buckets (missing or invalid) are not shown
+			continue
+		}
+		facets.Capacity = append(facets.Capacity, FacetCount{Value: capacityLabel(lower), Count: group.Count})
+	}
+
+	return facets
+}
+
+// Label of capacity bucket starting at lower bound, e.g. "3-4" or "11+"
+func capacityLabel(lower int) string {
+	for i, bound := range capacityBuckets {
+		if bound == lower && i+1 < len(capacityBuckets) {
+			return fmt.Sprintf("%d-%d", lower, capacityBuckets[i+1]-1)
+		}
+	}
+	return fmt.Sprintf("%d+", lower)
+}
+
+func toInt(value interface{}) (int, bool) {
+	switch v := value.(type) {
+	case int32:
+		return int(v), true
+	case int64:
+		return int(v), true
+	case float64:
+		return int(v), true
+	}
+	return 0, false
+}
@@ -0,0 +1,111 @@
+package data
+
+import (
+	"encoding/json"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	geoPointType   = "Point"
+	geoPolygonType = "Polygon"
+
+	// Earth radius used to convert distances to radians for $centerSphere
+	earthRadiusKm = 6378.1
+)
+
+var ErrInvalidCoordinates = errors.New("latitude must be in [-90, 90] and longitude in [-180, 180]")
+
+// GeoPoint is stored as a GeoJSON point so it can be indexed with 2dsphere,
+// but is exposed to clients as a plain latitude/longitude pair.
+type GeoPoint struct {
+	Type        string    `bson:"type"`
+	Coordinates []float64 `bson:"coordinates"` // [longitude, latitude]
+}
+
+type latLng struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// BoundingBox describes a map viewport by its south-west and north-east corners
+type BoundingBox struct {
+	South float64
+	West  float64
+	North float64
+	East  float64
+}
+
+func NewGeoPoint(latitude, longitude float64) (*GeoPoint, error) {
+	if !validCoordinates(latitude, longitude) {
+		return nil, ErrInvalidCoordinates
+	}
+	return &GeoPoint{
+		Type:        geoPointType,
+		Coordinates: []float64{longitude, latitude},
+	}, nil
+}
+
+func (g *GeoPoint) Latitude() float64 {
+	return g.Coordinates[1]
+}
+
+func (g *GeoPoint) Longitude() float64 {
+	return g.Coordinates[0]
+}
+
+func (g GeoPoint) MarshalJSON() ([]byte, error) {
+	if len(g.Coordinates) != 2 {
+		return []byte("null"), nil
+	}
+	return json.Marshal(latLng{Latitude: g.Latitude(), Longitude: g.Longitude()})
+}
+
+func (g *GeoPoint) UnmarshalJSON(b []byte) error {
+	var ll latLng
+	if err := json.Unmarshal(b, &ll); err != nil {
+		return err
+	}
+	point, err := NewGeoPoint(ll.Latitude, ll.Longitude)
+	if err != nil {
+		return err
+	}
+	*g = *point
+	return nil
+}
+
+func NewBoundingBox(south, west, north, east float64) (*BoundingBox, error) {
+	if !validCoordinates(south, west) || !validCoordinates(north, east) {
+		return nil, ErrInvalidCoordinates
+	}
+	if south >= north || west >= east {
+		return nil, errors.New("south-west corner must be below and left of north-east corner")
+	}
+	return &BoundingBox{South: south, West: west, North: north, East: east}, nil
+}
+
+// Center of the viewport, used as the reference point for distance ordering
+func (b *BoundingBox) Center() *GeoPoint {
+	point, _ := NewGeoPoint((b.South+b.North)/2, (b.West+b.East)/2)
+	return point
+}
+
+// Returns GeoJSON polygon covering the viewport, usable in $geoWithin queries
+func (b *BoundingBox) Polygon() bson.M {
+	ring := [][]float64{
+		{b.West, b.South},
+		{b.East, b.South},
+		{b.East, b.North},
+		{b.West, b.North},
+		{b.West, b.South},
+	}
+	return bson.M{
+		"type":        geoPolygonType,
+		"coordinates": [][][]float64{ring},
+	}
+}
+
+func validCoordinates(latitude, longitude float64) bool {
+	return latitude >= -90 && latitude <= 90 && longitude >= -180 && longitude <= 180
+}
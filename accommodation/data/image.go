@@ -0,0 +1,46 @@
+package data
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Metadata of an accommodation image. Image content is kept in image storage under Path,
+// resized variants under paths in Variants.
+type ImageRecord struct {
+	ID              primitive.ObjectID `json:"id" bson:"_id"`
+	AccommodationID primitive.ObjectID `json:"accommodationId" bson:"accommodationId"`
+	Path            string             `json:"path" bson:"path"`
+	Variants        map[string]string  `json:"variants,omitempty" bson:"variants,omitempty"`
+	// Content types of variants, which are encoded anew and can differ from the original's.
+	// Missing for records created before they were stored.
+	VariantContentTypes map[string]string `json:"variantContentTypes,omitempty" bson:"variantContentTypes,omitempty"`
+	ContentType         string            `json:"contentType" bson:"contentType"`
+	Order               int               `json:"order" bson:"order"`
+	Caption             string            `json:"caption" bson:"caption"`
+	Cover               bool              `json:"cover" bson:"cover"`
+	Width               int               `json:"width" bson:"width"`
+	Height              int               `json:"height" bson:"height"`
+	Size                int64             `json:"size" bson:"size"`
+	CreatedAt           time.Time         `json:"createdAt" bson:"createdAt"`
+}
+
+// Every path in storage belonging to the image
+func (ir *ImageRecord) Paths() []string {
+	paths := []string{ir.Path}
+	for _, path := range ir.Variants {
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+type ImageOrder struct {
+	ImageIDs []primitive.ObjectID `json:"imageIds"`
+}
+
+// Partial update of image metadata, only specified fields are changed
+type ImageUpdate struct {
+	Caption *string `json:"caption"`
+	Cover   *bool   `json:"cover"`
+}
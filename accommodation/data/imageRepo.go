@@ -0,0 +1,197 @@
+package data
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (ar *AccommodationRepository) CreateImages(ctx context.Context, images []*ImageRecord) error {
+	collection := ar.getImageCollection()
+
+	documents := make([]interface{}, len(images))
+	for i, image := range images {
+		documents[i] = image
+	}
+
+	_, err := collection.InsertMany(ctx, documents)
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#27 Failed to create images: %v", err))
+		return err
+	}
+
+	return nil
+}
+
+// Returns images of accommodation in display order
+func (ar *AccommodationRepository) GetImagesForAccommodation(ctx context.Context, accID primitive.ObjectID) ([]*ImageRecord, error) {
+	collection := ar.getImageCollection()
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "order", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := collection.Find(ctx, bson.M{"accommodationId": accID}, findOptions)
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#28 Failed to get images for accommodation '%v': %v", accID, err))
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	images := []*ImageRecord{}
+	if err := cursor.All(ctx, &images); err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#29 Failed to iterate over images: %v", err))
+		return nil, err
+	}
+
+	return images, nil
+}
+
+// Returns images of accommodations in display order, indexed by accommodation ID
+func (ar *AccommodationRepository) GetImagesForAccommodations(ctx context.Context, accIDs []primitive.ObjectID) (map[primitive.ObjectID][]*ImageRecord, error) {
+	collection := ar.getImageCollection()
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "accommodationId", Value: 1}, {Key: "order", Value: 1}})
+	cursor, err := collection.Find(ctx, bson.M{"accommodationId": bson.M{"$in": accIDs}}, findOptions)
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#54 Failed to get images for accommodations: %v", err))
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var images []*ImageRecord
+	if err := cursor.All(ctx, &images); err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#55 Failed to iterate over images: %v", err))
+		return nil, err
+	}
+
+	grouped := make(map[primitive.ObjectID][]*ImageRecord)
+	for _, image := range images {
+		grouped[image.AccommodationID] = append(grouped[image.AccommodationID], image)
+	}
+	return grouped, nil
+}
+
+func (ar *AccommodationRepository) GetImage(ctx context.Context, accID, imageID primitive.ObjectID) (*ImageRecord, error) {
+	collection := ar.getImageCollection()
+
+	var image ImageRecord
+	err := collection.FindOne(ctx, bson.M{"_id": imageID, "accommodationId": accID}).Decode(&image)
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#30 Failed to get image '%v': %v", imageID, err))
+		return nil, err
+	}
+
+	return &image, nil
+}
+
+func (ar *AccommodationRepository) UpdateImageCaption(ctx context.Context, imageID primitive.ObjectID, caption string) error {
+	collection := ar.getImageCollection()
+
+	_, err := collection.UpdateByID(ctx, imageID, bson.M{"$set": bson.M{"caption": caption}})
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#31 Failed to update caption of image '%v': %v", imageID, err))
+		return err
+	}
+
+	return nil
+}
+
+// Marks image as the only cover image of accommodation
+func (ar *AccommodationRepository) SetCoverImage(ctx context.Context, accID, imageID primitive.ObjectID) error {
+	collection := ar.getImageCollection()
+
+	_, err := collection.UpdateMany(ctx,
+		bson.M{"accommodationId": accID, "_id": bson.M{"$ne": imageID}},
+		bson.M{"$set": bson.M{"cover": false}})
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#32 Failed to unset cover images of accommodation '%v': %v", accID, err))
+		return err
+	}
+
+	_, err = collection.UpdateByID(ctx, imageID, bson.M{"$set": bson.M{"cover": true}})
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#33 Failed to set cover image '%v': %v", imageID, err))
+		return err
+	}
+
+	return nil
+}
+
+// Sets order of accommodation images to the order of given IDs
+func (ar *AccommodationRepository) ReorderImages(ctx context.Context, accID primitive.ObjectID, imageIDs []primitive.ObjectID) error {
+	collection := ar.getImageCollection()
+
+	models := make([]mongo.WriteModel, len(imageIDs))
+	for i, imageID := range imageIDs {
+		models[i] = mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": imageID, "accommodationId": accID}).
+			SetUpdate(bson.M{"$set": bson.M{"order": i}})
+	}
+
+	_, err := collection.BulkWrite(ctx, models)
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#34 Failed to reorder images of accommodation '%v': %v", accID, err))
+		return err
+	}
+
+	return nil
+}
+
+func (ar *AccommodationRepository) DeleteImage(ctx context.Context, imageID primitive.ObjectID) error {
+	collection := ar.getImageCollection()
+
+	_, err := collection.DeleteOne(ctx, bson.M{"_id": imageID})
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#35 Failed to delete image '%v': %v", imageID, err))
+		return err
+	}
+
+	return nil
+}
+
+func (ar *AccommodationRepository) DeleteImagesForAccommodations(ctx context.Context, accIDs []primitive.ObjectID) error {
+	collection := ar.getImageCollection()
+
+	_, err := collection.DeleteMany(ctx, bson.M{"accommodationId": bson.M{"$in": accIDs}})
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#36 Failed to delete images of accommodations: %v", err))
+		return err
+	}
+
+	return nil
+}
+
+// Returns every storage path referenced by image records, including variants
+func (ar *AccommodationRepository) GetAllImagePaths(ctx context.Context) (map[string]bool, error) {
+	collection := ar.getImageCollection()
+
+	findOptions := options.Find().SetProjection(bson.M{"path": 1, "variants": 1})
+	cursor, err := collection.Find(ctx, bson.M{}, findOptions)
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#37 Failed to get image paths: %v", err))
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	paths := make(map[string]bool)
+	for cursor.Next(ctx) {
+		var image ImageRecord
+		if err := cursor.Decode(&image); err != nil {
+			log.Error(fmt.Sprintf("[acco-repo]acr#38 Failed to decode image paths: %v", err))
+			return nil, err
+		}
+		for _, path := range image.Paths() {
+			paths[path] = true
+		}
+	}
+
+	return paths, cursor.Err()
+}
+
+func (ar *AccommodationRepository) getImageCollection() *mongo.Collection {
+	return ar.cli.Database("mongoDemo").Collection("images")
+}
@@ -0,0 +1,158 @@
+package data
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	MaxRoomCount      = 50
+	MaxBedsPerRoom    = 20
+	MaxRoomNameLength = 100
+)
+
+// PropertyType describes what kind of place the accommodation is.
+// Room and shared room are parts of a place, all other types are rented as entire places.
+type PropertyType string
+
+const (
+	PropertyApartment  PropertyType = "apartment"
+	PropertyHouse      PropertyType = "house"
+	PropertyVilla      PropertyType = "villa"
+	PropertyCabin      PropertyType = "cabin"
+	PropertyGuesthouse PropertyType = "guesthouse"
+	PropertyRoom       PropertyType = "room"
+	PropertySharedRoom PropertyType = "sharedRoom"
+)
+
+var propertyTypes = []PropertyType{
+	PropertyApartment,
+	PropertyHouse,
+	PropertyVilla,
+	PropertyCabin,
+	PropertyGuesthouse,
+	PropertyRoom,
+	PropertySharedRoom,
+}
+
+type BedType string
+
+const (
+	BedSingle BedType = "single"
+	BedDouble BedType = "double"
+	BedQueen  BedType = "queen"
+	BedKing   BedType = "king"
+	BedSofa   BedType = "sofaBed"
+	BedBunk   BedType = "bunkBed"
+	BedCrib   BedType = "crib"
+)
+
+var bedTypes = []BedType{BedSingle, BedDouble, BedQueen, BedKing, BedSofa, BedBunk, BedCrib}
+
+// Room of the accommodation with the beds in it, e.g. "Bedroom 1" with one queen bed
+type Room struct {
+	Name string     `json:"name" bson:"name"`
+	Beds []BedCount `json:"beds" bson:"beds"`
+}
+
+type BedCount struct {
+	Type  BedType `json:"type" bson:"type"`
+	Count int     `json:"count" bson:"count"`
+}
+
+func (pt PropertyType) IsValid() bool {
+	for _, valid := range propertyTypes {
+		if pt == valid {
+			return true
+		}
+	}
+	return false
+}
+
+func (pt PropertyType) IsEntirePlace() bool {
+	return pt.IsValid() && pt != PropertyRoom && pt != PropertySharedRoom
+}
+
+// Property types rented as entire places, used by search filter
+func EntirePlaceTypes() []PropertyType {
+	var types []PropertyType
+	for _, pt := range propertyTypes {
+		if pt.IsEntirePlace() {
+			types = append(types, pt)
+		}
+	}
+	return types
+}
+
+// Parses property type by name, case-insensitive
+func ParsePropertyType(value string) (PropertyType, error) {
+	value = strings.TrimSpace(value)
+	names := make([]string, len(propertyTypes))
+	for i, pt := range propertyTypes {
+		if strings.EqualFold(string(pt), value) {
+			return pt, nil
+		}
+		names[i] = string(pt)
+	}
+	return "", fmt.Errorf("unknown property type '%s', allowed values are: %s", value, strings.Join(names, ", "))
+}
+
+func (bt BedType) IsValid() bool {
+	for _, valid := range bedTypes {
+		if bt == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// Total number of beds in all rooms
+func (a *Accommodation) RoomBeds() int {
+	total := 0
+	for _, room := range a.Rooms {
+		for _, bed := range room.Beds {
+			total += bed.Count
+		}
+	}
+	return total
+}
+
+func (a *Accommodation) validateLayout(errs *ValidationErrors) {
+	if a.PropertyType != "" && !a.PropertyType.IsValid() {
+		_, err := ParsePropertyType(string(a.PropertyType))
+		errs.Add("propertyType", err.Error())
+	}
+
+	validateCount(errs, "bedrooms", a.Bedrooms)
+	validateCount(errs, "beds", a.Beds)
+	validateCount(errs, "bathrooms", a.Bathrooms)
+
+	if len(a.Rooms) > MaxRoomCount {
+		errs.Add("rooms", "must have at most %d rooms", MaxRoomCount)
+		return
+	}
+	for i, room := range a.Rooms {
+		field := fmt.Sprintf("rooms[%d]", i)
+		validateText(errs, field+".name", room.Name, MaxRoomNameLength, true)
+		for j, bed := range room.Beds {
+			bedField := fmt.Sprintf("%s.beds[%d]", field, j)
+			if !bed.Type.IsValid() {
+				errs.Add(bedField+".type", "unknown bed type '%s'", bed.Type)
+			}
+			if bed.Count < 1 || bed.Count > MaxBedsPerRoom {
+				errs.Add(bedField+".count", "must be between 1 and %d", MaxBedsPerRoom)
+			}
+		}
+	}
+
+	// Beds listed per room must add up to the total shown in search
+	if len(a.Rooms) > 0 && a.RoomBeds() != a.Beds {
+		errs.Add("beds", "must equal the number of beds in rooms (%d)", a.RoomBeds())
+	}
+}
+
+func validateCount(errs *ValidationErrors, field string, value int) {
+	if value < 0 || value > MaxRoomCount {
+		errs.Add(field, "must be between 0 and %d", MaxRoomCount)
+	}
+}
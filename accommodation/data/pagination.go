@@ -0,0 +1,217 @@
+package data
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+type SortOrder string
+
+const (
+	SortByNewest    SortOrder = "newest"
+	SortByName      SortOrder = "name"
+	SortByCapacity  SortOrder = "capacity"
+	SortByRelevance SortOrder = "relevance"
+	SortByDistance  SortOrder = "distance"
+)
+
+// Sort orders available on every accommodation listing
+var ListSortOrders = []SortOrder{SortByNewest, SortByName, SortByCapacity}
+
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+// PageRequest describes which page of a listing is requested.
+// Name, newest and capacity orders continue from the last returned accommodation (keyset),
+// relevance and distance orders, which are computed per query, continue from an offset. Offset pages are
+// computed again for every page, so accommodations added, changed or removed in the meantime can shift
+// results between pages, repeating or skipping some of them.
+type PageRequest struct {
+	Size  int
+	Sort  SortOrder
+	token *pageToken
+}
+
+// Continuation token, sent to clients base64 encoded so its content stays opaque
+type pageToken struct {
+	Sort     SortOrder          `json:"s"`
+	ID       primitive.ObjectID `json:"i,omitempty"`
+	Name     string             `json:"n,omitempty"`
+	Capacity int                `json:"c,omitempty"`
+	Offset   int64              `json:"o,omitempty"`
+}
+
+type Page[T any] struct {
+	Items         []T    `json:"items"`
+	TotalCount    int64  `json:"totalCount"`
+	NextPageToken string `json:"nextPageToken,omitempty"`
+}
+
+// Parses pageSize, sort and pageToken query parameters.
+// Sort must be one of allowed orders, defaultSort is used when it is not specified.
+func ParsePageRequest(query url.Values, defaultSort SortOrder, allowed []SortOrder) (*PageRequest, error) {
+	page := &PageRequest{Size: DefaultPageSize, Sort: defaultSort}
+
+	if size := query.Get("pageSize"); size != "" {
+		value, err := strconv.Atoi(size)
+		if err != nil || value < 1 || value > MaxPageSize {
+			return nil, fmt.Errorf("pageSize must be a number between 1 and %d", MaxPageSize)
+		}
+		page.Size = value
+	}
+
+	if sort := query.Get("sort"); sort != "" {
+		page.Sort = SortOrder(sort)
+	}
+	if !containsSortOrder(allowed, page.Sort) {
+		return nil, fmt.Errorf("invalid sort '%s', allowed values are %v", page.Sort, allowed)
+	}
+
+	if token := query.Get("pageToken"); token != "" {
+		decoded, err := decodePageToken(token)
+		if err != nil || decoded.Sort != page.Sort {
+			return nil, ErrInvalidPageToken
+		}
+		page.token = decoded
+	}
+
+	return page, nil
+}
+
+// Identifies requested page, e.g. for caching it
+func (p *PageRequest) Key() string {
+	token := ""
+	if p.token != nil {
+		token = p.token.encode()
+	}
+	return fmt.Sprintf("%d:%s:%s", p.Size, p.Sort, token)
+}
+
+func (p *PageRequest) isOffsetBased() bool {
+	return p.Sort == SortByRelevance || p.Sort == SortByDistance
+}
+
+func (p *PageRequest) offset() int64 {
+	if p.token == nil {
+		return 0
+	}
+	return p.token.Offset
+}
+
+// Returns sort specification for keyset orders, nil for orders computed per query
+func (p *PageRequest) sortSpec() bson.D {
+	switch p.Sort {
+	case SortByName:
+		return bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}}
+	case SortByCapacity:
+		return bson.D{{Key: "maxGuests", Value: -1}, {Key: "_id", Value: 1}}
+	case SortByNewest:
+		return bson.D{{Key: "_id", Value: -1}}
+	}
+	return nil
+}
+
+// Returns filter selecting accommodations after the one the token points to.
+// Returns nil for the first page and for offset based orders.
+func (p *PageRequest) keysetFilter() bson.M {
+	if p.token == nil || p.isOffsetBased() {
+		return nil
+	}
+
+	t := p.token
+	switch p.Sort {
+	case SortByName:
+		return bson.M{"$or": bson.A{
+			bson.M{"name": bson.M{"$gt": t.Name}},
+			bson.M{"name": t.Name, "_id": bson.M{"$gt": t.ID}},
+		}}
+	case SortByCapacity:
+		return bson.M{"$or": bson.A{
+			bson.M{"maxGuests": bson.M{"$lt": t.Capacity}},
+			bson.M{"maxGuests": t.Capacity, "_id": bson.M{"$gt": t.ID}},
+		}}
+	case SortByNewest:
+		return bson.M{"_id": bson.M{"$lt": t.ID}}
+	}
+	return nil
+}
+
+// Returns token for the page following the current one, which ended with last accommodation
+func (p *PageRequest) nextPageToken(last *Accommodation) string {
+	next := &pageToken{Sort: p.Sort}
+	if p.isOffsetBased() {
+		next.Offset = p.offset() + int64(p.Size)
+	} else {
+		next.ID = last.ID
+		next.Name = last.Name
+		next.Capacity = last.MaxGuests
+	}
+	return next.encode()
+}
+
+// Builds page from items fetched with limit Size+1; the extra item only signals a next page
+func newPage[T any](items []T, total int64, p *PageRequest, accommodation func(T) *Accommodation) *Page[T] {
+	page := &Page[T]{Items: items, TotalCount: total}
+	if page.Items == nil {
+		page.Items = []T{}
+	}
+	if len(items) > p.Size {
+		page.Items = items[:p.Size]
+		page.NextPageToken = p.nextPageToken(accommodation(page.Items[p.Size-1]))
+	}
+	return page
+}
+
+func (t *pageToken) encode() string {
+	raw, _ := json.Marshal(t)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodePageToken(token string) (*pageToken, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, err
+	}
+	var decoded pageToken
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return nil, err
+	}
+	return &decoded, nil
+}
+
+// Combines filters into one, skipping empty ones
+func andFilters(filters ...bson.M) bson.M {
+	var clauses bson.A
+	for _, filter := range filters {
+		if len(filter) > 0 {
+			clauses = append(clauses, filter)
+		}
+	}
+	switch len(clauses) {
+	case 0:
+		return bson.M{}
+	case 1:
+		return clauses[0].(bson.M)
+	}
+	return bson.M{"$and": clauses}
+}
+
+func containsSortOrder(orders []SortOrder, order SortOrder) bool {
+	for _, o := range orders {
+		if o == order {
+			return true
+		}
+	}
+	return false
+}
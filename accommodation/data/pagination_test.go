@@ -0,0 +1,122 @@
+package data
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func accommodations(n int) []*Accommodation {
+	items := make([]*Accommodation, n)
+	for i := range items {
+		items[i] = &Accommodation{ID: primitive.NewObjectID(), Name: string(rune('a' + i)), MaxGuests: i + 1}
+	}
+	return items
+}
+
+func self(a *Accommodation) *Accommodation { return a }
+
+func TestPageTokenRoundTrip(t *testing.T) {
+	items := accommodations(3)
+
+	tests := []struct {
+		name string
+		sort SortOrder
+		want pageToken
+	}{
+		{
+			name: "keyset order continues from last item",
+			sort: SortByName,
+			want: pageToken{Sort: SortByName, ID: items[1].ID, Name: "b", Capacity: 2},
+		},
+		{
+			name: "offset order continues from offset",
+			sort: SortByRelevance,
+			want: pageToken{Sort: SortByRelevance, Offset: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed := []SortOrder{tt.sort}
+			first, err := ParsePageRequest(url.Values{"pageSize": {"2"}}, tt.sort, allowed)
+			if err != nil {
+				t.Fatalf("failed to parse first page: %v", err)
+			}
+
+			page := newPage(items, 3, first, self)
+			if page.NextPageToken == "" {
+				t.Fatal("first page has no next page token")
+			}
+
+			next, err := ParsePageRequest(url.Values{"pageSize": {"2"}, "pageToken": {page.NextPageToken}}, tt.sort, allowed)
+			if err != nil {
+				t.Fatalf("failed to parse next page: %v", err)
+			}
+			if !reflect.DeepEqual(*next.token, tt.want) {
+				t.Errorf("token %+v, want %+v", *next.token, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePageRequestRejectsTamperedToken(t *testing.T) {
+	valid := (&pageToken{Sort: SortByName, ID: primitive.NewObjectID(), Name: "a"}).encode()
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "not base64", token: valid + "!"},
+		{name: "not JSON", token: base64.RawURLEncoding.EncodeToString([]byte("name=a"))},
+		{name: "id of wrong type", token: base64.RawURLEncoding.EncodeToString([]byte(`{"s":"name","i":"a"}`))},
+		{name: "token of other sort", token: (&pageToken{Sort: SortByCapacity, Capacity: 2}).encode()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := url.Values{"sort": {string(SortByName)}, "pageToken": {tt.token}}
+			_, err := ParsePageRequest(query, SortByNewest, ListSortOrders)
+			if !errors.Is(err, ErrInvalidPageToken) {
+				t.Errorf("error %v, want %v", err, ErrInvalidPageToken)
+			}
+		})
+	}
+}
+
+func TestNewPage(t *testing.T) {
+	tests := []struct {
+		name     string
+		fetched  int
+		items    int
+		hasToken bool
+	}{
+		{name: "empty page", fetched: 0, items: 0},
+		{name: "last page", fetched: 2, items: 2},
+		{name: "full last page", fetched: 3, items: 3},
+		{name: "page followed by another", fetched: 4, items: 3, hasToken: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &PageRequest{Size: 3, Sort: SortByNewest}
+			var fetched []*Accommodation
+			if tt.fetched > 0 {
+				fetched = accommodations(tt.fetched)
+			}
+
+			page := newPage(fetched, int64(tt.fetched), p, self)
+
+			if page.Items == nil || len(page.Items) != tt.items {
+				t.Errorf("items %v, want %v", page.Items, tt.items)
+			}
+			if (page.NextPageToken != "") != tt.hasToken {
+				t.Errorf("next page token %q, want token %v", page.NextPageToken, tt.hasToken)
+			}
+		})
+	}
+}
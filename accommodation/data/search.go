@@ -0,0 +1,249 @@
+package data
+
+import (
+	"encoding/json"
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	highlightOpen  = "<em>"
+	highlightClose = "</em>"
+	snippetLength  = 160
+)
+
+// Text fields covered by full-text search and their relevance weights.
+// Same weights are used for the text index and for partial-match scoring.
+var textSearchWeights = bson.D{
+	{Key: "name", Value: 10},
+	{Key: "location", Value: 5},
+	{Key: "description", Value: 1},
+}
+
+// Accommodation returned from search together with its ranking information
+type SearchResult struct {
+	Accommodation `bson:",inline"`
+	Distance      float64           `json:"distance,omitempty" bson:"distance,omitempty"`
+	Score         float64           `json:"score,omitempty" bson:"score,omitempty"`
+	Highlights    map[string]string `json:"highlights,omitempty" bson:"-"`
+	// Units free for the whole stay, set only when searching by dates
+	RemainingUnits int `json:"remainingUnits,omitempty" bson:"-"`
+}
+
+// Accommodation has its own encoding, which would otherwise be used for the whole result,
+// so ranking information is added to it
+func (sr SearchResult) MarshalJSON() ([]byte, error) {
+	accommodation, err := json.Marshal(sr.Accommodation)
+	if err != nil {
+		return nil, err
+	}
+	ranking, err := json.Marshal(struct {
+		Distance       float64           `json:"distance,omitempty"`
+		Score          float64           `json:"score,omitempty"`
+		Highlights     map[string]string `json:"highlights,omitempty"`
+		RemainingUnits int               `json:"remainingUnits,omitempty"`
+	}{sr.Distance, sr.Score, sr.Highlights, sr.RemainingUnits})
+	if err != nil {
+		return nil, err
+	}
+	if len(ranking) == len("{}") {
+		return accommodation, nil
+	}
+	return append(append(accommodation[:len(accommodation)-1], ','), ranking[1:]...), nil
+}
+
+// Search criteria for GetFilteredAccommodations
+type SearchQuery struct {
+	Filter        bson.M    // Attribute filters (location, guests, amenities, viewport...)
+	Text          string    // Full-text query
+	Near          *GeoPoint // Reference point for distance, results are ordered by distance from it by default
+	MaxDistanceKm float64   // Limits distance from Near, 0 means no limit
+	Page          *PageRequest
+}
+
+// Filter matching a search query, built by BuildSearchFilter
+type SearchFilter struct {
+	Filter bson.M
+	// Text query is matched with the text index, otherwise partially
+	TextIndex bool
+}
+
+// Returns the filter narrowed down to accommodations with given IDs
+func (sf *SearchFilter) WithIDs(ids []primitive.ObjectID) *SearchFilter {
+	return &SearchFilter{Filter: andFilters(sf.Filter, bson.M{"_id": bson.M{"$in": ids}}), TextIndex: sf.TextIndex}
+}
+
+// Returns default and allowed sort orders for the query.
+// Distance order is available only with geo queries, relevance only with text queries.
+func (sq *SearchQuery) SortOrders() (SortOrder, []SortOrder) {
+	allowed := append([]SortOrder{}, ListSortOrders...)
+	if sq.Near != nil {
+		return SortByDistance, append(allowed, SortByDistance)
+	}
+	if len(SearchTerms(sq.Text)) > 0 {
+		return SortByRelevance, append(allowed, SortByRelevance)
+	}
+	return SortByNewest, allowed
+}
+
+// Splits search query into lowercase terms, dropping punctuation and duplicates
+func SearchTerms(q string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	seen := make(map[string]bool, len(fields))
+	var terms []string
+	for _, field := range fields {
+		if !seen[field] {
+			seen[field] = true
+			terms = append(terms, field)
+		}
+	}
+	return terms
+}
+
+// Returns filter matching accommodations where every term is contained,
+// case-insensitive, in at least one of the text fields
+func PartialTextFilter(terms []string) bson.M {
+	clauses := bson.A{}
+	for _, term := range terms {
+		pattern := containsRegex(term)
+		var fields bson.A
+		for _, field := range textSearchWeights {
+			fields = append(fields, bson.M{field.Key: pattern})
+		}
+		clauses = append(clauses, bson.M{"$or": fields})
+	}
+	return bson.M{"$and": clauses}
+}
+
+// Returns aggregation expression scoring partial matches with text index weights, prefering whole words
+// and word prefixes: every word of a text field equal to a term adds the field weight, a word starting with
+// the term half of it and a word containing it a quarter. Mongo lowercases only ASCII letters of the words.
+func PartialMatchScore(terms []string) bson.M {
+	scores := bson.A{}
+	for _, field := range textSearchWeights {
+		weight := float64(field.Value.(int))
+		words := bson.M{"$map": bson.M{
+			"input": bson.M{"$regexFindAll": bson.M{
+				"input": bson.M{"$toLower": bson.M{"$ifNull": bson.A{"$" + field.Key, ""}}},
+				"regex": `[\p{L}\p{N}]+`,
+			}},
+			"in": "$$this.match",
+		}}
+		for _, term := range terms {
+			position := bson.M{"$indexOfCP": bson.A{"$$word", term}}
+			scores = append(scores, bson.M{"$sum": bson.M{"$map": bson.M{
+				"input": words,
+				"as":    "word",
+				"in": bson.M{"$switch": bson.M{
+					"branches": bson.A{
+						bson.M{"case": bson.M{"$eq": bson.A{"$$word", term}}, "then": weight},
+						bson.M{"case": bson.M{"$eq": bson.A{position, 0}}, "then": weight / 2},
+						bson.M{"case": bson.M{"$gt": bson.A{position, 0}}, "then": weight / 4},
+					},
+					"default": 0,
+				}},
+			}}})
+		}
+	}
+	return bson.M{"$add": scores}
+}
+
+// Fills Highlights with snippets of text fields where matched terms are wrapped in <em> tags.
+// Snippets are HTML escaped, so they are safe to render as markup.
+func (sr *SearchResult) Highlight(terms []string) {
+	for _, field := range textSearchWeights {
+		snippet, found := highlight(sr.textField(field.Key), terms)
+		if !found {
+			continue
+		}
+		if sr.Highlights == nil {
+			sr.Highlights = make(map[string]string)
+		}
+		sr.Highlights[field.Key] = snippet
+	}
+}
+
+func (sr *SearchResult) textField(name string) string {
+	switch name {
+	case "name":
+		return sr.Name
+	case "location":
+		return sr.Location
+	case "description":
+		return sr.Description
+	}
+	return ""
+}
+
+func highlight(text string, terms []string) (string, bool) {
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	marked := make([]bool, len(runes))
+	found := false
+	for _, term := range terms {
+		termRunes := []rune(term)
+		for i := 0; i+len(termRunes) <= len(lower); i++ {
+			if string(lower[i:i+len(termRunes)]) == term {
+				for j := i; j < i+len(termRunes); j++ {
+					marked[j] = true
+				}
+				found = true
+			}
+		}
+	}
+	if !found {
+		return "", false
+	}
+
+	// Long texts are cut to a window around the first match
+	start, end := 0, len(runes)
+	if end > snippetLength {
+		first := 0
+		for !marked[first] {
+			first++
+		}
+		start = first - snippetLength/4
+		if start < 0 {
+			start = 0
+		}
+		end = start + snippetLength
+		if end > len(runes) {
+			end = len(runes)
+		}
+	}
+
+	var sb strings.Builder
+	if start > 0 {
+		sb.WriteString("…")
+	}
+	for i := start; i < end; i++ {
+		if marked[i] && (i == start || !marked[i-1]) {
+			sb.WriteString(highlightOpen)
+		}
+		sb.WriteString(html.EscapeString(string(runes[i])))
+		if marked[i] && (i == end-1 || !marked[i+1]) {
+			sb.WriteString(highlightClose)
+		}
+	}
+	if end < len(runes) {
+		sb.WriteString("…")
+	}
+
+	return sb.String(), true
+}
+
+func containsRegex(term string) bson.M {
+	return bson.M{"$regex": regexp.QuoteMeta(term), "$options": "i"}
+}
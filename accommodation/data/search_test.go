@@ -0,0 +1,133 @@
+package data
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestSearchTerms(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{name: "empty query", query: "  ", want: nil},
+		{name: "terms are lowercased", query: "Sea VIEW", want: []string{"sea", "view"}},
+		{name: "punctuation separates terms", query: "villa, pool!(new)", want: []string{"villa", "pool", "new"}},
+		{name: "regex characters are dropped", query: "a.*b [c]+", want: []string{"a", "b", "c"}},
+		{name: "duplicates are dropped", query: "Pool pool POOL spa", want: []string{"pool", "spa"}},
+		{name: "letters and numbers of any script", query: "Kuća 2 Šabac", want: []string{"kuća", "2", "šabac"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SearchTerms(tt.query); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("terms %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPartialTextFilter(t *testing.T) {
+	filter := PartialTextFilter([]string{"sea", "a.b", "(x+"})
+
+	clauses := filter["$and"].(bson.A)
+	if len(clauses) != 3 {
+		t.Fatalf("clauses %v, want one per term", clauses)
+	}
+
+	tests := []struct {
+		name      string
+		clause    int
+		pattern   string
+		matches   []string
+		unmatched []string
+	}{
+		{name: "plain term", clause: 0, pattern: "sea", matches: []string{"Seaside", "by the SEA"}, unmatched: []string{"se a"}},
+		{name: "dot is matched literally", clause: 1, pattern: `a\.b`, matches: []string{"A.B"}, unmatched: []string{"axb"}},
+		{name: "invalid regex is escaped", clause: 2, pattern: `\(x\+`, matches: []string{"(x+y)"}, unmatched: []string{"xx"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := clauses[tt.clause].(bson.M)["$or"].(bson.A)
+			if len(fields) != len(textSearchWeights) {
+				t.Fatalf("fields %v, want every text field", fields)
+			}
+			for i, field := range textSearchWeights {
+				want := bson.M{field.Key: bson.M{"$regex": tt.pattern, "$options": "i"}}
+				if !reflect.DeepEqual(fields[i], want) {
+					t.Errorf("field filter %v, want %v", fields[i], want)
+				}
+			}
+
+			re := regexp.MustCompile("(?i)" + tt.pattern)
+			for _, text := range tt.matches {
+				if !re.MatchString(text) {
+					t.Errorf("pattern %s doesn't match %q", tt.pattern, text)
+				}
+			}
+			for _, text := range tt.unmatched {
+				if re.MatchString(text) {
+					t.Errorf("pattern %s matches %q", tt.pattern, text)
+				}
+			}
+		})
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	long := strings.Repeat("a", 100) + " pool " + strings.Repeat("b", 200)
+
+	tests := []struct {
+		name   string
+		result Accommodation
+		terms  []string
+		want   map[string]string
+	}{
+		{
+			name:   "no match",
+			result: Accommodation{Name: "Loft", Location: "Novi Sad"},
+			terms:  []string{"pool"},
+		},
+		{
+			name:   "matches are wrapped case-insensitively",
+			result: Accommodation{Name: "Pool house", Location: "Novi Sad", Description: "Quiet"},
+			terms:  []string{"pool", "sad"},
+			want:   map[string]string{"name": "<em>Pool</em> house", "location": "Novi <em>Sad</em>"},
+		},
+		{
+			name:   "overlapping matches are merged",
+			result: Accommodation{Name: "Seaside"},
+			terms:  []string{"seas", "side"},
+			want:   map[string]string{"name": "<em>Seaside</em>"},
+		},
+		{
+			name:   "text is HTML escaped",
+			result: Accommodation{Name: `<b>Pool</b> & "spa"`},
+			terms:  []string{"pool", "spa"},
+			want:   map[string]string{"name": `&lt;b&gt;<em>Pool</em>&lt;/b&gt; &amp; &#34;<em>spa</em>&#34;`},
+		},
+		{
+			name:   "long text is cut around first match",
+			result: Accommodation{Description: long},
+			terms:  []string{"pool"},
+			// Snippet of 160 runes starts 40 runes before the match
+			want: map[string]string{"description": "…" + strings.Repeat("a", 39) + " <em>pool</em> " + strings.Repeat("b", 115) + "…"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &SearchResult{Accommodation: tt.result}
+			result.Highlight(tt.terms)
+			if !reflect.DeepEqual(result.Highlights, tt.want) {
+				t.Errorf("highlights %q, want %q", result.Highlights, tt.want)
+			}
+		})
+	}
+}
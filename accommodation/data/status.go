@@ -0,0 +1,88 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// AccommodationStatus controls visibility of accommodation to guests.
+// Only published accommodations are listed and searched, hosts always see all of their own.
+type AccommodationStatus string
+
+const (
+	StatusDraft     AccommodationStatus = "draft"
+	StatusPublished AccommodationStatus = "published"
+	StatusPaused    AccommodationStatus = "paused"
+	StatusArchived  AccommodationStatus = "archived"
+)
+
+var (
+	ErrInvalidStatusTransition = errors.New("invalid status transition")
+	ErrStatusChanged           = errors.New("accommodation status was changed in the meantime")
+)
+
+// Allowed transitions from each status.
+// Archived accommodation can only be brought back as a draft, to be reviewed before publishing again.
+var statusTransitions = map[AccommodationStatus][]AccommodationStatus{
+	StatusDraft:     {StatusPublished, StatusArchived},
+	StatusPublished: {StatusPaused, StatusArchived},
+	StatusPaused:    {StatusPublished, StatusArchived},
+	StatusArchived:  {StatusDraft},
+}
+
+type StatusChange struct {
+	Status AccommodationStatus `json:"status"`
+}
+
+func (s AccommodationStatus) IsValid() bool {
+	_, ok := statusTransitions[s]
+	return ok
+}
+
+func (s AccommodationStatus) CanTransitionTo(target AccommodationStatus) bool {
+	for _, allowed := range statusTransitions[s] {
+		if allowed == target {
+			return true
+		}
+	}
+	return false
+}
+
+// Accommodations created before statuses were introduced have none and count as published
+func (a *Accommodation) CurrentStatus() AccommodationStatus {
+	if a.Status == "" {
+		return StatusPublished
+	}
+	return a.Status
+}
+
+// Checks that accommodation can be moved to target status
+func (a *Accommodation) ValidateTransition(target AccommodationStatus) error {
+	if !target.IsValid() {
+		return fmt.Errorf("unknown status '%s'", target)
+	}
+	current := a.CurrentStatus()
+	if !current.CanTransitionTo(target) {
+		return fmt.Errorf("%w from '%s' to '%s'", ErrInvalidStatusTransition, current, target)
+	}
+	if target == StatusPublished {
+		if a.Name == "" || a.Location == "" || a.MaxGuests < 1 {
+			return errors.New("name, location and maximum number of guests are required for publishing")
+		}
+	}
+	return nil
+}
+
+// Filter matching accommodations visible to guests
+func PublishedFilter() bson.M {
+	return statusFilter(StatusPublished)
+}
+
+func statusFilter(status AccommodationStatus) bson.M {
+	if status == StatusPublished {
+		return bson.M{"status": bson.M{"$in": bson.A{StatusPublished, nil}}}
+	}
+	return bson.M{"status": status}
+}
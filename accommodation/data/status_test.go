@@ -0,0 +1,100 @@
+package data
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateTransition(t *testing.T) {
+	statuses := []AccommodationStatus{StatusDraft, StatusPublished, StatusPaused, StatusArchived}
+
+	tests := []struct {
+		name    string
+		from    AccommodationStatus
+		allowed []AccommodationStatus
+	}{
+		{name: "from draft", from: StatusDraft, allowed: []AccommodationStatus{StatusPublished, StatusArchived}},
+		{name: "from published", from: StatusPublished, allowed: []AccommodationStatus{StatusPaused, StatusArchived}},
+		{name: "from paused", from: StatusPaused, allowed: []AccommodationStatus{StatusPublished, StatusArchived}},
+		{name: "from archived", from: StatusArchived, allowed: []AccommodationStatus{StatusDraft}},
+		{name: "empty status counts as published", from: "", allowed: []AccommodationStatus{StatusPaused, StatusArchived}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, target := range statuses {
+				allowed := false
+				for _, status := range tt.allowed {
+					allowed = allowed || status == target
+				}
+
+				accommodation := &Accommodation{Name: "Loft", Location: "Novi Sad", MaxGuests: 2, Status: tt.from}
+				err := accommodation.ValidateTransition(target)
+				if allowed && err != nil {
+					t.Errorf("transition to %s: %v, want allowed", target, err)
+				}
+				if !allowed && !errors.Is(err, ErrInvalidStatusTransition) {
+					t.Errorf("transition to %s: %v, want %v", target, err, ErrInvalidStatusTransition)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateTransitionChecks(t *testing.T) {
+	tests := []struct {
+		name          string
+		accommodation Accommodation
+		target        AccommodationStatus
+		valid         bool
+	}{
+		{
+			name:          "unknown target status",
+			accommodation: Accommodation{Name: "Loft", Location: "Novi Sad", MaxGuests: 2, Status: StatusDraft},
+			target:        "deleted",
+		},
+		{
+			name:          "empty target status",
+			accommodation: Accommodation{Name: "Loft", Location: "Novi Sad", MaxGuests: 2, Status: StatusDraft},
+			target:        "",
+		},
+		{
+			name:          "incomplete draft can't be published",
+			accommodation: Accommodation{Name: "Loft", Status: StatusDraft},
+			target:        StatusPublished,
+		},
+		{
+			name:          "incomplete draft can be archived",
+			accommodation: Accommodation{Name: "Loft", Status: StatusDraft},
+			target:        StatusArchived,
+			valid:         true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.accommodation.ValidateTransition(tt.target)
+			if (err == nil) != tt.valid {
+				t.Errorf("error %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestCurrentStatus(t *testing.T) {
+	tests := []struct {
+		status AccommodationStatus
+		want   AccommodationStatus
+	}{
+		{status: "", want: StatusPublished},
+		{status: StatusDraft, want: StatusDraft},
+		{status: StatusPaused, want: StatusPaused},
+	}
+
+	for _, tt := range tests {
+		accommodation := &Accommodation{Status: tt.status}
+		if got := accommodation.CurrentStatus(); got != tt.want {
+			t.Errorf("current status of %q is %v, want %v", tt.status, got, tt.want)
+		}
+	}
+}
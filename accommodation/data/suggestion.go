@@ -0,0 +1,75 @@
+package data
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	DefaultSuggestionLimit = 10
+	MaxSuggestionLimit     = 50
+)
+
+// Letters which don't decompose into base letter and accent
+var letterReplacer = strings.NewReplacer("đ", "d", "ł", "l", "ø", "o", "ß", "ss", "æ", "ae", "œ", "oe")
+
+// Location suggested for autocomplete, with number of published accommodations in it.
+// Locations differing only in case and accents share one suggestion.
+type LocationSuggestion struct {
+	Key      string `json:"-" bson:"_id"`
+	Location string `json:"location" bson:"location"`
+	Count    int64  `json:"count" bson:"count"`
+	// Normalized location from the start of each word, so "sad" finds "Novi Sad"
+	Terms []string `json:"-" bson:"terms"`
+}
+
+// Returns lowercase text without accents, e.g. "Čačak" becomes "cacak".
+// Suggestions are stored and looked up by normalized location.
+func NormalizeLocation(text string) string {
+	stripAccents := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	normalized, _, err := transform.String(stripAccents, strings.ToLower(text))
+	if err != nil {
+		normalized = strings.ToLower(text)
+	}
+	return strings.Join(strings.Fields(letterReplacer.Replace(normalized)), " ")
+}
+
+// Groups location counts by normalized location.
+// Most common spelling of a location is the one shown to users.
+func buildLocationSuggestions(counts map[string]int64) []*LocationSuggestion {
+	suggestions := make(map[string]*LocationSuggestion)
+	spellings := make(map[string]int64)
+
+	for location, count := range counts {
+		key := NormalizeLocation(location)
+		if key == "" {
+			continue
+		}
+
+		suggestion, ok := suggestions[key]
+		if !ok {
+			suggestion = &LocationSuggestion{Key: key}
+			suggestions[key] = suggestion
+		}
+		suggestion.Count += count
+
+		if count > spellings[key] || (count == spellings[key] && location < suggestion.Location) {
+			spellings[key] = count
+			suggestion.Location = strings.TrimSpace(location)
+		}
+	}
+
+	result := make([]*LocationSuggestion, 0, len(suggestions))
+	for key, suggestion := range suggestions {
+		words := strings.Fields(key)
+		for i := range words {
+			suggestion.Terms = append(suggestion.Terms, strings.Join(words[i:], " "))
+		}
+		result = append(result, suggestion)
+	}
+	return result
+}
@@ -0,0 +1,102 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	log "github.com/sirupsen/logrus"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Returns locations whose words start with prefix, those with most published accommodations first
+func (ar *AccommodationRepository) SuggestLocations(ctx context.Context, prefix string, limit int) ([]*LocationSuggestion, error) {
+	collection := ar.getSuggestionCollection()
+
+	suggestions := []*LocationSuggestion{}
+	prefix = NormalizeLocation(prefix)
+	if prefix == "" {
+		return suggestions, nil
+	}
+
+	// Anchored case-sensitive regex is answered from the terms index
+	filter := bson.M{"terms": bson.M{"$regex": "^" + regexp.QuoteMeta(prefix)}}
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#43 Failed to get location suggestions: %v", err))
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &suggestions); err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#44 Failed to iterate over location suggestions: %v", err))
+		return nil, err
+	}
+
+	return suggestions, nil
+}
+
+// Recomputes location suggestions from published accommodations.
+// Suggestions are upserted and the ones without accommodations removed, so lookups keep working during rebuild.
+func (ar *AccommodationRepository) RebuildLocationSuggestions(ctx context.Context) (int, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: PublishedFilter()}},
+		{{Key: "$group", Value: bson.M{"_id": "$location", "count": bson.M{"$sum": 1}}}},
+	}
+	cursor, err := ar.getAccommodationCollection().Aggregate(ctx, pipeline)
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#45 Failed to count accommodations per location: %v", err))
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var groups []struct {
+		Location string `bson:"_id"`
+		Count    int64  `bson:"count"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#46 Failed to decode location counts: %v", err))
+		return 0, err
+	}
+
+	counts := make(map[string]int64, len(groups))
+	for _, group := range groups {
+		counts[group.Location] = group.Count
+	}
+	suggestions := buildLocationSuggestions(counts)
+
+	collection := ar.getSuggestionCollection()
+	keys := bson.A{}
+	if len(suggestions) > 0 {
+		models := make([]mongo.WriteModel, len(suggestions))
+		for i, suggestion := range suggestions {
+			keys = append(keys, suggestion.Key)
+			models[i] = mongo.NewReplaceOneModel().
+				SetFilter(bson.M{"_id": suggestion.Key}).
+				SetReplacement(suggestion).
+				SetUpsert(true)
+		}
+		if _, err := collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false)); err != nil {
+			log.Error(fmt.Sprintf("[acco-repo]acr#47 Failed to write location suggestions: %v", err))
+			return 0, err
+		}
+	}
+
+	if _, err := collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$nin": keys}}); err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#48 Failed to remove stale location suggestions: %v", err))
+		return 0, err
+	}
+
+	return len(suggestions), nil
+}
+
+func (ar *AccommodationRepository) getSuggestionCollection() *mongo.Collection {
+	return ar.cli.Database("mongoDemo").Collection("locationSuggestions")
+}
@@ -0,0 +1,418 @@
+package data
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+
+	MaxImportRows = 1000
+
+	// Separates amenities, rooms and image URLs inside one CSV cell
+	listSeparator = "|"
+)
+
+// Header of exported CSV files; imported files must have the same columns, in any order.
+// Columns id, status and images are exported for reference and ignored on import.
+var transferColumns = []string{
+	"externalRef", "id", "name", "location", "description", "amenities",
+	"minGuests", "maxGuests", "latitude", "longitude", "propertyType",
+	"bedrooms", "beds", "bathrooms", "rooms", "units", "bookingMode", "requestHoldHours", "status", "images",
+}
+
+// Accommodation as exported to and imported from CSV and JSON files.
+// Accommodations are matched by ExternalRef, the reference used by the host's own or previous platform.
+// Records without ExternalRef are matched by ID, so exported accommodations created without reference can be re-imported.
+type ListingRecord struct {
+	ExternalRef string   `json:"externalRef"`
+	ID          string   `json:"id,omitempty"`
+	Name        string   `json:"name"`
+	Location    string   `json:"location"`
+	Description string   `json:"description,omitempty"`
+	Amenities   []string `json:"amenities"`
+	MinGuests   int      `json:"minGuests"`
+	MaxGuests   int      `json:"maxGuests"`
+	Latitude    *float64 `json:"latitude,omitempty"`
+	Longitude   *float64 `json:"longitude,omitempty"`
+	// Rooms are written to CSV as "Bedroom 1: queen x1, single x2|Living room: sofaBed x1"
+	PropertyType string `json:"propertyType,omitempty"`
+	Bedrooms     int    `json:"bedrooms"`
+	Beds         int    `json:"beds"`
+	Bathrooms    int    `json:"bathrooms"`
+	Rooms        []Room `json:"rooms,omitempty"`
+	Units        int    `json:"units,omitempty"`
+	BookingMode  string `json:"bookingMode,omitempty"`
+	// Hours a booking request holds the dates
+	RequestHoldHours int      `json:"requestHoldHours,omitempty"`
+	Status           string   `json:"status,omitempty"`
+	Images           []string `json:"images,omitempty"`
+}
+
+// Outcome of importing one record. Row is 1-based and counts data rows, without CSV header.
+type ImportRowResult struct {
+	Row         int              `json:"row"`
+	ExternalRef string           `json:"externalRef,omitempty"`
+	Action      string           `json:"action"`
+	ID          string           `json:"id,omitempty"`
+	Errors      ValidationErrors `json:"errors,omitempty"`
+}
+
+const (
+	ImportCreate = "create"
+	ImportUpdate = "update"
+	ImportFailed = "failed"
+)
+
+type ImportReport struct {
+	DryRun  bool               `json:"dryRun"`
+	Created int                `json:"created"`
+	Updated int                `json:"updated"`
+	Failed  int                `json:"failed"`
+	Rows    []*ImportRowResult `json:"rows"`
+}
+
+func (ir *ImportReport) Add(result *ImportRowResult) {
+	switch result.Action {
+	case ImportCreate:
+		ir.Created++
+	case ImportUpdate:
+		ir.Updated++
+	default:
+		ir.Failed++
+	}
+	ir.Rows = append(ir.Rows, result)
+}
+
+func NewListingRecord(accommodation *Accommodation, images []string) *ListingRecord {
+	record := &ListingRecord{
+		ExternalRef:      accommodation.ExternalRef,
+		ID:               accommodation.ID.Hex(),
+		Name:             accommodation.Name,
+		Location:         accommodation.Location,
+		Description:      accommodation.Description,
+		Amenities:        make([]string, len(accommodation.Amenities)),
+		MinGuests:        accommodation.MinGuests,
+		MaxGuests:        accommodation.MaxGuests,
+		PropertyType:     string(accommodation.PropertyType),
+		Bedrooms:         accommodation.Bedrooms,
+		Beds:             accommodation.Beds,
+		Bathrooms:        accommodation.Bathrooms,
+		Rooms:            accommodation.Rooms,
+		Units:            accommodation.Units,
+		BookingMode:      string(accommodation.BookingMode),
+		RequestHoldHours: accommodation.RequestHoldHours,
+		Status:           string(accommodation.CurrentStatus()),
+		Images:           images,
+	}
+	for i, amenity := range accommodation.Amenities {
+		record.Amenities[i] = string(amenity)
+	}
+	if accommodation.Position != nil {
+		latitude, longitude := accommodation.Position.Latitude(), accommodation.Position.Longitude()
+		record.Latitude, record.Longitude = &latitude, &longitude
+	}
+	return record
+}
+
+// Converts record to accommodation and validates it with the same rules as create and update.
+// Amenities are resolved with the catalog, by key, legacy number or English label.
+func (lr *ListingRecord) Accommodation(catalog *AmenityCatalog) (*Accommodation, ValidationErrors) {
+	var errs ValidationErrors
+
+	accommodation := &Accommodation{
+		ExternalRef:      strings.TrimSpace(lr.ExternalRef),
+		Name:             lr.Name,
+		Location:         lr.Location,
+		Description:      lr.Description,
+		Amenities:        []AmenityKey{},
+		MinGuests:        lr.MinGuests,
+		MaxGuests:        lr.MaxGuests,
+		Bedrooms:         lr.Bedrooms,
+		Beds:             lr.Beds,
+		Bathrooms:        lr.Bathrooms,
+		Rooms:            lr.Rooms,
+		Units:            lr.Units,
+		BookingMode:      BookingMode(strings.TrimSpace(lr.BookingMode)),
+		RequestHoldHours: lr.RequestHoldHours,
+	}
+
+	if accommodation.ExternalRef == "" {
+		if id := strings.TrimSpace(lr.ID); id == "" {
+			errs.Add("externalRef", "is required for records without id")
+		} else if _, err := primitive.ObjectIDFromHex(id); err != nil {
+			errs.Add("id", "is not a valid ID")
+		}
+	}
+
+	if strings.TrimSpace(lr.PropertyType) != "" {
+		propertyType, err := ParsePropertyType(lr.PropertyType)
+		if err != nil {
+			errs.Add("propertyType", err.Error())
+		}
+		accommodation.PropertyType = propertyType
+	}
+
+	for i, name := range lr.Amenities {
+		amenity, err := catalog.Resolve(name)
+		if err != nil {
+			errs.Add(fmt.Sprintf("amenities[%d]", i), err.Error())
+			continue
+		}
+		accommodation.Amenities = append(accommodation.Amenities, amenity)
+	}
+
+	switch {
+	case lr.Latitude != nil && lr.Longitude != nil:
+		position, err := NewGeoPoint(*lr.Latitude, *lr.Longitude)
+		if err != nil {
+			errs.Add("position", err.Error())
+		}
+		accommodation.Position = position
+	case lr.Latitude != nil || lr.Longitude != nil:
+		errs.Add("position", "both latitude and longitude must be specified")
+	}
+
+	errs = append(errs, accommodation.Validate()...)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return accommodation, nil
+}
+
+func WriteListingsJSON(w io.Writer, records []*ListingRecord) error {
+	return json.NewEncoder(w).Encode(records)
+}
+
+func WriteListingsCSV(w io.Writer, records []*ListingRecord) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(transferColumns); err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		row := []string{
+			record.ExternalRef,
+			record.ID,
+			record.Name,
+			record.Location,
+			record.Description,
+			strings.Join(record.Amenities, listSeparator),
+			strconv.Itoa(record.MinGuests),
+			strconv.Itoa(record.MaxGuests),
+			formatCoordinate(record.Latitude),
+			formatCoordinate(record.Longitude),
+			record.PropertyType,
+			strconv.Itoa(record.Bedrooms),
+			strconv.Itoa(record.Beds),
+			strconv.Itoa(record.Bathrooms),
+			formatRooms(record.Rooms),
+			strconv.Itoa(record.Units),
+			record.BookingMode,
+			strconv.Itoa(record.RequestHoldHours),
+			record.Status,
+			strings.Join(record.Images, listSeparator),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// Reads JSON array of listing records. Records which can't be decoded fail the whole file.
+func ReadListingsJSON(r io.Reader) ([]*ListingRecord, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+
+	var records []*ListingRecord
+	if err := decoder.Decode(&records); err != nil {
+		return nil, err
+	}
+	if len(records) > MaxImportRows {
+		return nil, fmt.Errorf("at most %d records can be imported at once", MaxImportRows)
+	}
+	return records, nil
+}
+
+// Reads CSV file with header row. Cells which can't be parsed are reported per row,
+// in the returned map indexed by 0-based record index.
+func ReadListingsCSV(r io.Reader) ([]*ListingRecord, map[int]ValidationErrors, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil, errors.New("CSV file is empty")
+		}
+		return nil, nil, err
+	}
+
+	known := make(map[string]bool, len(transferColumns))
+	for _, column := range transferColumns {
+		known[column] = true
+	}
+	columns := make(map[string]int, len(header))
+	for i, column := range header {
+		column = strings.TrimSpace(column)
+		if !known[column] {
+			return nil, nil, fmt.Errorf("unknown column '%s'", column)
+		}
+		columns[column] = i
+	}
+
+	var records []*ListingRecord
+	rowErrors := make(map[int]ValidationErrors)
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(records) == MaxImportRows {
+			return nil, nil, fmt.Errorf("at most %d records can be imported at once", MaxImportRows)
+		}
+
+		record, errs := parseCSVRow(row, columns)
+		if errs != nil {
+			rowErrors[len(records)] = errs
+		}
+		records = append(records, record)
+	}
+
+	return records, rowErrors, nil
+}
+
+func parseCSVRow(row []string, columns map[string]int) (*ListingRecord, ValidationErrors) {
+	var errs ValidationErrors
+	cell := func(column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+	number := func(column string) int {
+		value := cell(column)
+		if value == "" {
+			return 0
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			errs.Add(column, "must be a whole number")
+		}
+		return n
+	}
+	coordinate := func(column string) *float64 {
+		value := cell(column)
+		if value == "" {
+			return nil
+		}
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			errs.Add(column, "must be a number")
+			return nil
+		}
+		return &f
+	}
+
+	rooms, err := parseRooms(cell("rooms"))
+	if err != nil {
+		errs.Add("rooms", err.Error())
+	}
+
+	record := &ListingRecord{
+		ExternalRef:      cell("externalRef"),
+		Name:             cell("name"),
+		Location:         cell("location"),
+		Description:      cell("description"),
+		Amenities:        splitList(cell("amenities")),
+		MinGuests:        number("minGuests"),
+		MaxGuests:        number("maxGuests"),
+		Latitude:         coordinate("latitude"),
+		Longitude:        coordinate("longitude"),
+		PropertyType:     cell("propertyType"),
+		Bedrooms:         number("bedrooms"),
+		Beds:             number("beds"),
+		Bathrooms:        number("bathrooms"),
+		Rooms:            rooms,
+		Units:            number("units"),
+		BookingMode:      cell("bookingMode"),
+		RequestHoldHours: number("requestHoldHours"),
+	}
+	return record, errs
+}
+
+// Formats rooms for CSV cell, e.g. "Bedroom 1: queen x1, single x2|Living room: sofaBed x1"
+func formatRooms(rooms []Room) string {
+	formatted := make([]string, len(rooms))
+	for i, room := range rooms {
+		beds := make([]string, len(room.Beds))
+		for j, bed := range room.Beds {
+			beds[j] = fmt.Sprintf("%s x%d", bed.Type, bed.Count)
+		}
+		formatted[i] = room.Name + ": " + strings.Join(beds, ", ")
+	}
+	return strings.Join(formatted, listSeparator)
+}
+
+// Parses rooms formatted by formatRooms. Bed count may be left out, e.g. "Bedroom: queen".
+func parseRooms(value string) ([]Room, error) {
+	var rooms []Room
+	for _, item := range splitList(value) {
+		separator := strings.LastIndex(item, ":")
+		if separator < 0 {
+			return nil, fmt.Errorf("room '%s' must be written as 'name: bed x count, ...'", item)
+		}
+
+		room := Room{Name: strings.TrimSpace(item[:separator]), Beds: []BedCount{}}
+		for _, bed := range strings.Split(item[separator+1:], ",") {
+			bed = strings.TrimSpace(bed)
+			if bed == "" {
+				continue
+			}
+			bedType, count, found := strings.Cut(bed, " x")
+			n := 1
+			if found {
+				var err error
+				if n, err = strconv.Atoi(strings.TrimSpace(count)); err != nil {
+					return nil, fmt.Errorf("invalid bed count in '%s'", bed)
+				}
+			}
+			room.Beds = append(room.Beds, BedCount{Type: BedType(strings.TrimSpace(bedType)), Count: n})
+		}
+		rooms = append(rooms, room)
+	}
+	return rooms, nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, listSeparator) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func formatCoordinate(value *float64) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*value, 'f', -1, 64)
+}
@@ -0,0 +1,102 @@
+package data
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	MaxNameLength        = 100
+	MaxLocationLength    = 200
+	MaxDescriptionLength = 5000
+	MaxGuestsLimit       = 100
+	MaxExternalRefLength = 100
+	MaxUnits             = 500
+)
+
+// Violation of a validation rule by one field of the payload
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// All violations found in a payload, reported to clients together
+type ValidationErrors []FieldError
+
+func (ve ValidationErrors) Error() string {
+	messages := make([]string, len(ve))
+	for i, fe := range ve {
+		messages[i] = fe.Field + ": " + fe.Message
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+func (ve *ValidationErrors) Add(field, format string, args ...interface{}) {
+	*ve = append(*ve, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Checks accommodation sent by client, same rules apply on create and update.
+// Returns nil when accommodation is valid.
+func (a *Accommodation) Validate() ValidationErrors {
+	var errs ValidationErrors
+
+	validateText(&errs, "name", a.Name, MaxNameLength, true)
+	validateText(&errs, "location", a.Location, MaxLocationLength, true)
+	validateText(&errs, "description", a.Description, MaxDescriptionLength, false)
+	validateText(&errs, "externalRef", a.ExternalRef, MaxExternalRefLength, false)
+
+	if a.MinGuests < 1 {
+		errs.Add("minGuests", "must be at least 1")
+	}
+	if a.MaxGuests < 1 {
+		errs.Add("maxGuests", "must be at least 1")
+	} else if a.MaxGuests > MaxGuestsLimit {
+		errs.Add("maxGuests", "must be at most %d", MaxGuestsLimit)
+	}
+	if a.MinGuests >= 1 && a.MaxGuests >= 1 && a.MinGuests > a.MaxGuests {
+		errs.Add("minGuests", "must not be greater than maxGuests")
+	}
+
+	if a.Units < 0 || a.Units > MaxUnits {
+		errs.Add("units", "must be between 1 and %d, or 0 for one unit", MaxUnits)
+	}
+
+	if !a.BookingMode.IsValid() {
+		errs.Add("bookingMode", "must be %s or %s", BookingInstant, BookingRequest)
+	}
+	if a.RequestHoldHours < 0 || a.RequestHoldHours > MaxRequestHoldHours {
+		errs.Add("requestHoldHours", "must be between 1 and %d, or 0 for the default of %d", MaxRequestHoldHours, DefaultRequestHoldHours)
+	}
+
+	// Amenities are checked against the amenity catalog by its caller, see AmenityCatalog.Validate
+	seen := make(map[AmenityKey]bool, len(a.Amenities))
+	for i, amenity := range a.Amenities {
+		field := fmt.Sprintf("amenities[%d]", i)
+		if amenity == "" {
+			errs.Add(field, "is required")
+			continue
+		}
+		if seen[amenity] {
+			errs.Add(field, "duplicate amenity %s", amenity)
+		}
+		seen[amenity] = true
+	}
+
+	a.validateLayout(&errs)
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func validateText(errs *ValidationErrors, field, value string, maxLength int, required bool) {
+	if required && strings.TrimSpace(value) == "" {
+		errs.Add(field, "is required")
+		return
+	}
+	if utf8.RuneCountInString(value) > maxLength {
+		errs.Add(field, "must be at most %d characters long", maxLength)
+	}
+}
@@ -0,0 +1,102 @@
+package data
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func validAccommodation() *Accommodation {
+	return &Accommodation{
+		Name:      "Sea view apartment",
+		Location:  "Budva",
+		Amenities: []AmenityKey{"wifi", "parking"},
+		MinGuests: 1,
+		MaxGuests: 4,
+		Beds:      1,
+		Rooms:     []Room{{Name: "Bedroom", Beds: []BedCount{{Type: "double", Count: 1}}}},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(a *Accommodation)
+		fields []string
+	}{
+		{
+			name:   "valid accommodation",
+			change: func(a *Accommodation) {},
+		},
+		{
+			name:   "blank name",
+			change: func(a *Accommodation) { a.Name = "  " },
+			fields: []string{"name"},
+		},
+		{
+			name:   "too long description",
+			change: func(a *Accommodation) { a.Description = strings.Repeat("a", MaxDescriptionLength+1) },
+			fields: []string{"description"},
+		},
+		{
+			name:   "min guests above max guests",
+			change: func(a *Accommodation) { a.MinGuests = 5 },
+			fields: []string{"minGuests"},
+		},
+		{
+			name:   "max guests above limit",
+			change: func(a *Accommodation) { a.MaxGuests = MaxGuestsLimit + 1 },
+			fields: []string{"maxGuests"},
+		},
+		{
+			name:   "zero units count as one",
+			change: func(a *Accommodation) { a.Units = 0 },
+		},
+		{
+			name:   "units above limit",
+			change: func(a *Accommodation) { a.Units = MaxUnits + 1 },
+			fields: []string{"units"},
+		},
+		{
+			name:   "unknown booking mode",
+			change: func(a *Accommodation) { a.BookingMode = "later" },
+			fields: []string{"bookingMode"},
+		},
+		{
+			name:   "empty and duplicate amenities",
+			change: func(a *Accommodation) { a.Amenities = []AmenityKey{"wifi", "", "wifi"} },
+			fields: []string{"amenities[1]", "amenities[2]"},
+		},
+		{
+			name:   "unknown bed type",
+			change: func(a *Accommodation) { a.Rooms[0].Beds[0].Type = "hammock" },
+			fields: []string{"rooms[0].beds[0].type"},
+		},
+		{
+			name: "every violation is reported",
+			change: func(a *Accommodation) {
+				a.Name = ""
+				a.Location = ""
+				a.MinGuests = 0
+				a.MaxGuests = 0
+				a.RequestHoldHours = -1
+			},
+			fields: []string{"name", "location", "minGuests", "maxGuests", "requestHoldHours"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accommodation := validAccommodation()
+			tt.change(accommodation)
+
+			var fields []string
+			for _, fieldErr := range accommodation.Validate() {
+				fields = append(fields, fieldErr.Field)
+			}
+			if !reflect.DeepEqual(fields, tt.fields) {
+				t.Errorf("errors for %v, want %v", fields, tt.fields)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package data
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	MaxWishlistNameLength     = 100
+	MaxWishlistsPerGuest      = 50
+	MaxWishlistAccommodations = 500
+)
+
+// Named list of accommodations saved by a guest
+type Wishlist struct {
+	ID               primitive.ObjectID   `json:"id" bson:"_id"`
+	GuestID          primitive.ObjectID   `json:"guestId" bson:"guestId"`
+	Name             string               `json:"name" bson:"name"`
+	AccommodationIDs []primitive.ObjectID `json:"accommodationIds" bson:"accommodationIds"`
+	CreatedAt        time.Time            `json:"createdAt" bson:"createdAt"`
+	UpdatedAt        time.Time            `json:"updatedAt" bson:"updatedAt"`
+}
+
+// Wishlist with its accommodations resolved, in the order they were saved.
+// Accommodations which are not published at the moment are left out.
+type WishlistDetails struct {
+	*Wishlist
+	Accommodations []*Accommodation `json:"accommodations"`
+}
+
+// Body of create and rename requests
+type WishlistName struct {
+	Name string `json:"name"`
+}
+
+func (wn *WishlistName) Validate() ValidationErrors {
+	var errs ValidationErrors
+	wn.Name = strings.TrimSpace(wn.Name)
+	if wn.Name == "" {
+		errs.Add("name", "is required")
+	} else if utf8.RuneCountInString(wn.Name) > MaxWishlistNameLength {
+		errs.Add("name", "must be at most %d characters long", MaxWishlistNameLength)
+	}
+	return errs
+}
+
+func NewWishlistDetails(wishlist *Wishlist, accommodations []Accommodation) *WishlistDetails {
+	byID := make(map[primitive.ObjectID]*Accommodation, len(accommodations))
+	for i := range accommodations {
+		byID[accommodations[i].ID] = &accommodations[i]
+	}
+
+	details := &WishlistDetails{Wishlist: wishlist, Accommodations: []*Accommodation{}}
+	for _, id := range wishlist.AccommodationIDs {
+		if accommodation, ok := byID[id]; ok && accommodation.CurrentStatus() == StatusPublished {
+			details.Accommodations = append(details.Accommodations, accommodation)
+		}
+	}
+	return details
+}
@@ -0,0 +1,144 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (ar *AccommodationRepository) CreateWishlist(ctx context.Context, wishlist *Wishlist) error {
+	collection := ar.getWishlistCollection()
+
+	_, err := collection.InsertOne(ctx, wishlist)
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#56 Failed to create wishlist: %v", err))
+		return err
+	}
+
+	return nil
+}
+
+// Returns wishlists of guest, oldest first
+func (ar *AccommodationRepository) GetWishlistsForGuest(ctx context.Context, guestID primitive.ObjectID) ([]*Wishlist, error) {
+	collection := ar.getWishlistCollection()
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	cursor, err := collection.Find(ctx, bson.M{"guestId": guestID}, findOptions)
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#57 Failed to get wishlists of guest '%v': %v", guestID, err))
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	wishlists := []*Wishlist{}
+	if err := cursor.All(ctx, &wishlists); err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#58 Failed to iterate over wishlists: %v", err))
+		return nil, err
+	}
+
+	return wishlists, nil
+}
+
+func (ar *AccommodationRepository) CountWishlistsForGuest(ctx context.Context, guestID primitive.ObjectID) (int64, error) {
+	count, err := ar.getWishlistCollection().CountDocuments(ctx, bson.M{"guestId": guestID})
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#59 Failed to count wishlists of guest '%v': %v", guestID, err))
+		return 0, err
+	}
+	return count, nil
+}
+
+// Returns wishlist only if it belongs to guest, mongo.ErrNoDocuments otherwise
+func (ar *AccommodationRepository) GetWishlist(ctx context.Context, guestID, id primitive.ObjectID) (*Wishlist, error) {
+	collection := ar.getWishlistCollection()
+
+	var wishlist Wishlist
+	err := collection.FindOne(ctx, bson.M{"_id": id, "guestId": guestID}).Decode(&wishlist)
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#60 Failed to get wishlist '%v': %v", id, err))
+		return nil, err
+	}
+
+	return &wishlist, nil
+}
+
+func (ar *AccommodationRepository) RenameWishlist(ctx context.Context, guestID, id primitive.ObjectID, name string) error {
+	update := bson.M{"$set": bson.M{"name": name, "updatedAt": time.Now()}}
+	return ar.updateWishlist(ctx, guestID, id, bson.M{}, update, "acr#61")
+}
+
+// Adds accommodation to the end of wishlist, nothing changes if it is already there.
+// Returns mongo.ErrNoDocuments if wishlist doesn't exist or is full.
+func (ar *AccommodationRepository) AddToWishlist(ctx context.Context, guestID, id, accID primitive.ObjectID) error {
+	// Wishlist is matched only while it has room, so concurrent additions can't overfill it
+	filter := bson.M{fmt.Sprintf("accommodationIds.%d", MaxWishlistAccommodations-1): bson.M{"$exists": false}}
+	update := bson.M{
+		"$addToSet": bson.M{"accommodationIds": accID},
+		"$set":      bson.M{"updatedAt": time.Now()},
+	}
+	return ar.updateWishlist(ctx, guestID, id, filter, update, "acr#62")
+}
+
+func (ar *AccommodationRepository) RemoveFromWishlist(ctx context.Context, guestID, id, accID primitive.ObjectID) error {
+	update := bson.M{
+		"$pull": bson.M{"accommodationIds": accID},
+		"$set":  bson.M{"updatedAt": time.Now()},
+	}
+	return ar.updateWishlist(ctx, guestID, id, bson.M{}, update, "acr#63")
+}
+
+func (ar *AccommodationRepository) DeleteWishlist(ctx context.Context, guestID, id primitive.ObjectID) error {
+	collection := ar.getWishlistCollection()
+
+	result, err := collection.DeleteOne(ctx, bson.M{"_id": id, "guestId": guestID})
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#64 Failed to delete wishlist '%v': %v", id, err))
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// Removes deleted accommodations from every wishlist they were saved to
+func (ar *AccommodationRepository) RemoveAccommodationsFromWishlists(ctx context.Context, accIDs []primitive.ObjectID) error {
+	collection := ar.getWishlistCollection()
+
+	filter := bson.M{"accommodationIds": bson.M{"$in": accIDs}}
+	update := bson.M{"$pull": bson.M{"accommodationIds": bson.M{"$in": accIDs}}}
+	_, err := collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]acr#65 Failed to remove accommodations from wishlists: %v", err))
+		return err
+	}
+
+	return nil
+}
+
+func (ar *AccommodationRepository) updateWishlist(ctx context.Context, guestID, id primitive.ObjectID, filter, update bson.M, logCode string) error {
+	collection := ar.getWishlistCollection()
+
+	result, err := collection.UpdateOne(ctx, andFilters(bson.M{"_id": id, "guestId": guestID}, filter), update)
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-repo]%s Failed to update wishlist '%v': %v", logCode, id, err))
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+func (ar *AccommodationRepository) getWishlistCollection() *mongo.Collection {
+	return ar.cli.Database("mongoDemo").Collection("wishlists")
+}
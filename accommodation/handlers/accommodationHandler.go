@@ -7,11 +7,13 @@ import (
 	"accommodation/storage"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -21,6 +23,7 @@ import (
 	"github.com/gorilla/mux"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 const ApplicationJson = "application/json"
@@ -28,22 +31,34 @@ const ContentType = "Content-Type"
 const FailedToEncodeAccommodation = "Failed to encode accommodations"
 const InvalidID = "Invalid ID"
 const FailedToDecodeRequestBody = "Failed to decode request body"
-const ImageLiteral = "%s-image-%d"
 
 type AccommodationHandler struct {
-	repo        *data.AccommodationRepository
-	reservation clients.ReservationClient
-	profile     clients.ProfileClient
-	imageCache  *cache.ImageCache
-	images      *storage.FileStorage
+	repo               *data.AccommodationRepository
+	reservation        clients.ReservationClient
+	profile            clients.ProfileClient
+	imageCache         *cache.ImageCache
+	accommodationCache *cache.AccommodationCache
+	images             storage.BlobStorage
+	// Snapshot of the amenity catalog, replaced whenever it is reloaded
+	amenities atomic.Pointer[data.AmenityCatalog]
 }
 
 var secretKey = []byte("stayinn_secret")
 
 func NewAccommodationsHandler(r *data.AccommodationRepository,
 	rc clients.ReservationClient, p clients.ProfileClient,
-	ic *cache.ImageCache, i *storage.FileStorage) *AccommodationHandler {
-	return &AccommodationHandler{r, rc, p, ic, i}
+	ic *cache.ImageCache, ac *cache.AccommodationCache, i storage.BlobStorage) *AccommodationHandler {
+	ah := &AccommodationHandler{
+		repo:               r,
+		reservation:        rc,
+		profile:            p,
+		imageCache:         ic,
+		accommodationCache: ac,
+		images:             i,
+	}
+	// Default amenities are used until the catalog is loaded from the database
+	ah.amenities.Store(data.NewAmenityCatalog(data.DefaultAmenities()))
+	return ah
 }
 
 func (ah *AccommodationHandler) GetAllAccommodations(rw http.ResponseWriter, r *http.Request) {
@@ -51,7 +66,14 @@ func (ah *AccommodationHandler) GetAllAccommodations(rw http.ResponseWriter, r *
 
 	log.Info(fmt.Sprintf("[acco-handler]ach#1 Received request from '%s' for all accommodations", r.RemoteAddr))
 
-	accommodations, err := ah.repo.GetAllAccommodations(ctx)
+	page, err := data.ParsePageRequest(r.URL.Query(), data.SortByNewest, data.ListSortOrders)
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#75 Invalid pagination parameters: %v", err))
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	accommodations, err := ah.repo.GetAllAccommodations(ctx, page)
 	if err != nil {
 		http.Error(rw, "Failed to retrieve accommodations", http.StatusInternalServerError)
 		log.Error(fmt.Sprintf("[acco-handler]ach#2 Failed to retrieve accommodations: %v", err))
@@ -76,27 +98,95 @@ func (ah *AccommodationHandler) GetAccommodation(rw http.ResponseWriter, r *http
 	}
 	log.Info(fmt.Sprintf("[acco-handler]ach#5 Received request from '%s' for accommodation '%s'", r.RemoteAddr, id.Hex()))
 
-	ctx := r.Context()
-	accommodation, err := ah.repo.GetAccommodation(ctx, id)
+	accommodation, ok := ah.authorizeAccommodationViewer(rw, r, id)
+	if !ok {
+		return
+	}
+
+	rw.Header().Set(ContentType, ApplicationJson)
+	rw.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(rw).Encode(accommodation); err != nil {
+		http.Error(rw, "Failed to encode accommodation", http.StatusInternalServerError)
+		log.Error(fmt.Sprintf("[acco-handler]ach#8 Failed to encode accommodation '%s': %v", id.Hex(), err))
+	}
+	log.Info(fmt.Sprintf("[acco-handler]ach#9 Successfully fetched accommodation with id '%s'", id.Hex()))
+}
+
+// Returns number of units of accommodation whatever its status, for reservation service which keeps
+// reserving nights of accepted reservations after their accommodation is paused or archived
+func (ah *AccommodationHandler) GetAccommodationUnits(rw http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := primitive.ObjectIDFromHex(vars["id"])
 	if err != nil {
-		http.Error(rw, "Failed to retrieve accommodation", http.StatusInternalServerError)
-		log.Error(fmt.Sprintf("[acco-handler]ach#6 Failed to retrieve accommodation '%s': %v", id.Hex(), err))
+		http.Error(rw, InvalidID, http.StatusBadRequest)
 		return
 	}
+	log.Info(fmt.Sprintf("[acco-handler]ach#149 Received request from '%s' for units of accommodation '%s'", r.RemoteAddr, id.Hex()))
 
-	if accommodation == nil {
+	accommodation, err := ah.accommodationCache.GetAccommodation(id.Hex(), func(ctx context.Context) (*data.Accommodation, error) {
+		return ah.repo.GetAccommodation(ctx, id)
+	})
+	if errors.Is(err, mongo.ErrNoDocuments) {
 		http.NotFound(rw, r)
-		log.Info(fmt.Sprintf("[acco-handler]ach#7 Accommodation with id '%s' not found", id.Hex()))
+		log.Info(fmt.Sprintf("[acco-handler]ach#150 Accommodation with id '%s' not found", id.Hex()))
+		return
+	}
+	if err != nil {
+		http.Error(rw, "Failed to retrieve accommodation", http.StatusInternalServerError)
+		log.Error(fmt.Sprintf("[acco-handler]ach#151 Failed to retrieve accommodation '%s': %v", id.Hex(), err))
 		return
 	}
 
 	rw.Header().Set(ContentType, ApplicationJson)
 	rw.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(rw).Encode(accommodation); err != nil {
-		http.Error(rw, "Failed to encode accommodation", http.StatusInternalServerError)
-		log.Error(fmt.Sprintf("[acco-handler]ach#8 Failed to encode accommodation '%s': %v", id.Hex(), err))
+	if err := json.NewEncoder(rw).Encode(map[string]int{"units": accommodation.UnitCount()}); err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#152 Failed to encode units of accommodation '%s': %v", id.Hex(), err))
 	}
-	log.Info(fmt.Sprintf("[acco-handler]ach#9 Successfully fetched accommodation with id '%s'", id.Hex()))
+}
+
+// Loads accommodation and checks that user sending the request can see it. Accommodations that are
+// not published are visible only to their host, to others they are not found, like in search.
+// Writes error response and returns false if the accommodation can't be shown.
+func (ah *AccommodationHandler) authorizeAccommodationViewer(rw http.ResponseWriter, r *http.Request, id primitive.ObjectID) (*data.Accommodation, bool) {
+	accommodation, err := ah.accommodationCache.GetAccommodation(id.Hex(), func(ctx context.Context) (*data.Accommodation, error) {
+		return ah.repo.GetAccommodation(ctx, id)
+	})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.NotFound(rw, r)
+		log.Info(fmt.Sprintf("[acco-handler]ach#7 Accommodation with id '%s' not found", id.Hex()))
+		return nil, false
+	}
+	if err != nil {
+		http.Error(rw, "Failed to retrieve accommodation", http.StatusInternalServerError)
+		log.Error(fmt.Sprintf("[acco-handler]ach#6 Failed to retrieve accommodation '%s': %v", id.Hex(), err))
+		return nil, false
+	}
+
+	if accommodation.CurrentStatus() != data.StatusPublished {
+		// Requests without a valid token are not from the host
+		if isHost, _ := ah.isAccommodationHost(r, accommodation); !isHost {
+			log.Info(fmt.Sprintf("[acco-handler]ach#147 Accommodation '%s' is %s, hidden from '%s'", id.Hex(), accommodation.CurrentStatus(), r.RemoteAddr))
+			http.NotFound(rw, r)
+			return nil, false
+		}
+	}
+
+	return accommodation, true
+}
+
+// Responds with not found to requests for images of accommodations the user can't see
+func (ah *AccommodationHandler) MiddlewareAccommodationVisible(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, h *http.Request) {
+		id, err := primitive.ObjectIDFromHex(mux.Vars(h)["id"])
+		if err != nil {
+			http.Error(rw, InvalidID, http.StatusBadRequest)
+			return
+		}
+		if _, ok := ah.authorizeAccommodationViewer(rw, h, id); !ok {
+			return
+		}
+		next.ServeHTTP(rw, h)
+	})
 }
 
 func (ah *AccommodationHandler) CreateAccommodation(rw http.ResponseWriter, r *http.Request) {
@@ -104,9 +194,9 @@ func (ah *AccommodationHandler) CreateAccommodation(rw http.ResponseWriter, r *h
 
 	log.Info(fmt.Sprintf("[acco-handler]ach#10 User from '%s' creating a new accommodation", r.RemoteAddr))
 
-	if err := json.NewDecoder(r.Body).Decode(&accommodation); err != nil {
-		http.Error(rw, FailedToDecodeRequestBody, http.StatusBadRequest)
-		log.Error(fmt.Sprintf("[acco-handler]ach#11 Failed to decode request body: %v", err))
+	if err := decodeAccommodation(r.Body, &accommodation, ah.amenityCatalog()); err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#11 Invalid accommodation: %v", err))
+		writeDecodeError(rw, err)
 		return
 	}
 
@@ -132,13 +222,15 @@ func (ah *AccommodationHandler) CreateAccommodation(rw http.ResponseWriter, r *h
 		return
 	}
 
-	// Adding accommodation
+	// Adding accommodation, it stays hidden from guests until host publishes it
 	accommodation.ID = primitive.NewObjectID()
+	accommodation.Status = data.StatusDraft
 	if err := ah.repo.CreateAccommodation(r.Context(), &accommodation); err != nil {
 		log.Error(fmt.Sprintf("[acco-handler]ach#15 Failed to create accommodation: %v", err))
 		http.Error(rw, "Failed to create accommodation", http.StatusInternalServerError)
 		return
 	}
+	ah.invalidateAccommodations(accommodation.HostID)
 
 	rw.Header().Set(ContentType, ApplicationJson)
 	rw.WriteHeader(http.StatusCreated)
@@ -150,67 +242,57 @@ func (ah *AccommodationHandler) CreateAccommodation(rw http.ResponseWriter, r *h
 	log.Info(fmt.Sprintf("[acco-handler]ach#17 Successfully created accommodation with id '%s'", accommodation.ID.Hex()))
 }
 
-func (ah *AccommodationHandler) CreateAccommodationImages(rw http.ResponseWriter, r *http.Request) {
-	var images cache.Images
-	var accID string
+func (ah *AccommodationHandler) UpdateAccommodation(rw http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := primitive.ObjectIDFromHex(vars["id"])
+	if err != nil {
+		http.Error(rw, InvalidID, http.StatusBadRequest)
+		return
+	}
 
-	log.Info(fmt.Sprintf("[acco-handler]ach#18 Recieved request to create accommodation images from '%s'", r.RemoteAddr))
+	log.Info(fmt.Sprintf("[acco-handler]ach#25 Received request to update accommodation '%s' from '%s'", id.Hex(), r.RemoteAddr))
 
-	if err := json.NewDecoder(r.Body).Decode(&images); err != nil {
-		http.Error(rw, FailedToDecodeRequestBody, http.StatusBadRequest)
-		log.Error(fmt.Sprintf("[acco-handler]ach#19 Failed to decode request body: %v", err))
+	var updatedAccommodation data.Accommodation
+	if err := decodeAccommodation(r.Body, &updatedAccommodation, ah.amenityCatalog()); err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#26 Invalid accommodation: %v", err))
+		writeDecodeError(rw, err)
 		return
 	}
 
-	for _, image := range images {
-		ah.images.WriteFileBytes(image.Data, image.AccID+"-image-"+image.ID)
-		accID = image.AccID
+	previous, err := ah.repo.GetAccommodation(r.Context(), id)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		log.Error(fmt.Sprintf("[acco-handler]ach#109 Failed to retrieve accommodation: %v", err))
+		http.Error(rw, "Failed to update accommodation", http.StatusInternalServerError)
+		return
 	}
-	ah.imageCache.PostAll(accID, images)
 
-	rw.Header().Set(ContentType, ApplicationJson)
-	rw.WriteHeader(http.StatusCreated)
-	log.Info(fmt.Sprintf("[acco-handler]ach#20 Successfully created images for accommodation '%s'", accID))
-}
-
-func (ah *AccommodationHandler) GetAccommodationImages(rw http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	accID := vars["id"]
-
-	log.Info(fmt.Sprintf("[acco-handler]ach#21 Received request for accommodation images from '%s'", r.RemoteAddr))
-
-	var images []*cache.Image
-
-	for i := 0; i < 10; i++ {
-		filename := fmt.Sprintf(ImageLiteral, accID, i)
-		data, err := ah.images.ReadFileBytes(filename, false)
-		if err != nil {
-			break
-		}
-		image := &cache.Image{
-			ID:   strconv.Itoa(i),
-			Data: data,
-		}
-		images = append(images, image)
+	// Status is changed only through ChangeAccommodationStatus, so transitions are checked
+	updatedAccommodation.ID = id
+	updatedAccommodation.Status = ""
+	if err := ah.repo.UpdateAccommodation(r.Context(), &updatedAccommodation); err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#27 Failed to update accommodation: %v", err))
+		http.Error(rw, "Failed to update accommodation", http.StatusInternalServerError)
+		return
 	}
 
-	if len(images) > 0 {
-		err := ah.imageCache.PostAll(accID, images)
-		if err != nil {
-			log.Error(fmt.Sprintf("[acco-handler]ach#22 Unable to write to cache: %v", err))
-		}
+	// Host can change with the update, listings of both hosts are invalidated
+	if previous != nil {
+		updatedAccommodation.Status = previous.Status
+		ah.invalidateAccommodations(previous.HostID, id)
 	}
+	ah.invalidateAccommodations(updatedAccommodation.HostID, id)
 
 	rw.Header().Set(ContentType, ApplicationJson)
 	rw.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(rw).Encode(images); err != nil {
-		log.Error(fmt.Sprintf("[acco-handler]ach#23 Failed to encode images: %v", err))
-		http.Error(rw, "Failed to encode images", http.StatusInternalServerError)
+	if err := json.NewEncoder(rw).Encode(updatedAccommodation); err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#28 Failed to encode updated accommodation: %v", err))
+		http.Error(rw, "Failed to encode updated accommodation", http.StatusInternalServerError)
 	}
-	log.Info(fmt.Sprintf("[acco-handler]ach#24 Successfully fetched images for accommodation '%s'", accID))
+	log.Info(fmt.Sprintf("[acco-handler]ach#29 Successfully updated accommodation '%s'", updatedAccommodation.ID.Hex()))
 }
 
-func (ah *AccommodationHandler) UpdateAccommodation(rw http.ResponseWriter, r *http.Request) {
+// Moves accommodation to another status (draft, published, paused, archived), only its host can do it
+func (ah *AccommodationHandler) ChangeAccommodationStatus(rw http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := primitive.ObjectIDFromHex(vars["id"])
 	if err != nil {
@@ -218,29 +300,49 @@ func (ah *AccommodationHandler) UpdateAccommodation(rw http.ResponseWriter, r *h
 		return
 	}
 
-	log.Info(fmt.Sprintf("[acco-handler]ach#25 Received request to update accommodation '%s' from '%s'", id.Hex(), r.RemoteAddr))
+	log.Info(fmt.Sprintf("[acco-handler]ach#113 Recieved request from '%s' to change status of accommodation '%s'", r.RemoteAddr, id.Hex()))
 
-	var updatedAccommodation data.Accommodation
-	if err := json.NewDecoder(r.Body).Decode(&updatedAccommodation); err != nil {
+	var change data.StatusChange
+	if err := json.NewDecoder(r.Body).Decode(&change); err != nil {
 		http.Error(rw, FailedToDecodeRequestBody, http.StatusBadRequest)
-		log.Error(fmt.Sprintf("[acco-handler]ach#26 Failed to decode request body: %v", err))
+		log.Error(fmt.Sprintf("[acco-handler]ach#114 Failed to decode request body: %v", err))
 		return
 	}
 
-	updatedAccommodation.ID = id
-	if err := ah.repo.UpdateAccommodation(r.Context(), &updatedAccommodation); err != nil {
-		log.Error(fmt.Sprintf("[acco-handler]ach#27 Failed to update accommodation: %v", err))
-		http.Error(rw, "Failed to update accommodation", http.StatusInternalServerError)
+	accommodation, ok := ah.authorizeAccommodationHost(rw, r, id)
+	if !ok {
+		return
+	}
+
+	if err := accommodation.ValidateTransition(change.Status); err != nil {
+		if errors.Is(err, data.ErrInvalidStatusTransition) {
+			http.Error(rw, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(rw, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	err = ah.repo.UpdateAccommodationStatus(r.Context(), id, accommodation.CurrentStatus(), change.Status)
+	if errors.Is(err, data.ErrStatusChanged) {
+		http.Error(rw, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#115 Failed to change status of accommodation '%s': %v", id.Hex(), err))
+		http.Error(rw, "Failed to change accommodation status", http.StatusInternalServerError)
+		return
+	}
+
+	ah.invalidateAccommodations(accommodation.HostID, id)
+
+	accommodation.Status = change.Status
 	rw.Header().Set(ContentType, ApplicationJson)
 	rw.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(rw).Encode(updatedAccommodation); err != nil {
-		log.Error(fmt.Sprintf("[acco-handler]ach#28 Failed to encode updated accommodation: %v", err))
-		http.Error(rw, "Failed to encode updated accommodation", http.StatusInternalServerError)
+	if err := json.NewEncoder(rw).Encode(accommodation); err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#116 Failed to encode accommodation: %v", err))
 	}
-	log.Info(fmt.Sprintf("[acco-handler]ach#29 Successfully updated accommodation '%s'", updatedAccommodation.ID.Hex()))
+	log.Info(fmt.Sprintf("[acco-handler]ach#117 Accommodation '%s' is now %s", id.Hex(), change.Status))
 }
 
 func (ah *AccommodationHandler) DeleteAccommodation(rw http.ResponseWriter, r *http.Request) {
@@ -254,6 +356,13 @@ func (ah *AccommodationHandler) DeleteAccommodation(rw http.ResponseWriter, r *h
 
 	log.Info(fmt.Sprintf("[acco-handler]ach#30 Recieved request from '%s' to delete accommodation '%s'", r.RemoteAddr, id.Hex()))
 
+	accommodation, err := ah.repo.GetAccommodation(r.Context(), id)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		log.Error(fmt.Sprintf("[acco-handler]ach#110 Failed to retrieve accommodation: %v", err))
+		http.Error(rw, "Failed to delete accommodation", http.StatusInternalServerError)
+		return
+	}
+
 	var accIDs []primitive.ObjectID
 	accIDs = append(accIDs, id)
 
@@ -272,14 +381,11 @@ func (ah *AccommodationHandler) DeleteAccommodation(rw http.ResponseWriter, r *h
 		return
 	}
 
-	for i := 0; i < 10; i++ {
-		filename := fmt.Sprintf(ImageLiteral, id.Hex(), i)
-		err := ah.images.DeleteFile(filename, false)
-		if err != nil {
-			break
-		}
-		log.Info(fmt.Sprintf("[acco-handler]ach#33 %s-image-%d deleted\n", id.Hex(), i))
+	if accommodation != nil {
+		ah.invalidateAccommodations(accommodation.HostID, id)
 	}
+	ah.deleteImages(r.Context(), accIDs)
+	ah.removeFromWishlists(r, accIDs)
 
 	rw.WriteHeader(http.StatusNoContent)
 	log.Info(fmt.Sprintf("[acco-handler]ach#34 Successfully deleted accommodation '%s'", id.Hex()))
@@ -306,7 +412,24 @@ func (ah *AccommodationHandler) GetAccommodationsForUser(rw http.ResponseWriter,
 		return
 	}
 
-	accommodations, err := ah.repo.GetAccommodationsForUser(r.Context(), userID)
+	page, err := data.ParsePageRequest(r.URL.Query(), data.SortByNewest, data.ListSortOrders)
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#76 Invalid pagination parameters: %v", err))
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Host sees all of their accommodations, others only published ones
+	requester, err := ah.getUsername(tokenStr)
+	onlyPublished := err != nil || requester != username
+	pageKey := page.Key()
+	if onlyPublished {
+		pageKey += ":published"
+	}
+
+	accommodations, err := ah.accommodationCache.GetHostAccommodations(userID.Hex(), pageKey, func(ctx context.Context) (*data.Page[*data.Accommodation], error) {
+		return ah.repo.GetAccommodationsForUser(ctx, userID, page, onlyPublished)
+	})
 	if err != nil {
 		log.Error(fmt.Sprintf("[acco-handler]ach#38 Failed to get accommodations for UserID '%v': %v", userID, err))
 		http.Error(rw, "Failed to get accommodations for userID: "+userID.Hex(), http.StatusInternalServerError)
@@ -333,18 +456,13 @@ func (ah *AccommodationHandler) DeleteUserAccommodations(rw http.ResponseWriter,
 
 	log.Info(fmt.Sprintf("[acco-handler]ach#41 Recieved request from '%s' to delete user '%v' accommodations", r.RemoteAddr, userID))
 
-	accommodations, err := ah.repo.GetAccommodationsForUser(r.Context(), userID)
+	accIDs, err := ah.repo.GetAccommodationIDsForUser(r.Context(), userID)
 	if err != nil {
 		log.Error(fmt.Sprintf("[acco-handler]ach#42 Failed to get accommodations for UserID '%v': %v", userID, err))
 		http.Error(rw, "Failed to get accommodations for userID: "+userID.Hex(), http.StatusInternalServerError)
 		return
 	}
 
-	var accIDs []primitive.ObjectID
-	for _, accommodation := range accommodations {
-		accIDs = append(accIDs, accommodation.ID)
-	}
-
 	// 4000 ms because it's second in chain of service calls
 	ctx, cancel := context.WithTimeout(r.Context(), 4000*time.Millisecond)
 	defer cancel()
@@ -361,19 +479,43 @@ func (ah *AccommodationHandler) DeleteUserAccommodations(rw http.ResponseWriter,
 		return
 	}
 
+	ah.invalidateAccommodations(userID, accIDs...)
+	ah.deleteImages(r.Context(), accIDs)
+	ah.removeFromWishlists(r, accIDs)
+
+	rw.WriteHeader(http.StatusNoContent)
+	log.Info(fmt.Sprintf("[acco-handler]ach#46 Successfully deleted accommodations for user '%v'", userID))
+}
+
+// Invalidates cached accommodations and listings of their host after a write
+func (ah *AccommodationHandler) invalidateAccommodations(hostID primitive.ObjectID, accIDs ...primitive.ObjectID) {
 	for _, accID := range accIDs {
-		for i := 0; i < 10; i++ {
-			filename := fmt.Sprintf(ImageLiteral, accID.Hex(), i)
-			err := ah.images.DeleteFile(filename, false)
-			if err != nil {
-				break
-			}
-			log.Info(fmt.Sprintf("[acco-handler]ach#45 %s-image-%d deleted\n", accID.Hex(), i))
+		if err := ah.accommodationCache.InvalidateAccommodation(accID.Hex()); err != nil {
+			log.Error(fmt.Sprintf("[acco-handler]ach#111 Unable to invalidate cached accommodation '%s': %v", accID.Hex(), err))
 		}
 	}
+	if hostID.IsZero() {
+		return
+	}
+	if err := ah.accommodationCache.InvalidateHost(hostID.Hex()); err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#112 Unable to invalidate cached accommodations of host '%s': %v", hostID.Hex(), err))
+	}
+}
 
-	rw.WriteHeader(http.StatusNoContent)
-	log.Info(fmt.Sprintf("[acco-handler]ach#46 Successfully deleted accommodations for user '%v'", userID))
+// Returns ID of user sending the request, resolved from username in their token by profile service
+func (ah *AccommodationHandler) currentUserID(r *http.Request) (primitive.ObjectID, error) {
+	tokenStr := ah.extractTokenFromHeader(r)
+	username, err := ah.getUsername(tokenStr)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	userID, err := ah.profile.GetUserId(r.Context(), username, tokenStr)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	return primitive.ObjectIDFromHex(userID)
 }
 
 func (ah *AccommodationHandler) getUsername(tokenString string) (string, error) {
@@ -458,8 +600,6 @@ func (ah *AccommodationHandler) SearchAccommodations(rw http.ResponseWriter, r *
 
 	log.Info(fmt.Sprintf("[acco-handler]ach#52 Recieved request from '%s' to search accommodations", r.RemoteAddr))
 
-	var accommodationIDs []primitive.ObjectID
-
 	startDateStr := r.URL.Query().Get("startDate")
 	endDateStr := r.URL.Query().Get("endDate")
 
@@ -499,6 +639,20 @@ func (ah *AccommodationHandler) SearchAccommodations(rw http.ResponseWriter, r *
 		}
 	}
 
+	geo, err := parseGeoQuery(r.URL.Query())
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#72 Invalid geo search parameters: %v", err))
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	amenities, err := parseAmenityFilter(r.URL.Query(), ah.amenityCatalog())
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#73 Invalid amenities filter: %v", err))
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	filter := make(bson.M)
 
 	if location != "" {
@@ -506,16 +660,38 @@ func (ah *AccommodationHandler) SearchAccommodations(rw http.ResponseWriter, r *
 	}
 
 	if numGuests > 0 {
-		filter["$and"] = bson.A{
-			bson.M{"minGuests": bson.M{"$lte": numGuests}},
-			bson.M{"maxGuests": bson.M{"$gte": numGuests}},
-		}
+		filter["minGuests"] = bson.M{"$lte": numGuests}
+		filter["maxGuests"] = bson.M{"$gte": numGuests}
+	}
+
+	if amenities != nil {
+		filter["amenities"] = amenities
+	}
+
+	if err := parseLayoutFilter(r.URL.Query(), filter); err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#142 Invalid property filter: %v", err))
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	accommodations, err := ah.repo.GetFilteredAccommodations(ctx, filter)
+	if geo.Viewport != nil {
+		filter["position"] = bson.M{"$geoWithin": bson.M{"$geometry": geo.Viewport.Polygon()}}
+	}
+
+	search := &data.SearchQuery{
+		Filter: filter,
+		Text:   r.URL.Query().Get("q"),
+	}
+	if !geo.isEmpty() {
+		search.Near = geo.origin()
+		search.MaxDistanceKm = geo.RadiusKm
+	}
+
+	defaultSort, allowedSorts := search.SortOrders()
+	search.Page, err = data.ParsePageRequest(r.URL.Query(), defaultSort, allowedSorts)
 	if err != nil {
-		log.Error(fmt.Sprintf("[acco-handler]ach#56 Failed to fetch filtered accommodations: %v", err))
-		http.Error(rw, "Failed to retrieve accommodations", http.StatusInternalServerError)
+		log.Error(fmt.Sprintf("[acco-handler]ach#74 Invalid pagination parameters: %v", err))
+		http.Error(rw, err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -531,6 +707,7 @@ func (ah *AccommodationHandler) SearchAccommodations(rw http.ResponseWriter, r *
 		return
 	}
 
+	var remainingUnits map[primitive.ObjectID]int
 	if endDateStr != "" && startDateStr != "" {
 		if startDate.Before(time.Now()) {
 			log.Error(fmt.Sprintf("[acco-handler]ach#59 Start date not in future"))
@@ -549,44 +726,74 @@ func (ah *AccommodationHandler) SearchAccommodations(rw http.ResponseWriter, r *
 			http.Error(rw, "Start date must be before end date", http.StatusBadRequest)
 			return
 		}
+	}
 
-		for _, accommodation := range accommodations {
-			accommodationIDs = append(accommodationIDs, accommodation.ID)
-		}
+	searchFilter, err := ah.repo.BuildSearchFilter(ctx, search)
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#148 Failed to build search filter: %v", err))
+		http.Error(rw, "Failed to retrieve accommodations", http.StatusInternalServerError)
+		return
+	}
 
-		ids, err := ah.reservation.PassDatesToReservationService(ctx, accommodationIDs, startDate, endDate, tokenStr)
+	if endDateStr != "" && startDateStr != "" {
+		// Availability is checked for every match before paginating, so pages stay full
+		accommodationUnits, err := ah.repo.FindAccommodationUnits(ctx, searchFilter)
 		if err != nil {
-			log.Warning(fmt.Sprintf("[acco-handler]ach#62 Reservation service is unavaible: %v", err))
-			writeResp(err, http.StatusServiceUnavailable, rw)
+			log.Error(fmt.Sprintf("[acco-handler]ach#56 Failed to fetch filtered accommodations: %v", err))
+			http.Error(rw, "Failed to retrieve accommodations", http.StatusInternalServerError)
 			return
 		}
 
-		accommodationForReturn, err := ah.repo.FindAccommodationsByIDs(ctx, ids)
+		remainingUnits, err = ah.reservation.PassDatesToReservationService(ctx, accommodationUnits, startDate, endDate, tokenStr)
 		if err != nil {
-			log.Warning(fmt.Sprintf("[acco-handler]ach#63 Reservation service is unavaible: %v", err))
+			log.Warning(fmt.Sprintf("[acco-handler]ach#62 Reservation service is unavaible: %v", err))
 			writeResp(err, http.StatusServiceUnavailable, rw)
 			return
 		}
 
-		rw.Header().Set(ContentType, ApplicationJson)
-		rw.WriteHeader(http.StatusOK)
-		if err := json.NewEncoder(rw).Encode(accommodationForReturn); err != nil {
-			log.Error(fmt.Sprintf("[acco-handler]ach#64 Failed to encode accommodations: %v", err))
-			http.Error(rw, FailedToEncodeAccommodation, http.StatusInternalServerError)
-		}
-	} else {
-		rw.Header().Set(ContentType, ApplicationJson)
-		rw.WriteHeader(http.StatusOK)
-		if err := json.NewEncoder(rw).Encode(accommodations); err != nil {
-			log.Error(fmt.Sprintf("[acco-handler]ach#65 Failed to encode accommodations: %v", err))
-			http.Error(rw, FailedToEncodeAccommodation, http.StatusInternalServerError)
+		ids := make([]primitive.ObjectID, 0, len(remainingUnits))
+		for id := range remainingUnits {
+			ids = append(ids, id)
 		}
+		searchFilter = searchFilter.WithIDs(ids)
+	}
+
+	accommodations, err := ah.repo.GetFilteredAccommodations(ctx, search, searchFilter)
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#63 Failed to fetch filtered accommodations: %v", err))
+		http.Error(rw, "Failed to retrieve accommodations", http.StatusInternalServerError)
+		return
+	}
+	for _, result := range accommodations.Items {
+		result.RemainingUnits = remainingUnits[result.ID]
+	}
+
+	facets, err := ah.repo.GetSearchFacets(ctx, searchFilter)
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#119 Failed to compute search facets: %v", err))
+		http.Error(rw, "Failed to retrieve accommodations", http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set(ContentType, ApplicationJson)
+	rw.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(rw).Encode(data.SearchPage{Page: accommodations, Facets: facets}); err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#64 Failed to encode accommodations: %v", err))
+		http.Error(rw, FailedToEncodeAccommodation, http.StatusInternalServerError)
 	}
 	log.Info(fmt.Sprintf("[acco-handler]ach#66 Successfully searched accommodations"))
 }
 
 func (ah *AccommodationHandler) WalkRoot(rw http.ResponseWriter, r *http.Request) {
-	pathsArray := ah.images.WalkDirectories()
+	files, err := ah.images.List("")
+	if err != nil {
+		http.Error(rw, "Failed to list stored files", http.StatusInternalServerError)
+		return
+	}
+	var pathsArray []string
+	for _, file := range files {
+		pathsArray = append(pathsArray, fmt.Sprintf("File: %s (%d bytes)", file.Name, file.Size))
+	}
 	paths := strings.Join(pathsArray, "\n")
 	io.WriteString(rw, paths)
 }
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"accommodation/data"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Lists the amenity catalog with labels in requested language, e.g. /amenities?lang=sr.
+// Without lang parameter the language is taken from Accept-Language header.
+func (ah *AccommodationHandler) GetAmenityCatalog(rw http.ResponseWriter, r *http.Request) {
+	language := r.URL.Query().Get("lang")
+	if language == "" {
+		language = preferredLanguage(r.Header.Get("Accept-Language"))
+	}
+
+	log.Info(fmt.Sprintf("[acco-handler]ach#143 Recieved request from '%s' for amenity catalog in '%s'", r.RemoteAddr, language))
+
+	rw.Header().Set(ContentType, ApplicationJson)
+	rw.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(rw).Encode(ah.amenityCatalog().Localized(language)); err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#144 Failed to encode amenity catalog: %v", err))
+	}
+}
+
+// Loads amenity catalog from the database, replacing the one in use
+func (ah *AccommodationHandler) LoadAmenityCatalog(ctx context.Context) error {
+	catalog, err := ah.repo.GetAmenityCatalog(ctx)
+	if err != nil {
+		return err
+	}
+	ah.amenities.Store(catalog)
+	return nil
+}
+
+// Reloads amenity catalog periodically until context is cancelled, so amenities added
+// to the database become available without restarting the service
+func (ah *AccommodationHandler) RunAmenityCatalogRefresher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := ah.LoadAmenityCatalog(ctx); err != nil {
+			log.Error(fmt.Sprintf("[acco-handler]ach#145 Failed to reload amenity catalog: %v", err))
+		}
+	}
+}
+
+func (ah *AccommodationHandler) amenityCatalog() *data.AmenityCatalog {
+	return ah.amenities.Load()
+}
+
+// Returns primary language of the first language in Accept-Language header, e.g. "sr" for "sr-Latn-RS,en;q=0.8"
+func preferredLanguage(header string) string {
+	first, _, _ := strings.Cut(header, ",")
+	first, _, _ = strings.Cut(first, ";")
+	language, _, _ := strings.Cut(strings.TrimSpace(first), "-")
+	if language == "" || language == "*" {
+		return data.DefaultLanguage
+	}
+	return strings.ToLower(language)
+}
@@ -0,0 +1,784 @@
+package handlers
+
+import (
+	"accommodation/cache"
+	"accommodation/data"
+	"accommodation/imaging"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Storage names of images uploaded before image records existed, indexed from 0
+const ImageLiteral = "%s-image-%d"
+
+// Storage names of images and their variants, identified by image record ID
+const ImagePathLiteral = "%s-image-%s"
+const ImageVariantLiteral = "%s-image-%s-%s"
+
+const (
+	// Memory used for parsing multipart uploads, bigger files are kept in temporary files
+	multipartMemory   = 8 << 20
+	multipartOverhead = 1 << 20
+	// Base64 encoding adds a third to the size of images
+	maxJSONImagesBody = imaging.MaxListingSize*4/3 + multipartOverhead
+
+	maxCaptionLength = 500
+
+	// Files without image record younger than this are kept by garbage collection,
+	// their upload may still be in progress
+	imageGCGracePeriod = time.Hour
+
+	imageCacheControl = "public, max-age=31536000, immutable"
+)
+
+// Image URLs are prefixed with IMAGE_BASE_URL, e.g. address of API gateway route to this service
+var imageBaseURL = strings.TrimSuffix(os.Getenv("IMAGE_BASE_URL"), "/")
+
+// Legacy names end with the image index, names of images with records with the 24 hex digits of the record ID.
+// Indexes are written without leading zeros and are far shorter, so record IDs made of digits are never taken for them.
+var legacyImageName = regexp.MustCompile(`^([0-9a-f]{24})-image-(0|[1-9][0-9]{0,8})$`)
+
+// Deprecated JSON upload with base64 encoded images, kept for older clients.
+// Images go through the same validation and processing as multipart uploads.
+func (ah *AccommodationHandler) CreateAccommodationImages(rw http.ResponseWriter, r *http.Request) {
+	var images cache.Images
+
+	log.Info(fmt.Sprintf("[acco-handler]ach#18 Recieved request to create accommodation images from '%s'", r.RemoteAddr))
+
+	r.Body = http.MaxBytesReader(rw, r.Body, maxJSONImagesBody)
+	if err := json.NewDecoder(r.Body).Decode(&images); err != nil {
+		http.Error(rw, FailedToDecodeRequestBody, http.StatusBadRequest)
+		log.Error(fmt.Sprintf("[acco-handler]ach#19 Failed to decode request body: %v", err))
+		return
+	}
+
+	if len(images) == 0 {
+		http.Error(rw, "No images provided", http.StatusBadRequest)
+		return
+	}
+
+	var uploads [][]byte
+	for _, image := range images {
+		if image.AccID != images[0].AccID {
+			http.Error(rw, "All images must belong to the same accommodation", http.StatusBadRequest)
+			return
+		}
+		uploads = append(uploads, image.Data)
+	}
+
+	accID, err := primitive.ObjectIDFromHex(images[0].AccID)
+	if err != nil {
+		http.Error(rw, InvalidID, http.StatusBadRequest)
+		return
+	}
+
+	ah.saveImages(rw, r, accID, uploads)
+}
+
+// Accepts multipart/form-data with one or more files in "images" field
+func (ah *AccommodationHandler) UploadAccommodationImages(rw http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	accID, err := primitive.ObjectIDFromHex(vars["id"])
+	if err != nil {
+		http.Error(rw, InvalidID, http.StatusBadRequest)
+		return
+	}
+
+	log.Info(fmt.Sprintf("[acco-handler]ach#77 Recieved request to upload images for accommodation '%s' from '%s'", accID.Hex(), r.RemoteAddr))
+
+	r.Body = http.MaxBytesReader(rw, r.Body, imaging.MaxListingSize+multipartOverhead)
+	if err := r.ParseMultipartForm(multipartMemory); err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#78 Failed to parse multipart form: %v", err))
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(rw, fmt.Sprintf("Upload exceeds %d bytes", imaging.MaxListingSize), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(rw, "Expected multipart/form-data with images", http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	files := r.MultipartForm.File["images"]
+	if len(files) == 0 {
+		http.Error(rw, "No images provided", http.StatusBadRequest)
+		return
+	}
+
+	var uploads [][]byte
+	for _, header := range files {
+		if header.Size > imaging.MaxImageSize {
+			http.Error(rw, fmt.Sprintf("Image '%s' exceeds %d bytes", header.Filename, imaging.MaxImageSize), http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		file, err := header.Open()
+		if err != nil {
+			log.Error(fmt.Sprintf("[acco-handler]ach#79 Failed to open uploaded image '%s': %v", header.Filename, err))
+			http.Error(rw, "Failed to read uploaded image", http.StatusBadRequest)
+			return
+		}
+		content, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			log.Error(fmt.Sprintf("[acco-handler]ach#80 Failed to read uploaded image '%s': %v", header.Filename, err))
+			http.Error(rw, "Failed to read uploaded image", http.StatusBadRequest)
+			return
+		}
+		uploads = append(uploads, content)
+	}
+
+	ah.saveImages(rw, r, accID, uploads)
+}
+
+// Validates and processes uploaded images, then stores them with their variants and records.
+// Nothing is stored unless every image is valid and listing limits are respected.
+func (ah *AccommodationHandler) saveImages(rw http.ResponseWriter, r *http.Request, accID primitive.ObjectID, uploads [][]byte) {
+	if _, ok := ah.authorizeAccommodationHost(rw, r, accID); !ok {
+		return
+	}
+
+	existing, err := ah.repo.GetImagesForAccommodation(r.Context(), accID)
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#81 Failed to get images of accommodation '%s': %v", accID.Hex(), err))
+		http.Error(rw, "Failed to retrieve images", http.StatusInternalServerError)
+		return
+	}
+
+	if len(existing)+len(uploads) > imaging.MaxImagesPerListing {
+		http.Error(rw, fmt.Sprintf("Accommodation can have at most %d images", imaging.MaxImagesPerListing), http.StatusBadRequest)
+		return
+	}
+
+	var listingSize int64
+	for _, image := range existing {
+		listingSize += image.Size
+	}
+
+	var processed []*imaging.ProcessedImage
+	for i, upload := range uploads {
+		image, err := imaging.Process(upload)
+		if err != nil {
+			log.Warning(fmt.Sprintf("[acco-handler]ach#84 Rejected image %d for accommodation '%s': %v", i, accID.Hex(), err))
+			http.Error(rw, fmt.Sprintf("Image %d: %v", i, err), imageErrorStatus(err))
+			return
+		}
+		listingSize += int64(len(image.Original.Data))
+		processed = append(processed, image)
+	}
+
+	if listingSize > imaging.MaxListingSize {
+		http.Error(rw, fmt.Sprintf("Images of one accommodation can take at most %d bytes", imaging.MaxListingSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// Files are written before records; files left without record after a failure are garbage collected
+	var records []*data.ImageRecord
+	for i, image := range processed {
+		record := &data.ImageRecord{
+			ID:                  primitive.NewObjectID(),
+			AccommodationID:     accID,
+			ContentType:         image.Original.ContentType,
+			Order:               len(existing) + i,
+			Cover:               len(existing) == 0 && i == 0,
+			Width:               image.Original.Width,
+			Height:              image.Original.Height,
+			Size:                int64(len(image.Original.Data)),
+			CreatedAt:           time.Now(),
+			Variants:            make(map[string]string),
+			VariantContentTypes: make(map[string]string),
+		}
+
+		record.Path = fmt.Sprintf(ImagePathLiteral, accID.Hex(), record.ID.Hex())
+		if err := ah.images.Write(record.Path, image.Original.Data); err != nil {
+			log.Error(fmt.Sprintf("[acco-handler]ach#85 Failed to store image '%s': %v", record.Path, err))
+			http.Error(rw, "Failed to store images", http.StatusInternalServerError)
+			return
+		}
+
+		for _, variant := range imaging.Variants {
+			path := fmt.Sprintf(ImageVariantLiteral, accID.Hex(), record.ID.Hex(), variant.Name)
+			if err := ah.images.Write(path, image.Variants[variant.Name].Data); err != nil {
+				log.Error(fmt.Sprintf("[acco-handler]ach#86 Failed to store image variant '%s': %v", path, err))
+				http.Error(rw, "Failed to store images", http.StatusInternalServerError)
+				return
+			}
+			record.Variants[variant.Name] = path
+			record.VariantContentTypes[variant.Name] = image.Variants[variant.Name].ContentType
+		}
+
+		records = append(records, record)
+	}
+
+	if err := ah.repo.CreateImages(r.Context(), records); err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#89 Failed to create image records: %v", err))
+		http.Error(rw, "Failed to store images", http.StatusInternalServerError)
+		return
+	}
+
+	ah.invalidateImageCache(accID)
+
+	rw.Header().Set(ContentType, ApplicationJson)
+	rw.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(rw).Encode(records); err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#88 Failed to encode stored images: %v", err))
+	}
+	log.Info(fmt.Sprintf("[acco-handler]ach#20 Successfully created images for accommodation '%s'", accID.Hex()))
+}
+
+// Lists images of accommodation with URLs of their content, in display order.
+// Image content is served by ServeAccommodationImage.
+func (ah *AccommodationHandler) GetAccommodationImages(rw http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	accID, err := primitive.ObjectIDFromHex(vars["id"])
+	if err != nil {
+		http.Error(rw, InvalidID, http.StatusBadRequest)
+		return
+	}
+
+	log.Info(fmt.Sprintf("[acco-handler]ach#21 Received request for accommodation images from '%s'", r.RemoteAddr))
+
+	records, err := ah.repo.GetImagesForAccommodation(r.Context(), accID)
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#90 Failed to get images of accommodation '%s': %v", accID.Hex(), err))
+		http.Error(rw, "Failed to retrieve images", http.StatusInternalServerError)
+		return
+	}
+
+	images := cache.Images{}
+	for _, record := range records {
+		image := &cache.Image{
+			ID:          record.ID.Hex(),
+			AccID:       accID.Hex(),
+			URL:         imageURL(record, ""),
+			Variants:    make(map[string]string, len(record.Variants)),
+			ContentType: record.ContentType,
+			Width:       record.Width,
+			Height:      record.Height,
+			Caption:     record.Caption,
+			Cover:       record.Cover,
+			Order:       record.Order,
+		}
+		for variant := range record.Variants {
+			image.Variants[variant] = imageURL(record, variant)
+		}
+		images = append(images, image)
+	}
+
+	if len(images) > 0 {
+		err := ah.imageCache.PostAll(accID.Hex(), images)
+		if err != nil {
+			log.Error(fmt.Sprintf("[acco-handler]ach#22 Unable to write to cache: %v", err))
+		}
+	}
+
+	rw.Header().Set(ContentType, ApplicationJson)
+	rw.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(rw).Encode(images); err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#23 Failed to encode images: %v", err))
+		http.Error(rw, "Failed to encode images", http.StatusInternalServerError)
+	}
+	log.Info(fmt.Sprintf("[acco-handler]ach#24 Successfully fetched images for accommodation '%s'", accID.Hex()))
+}
+
+// Streams image content from storage, or its resized variant (thumbnail, card, full) if requested.
+// Conditional (If-None-Match, If-Modified-Since) and range requests are handled by http.ServeContent.
+func (ah *AccommodationHandler) ServeAccommodationImage(rw http.ResponseWriter, r *http.Request) {
+	accID, imageID, ok := imageIDsFromPath(rw, r)
+	if !ok {
+		return
+	}
+
+	variant := r.URL.Query().Get("variant")
+	if variant != "" && !isImageVariant(variant) {
+		http.Error(rw, "Invalid image variant", http.StatusBadRequest)
+		return
+	}
+
+	accommodation, ok := ah.authorizeAccommodationViewer(rw, r, accID)
+	if !ok {
+		return
+	}
+
+	record, ok := ah.getImageRecord(rw, r, accID, imageID)
+	if !ok {
+		return
+	}
+
+	path := record.Path
+	if variant != "" {
+		variantPath, ok := record.Variants[variant]
+		if !ok {
+			http.NotFound(rw, r)
+			return
+		}
+		path = variantPath
+	}
+
+	file, err := ah.images.Open(path)
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#108 Missing content of image '%s': %v", path, err))
+		if errors.Is(err, os.ErrNotExist) {
+			http.NotFound(rw, r)
+			return
+		}
+		http.Error(rw, "Failed to read image", http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	// Content under an image URL never changes, new uploads get new IDs
+	etag := `"` + record.ID.Hex() + `"`
+	contentType := record.ContentType
+	if variant != "" {
+		etag = `"` + record.ID.Hex() + "-" + variant + `"`
+		// Left to detection from content for variants of older records
+		contentType = record.VariantContentTypes[variant]
+	}
+	if contentType != "" {
+		rw.Header().Set(ContentType, contentType)
+	}
+	rw.Header().Set("X-Content-Type-Options", "nosniff")
+	rw.Header().Set("ETag", etag)
+	if accommodation.CurrentStatus() == data.StatusPublished {
+		rw.Header().Set("Cache-Control", imageCacheControl)
+	} else {
+		// Only the host can see it, shared caches must not keep it
+		rw.Header().Set("Cache-Control", "private, no-cache")
+	}
+
+	http.ServeContent(rw, r, "", record.CreatedAt, file)
+}
+
+// Sets display order of accommodation images, every image must be listed exactly once
+func (ah *AccommodationHandler) ReorderAccommodationImages(rw http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	accID, err := primitive.ObjectIDFromHex(vars["id"])
+	if err != nil {
+		http.Error(rw, InvalidID, http.StatusBadRequest)
+		return
+	}
+
+	log.Info(fmt.Sprintf("[acco-handler]ach#92 Recieved request from '%s' to reorder images of accommodation '%s'", r.RemoteAddr, accID.Hex()))
+
+	var order data.ImageOrder
+	if err := json.NewDecoder(r.Body).Decode(&order); err != nil {
+		http.Error(rw, FailedToDecodeRequestBody, http.StatusBadRequest)
+		log.Error(fmt.Sprintf("[acco-handler]ach#93 Failed to decode request body: %v", err))
+		return
+	}
+
+	if _, ok := ah.authorizeAccommodationHost(rw, r, accID); !ok {
+		return
+	}
+
+	records, err := ah.repo.GetImagesForAccommodation(r.Context(), accID)
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#94 Failed to get images of accommodation '%s': %v", accID.Hex(), err))
+		http.Error(rw, "Failed to retrieve images", http.StatusInternalServerError)
+		return
+	}
+
+	remaining := make(map[primitive.ObjectID]bool, len(records))
+	for _, record := range records {
+		remaining[record.ID] = true
+	}
+	for _, id := range order.ImageIDs {
+		if !remaining[id] {
+			http.Error(rw, fmt.Sprintf("Image '%s' is unknown or listed more than once", id.Hex()), http.StatusBadRequest)
+			return
+		}
+		delete(remaining, id)
+	}
+	if len(remaining) > 0 {
+		http.Error(rw, "Every image of the accommodation must be listed", http.StatusBadRequest)
+		return
+	}
+
+	if err := ah.repo.ReorderImages(r.Context(), accID, order.ImageIDs); err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#95 Failed to reorder images of accommodation '%s': %v", accID.Hex(), err))
+		http.Error(rw, "Failed to reorder images", http.StatusInternalServerError)
+		return
+	}
+
+	ah.invalidateImageCache(accID)
+	ah.writeImageRecords(rw, r, accID)
+}
+
+// Changes caption of an image or makes it the cover image
+func (ah *AccommodationHandler) UpdateAccommodationImage(rw http.ResponseWriter, r *http.Request) {
+	accID, imageID, ok := imageIDsFromPath(rw, r)
+	if !ok {
+		return
+	}
+
+	log.Info(fmt.Sprintf("[acco-handler]ach#96 Recieved request from '%s' to update image '%s'", r.RemoteAddr, imageID.Hex()))
+
+	var update data.ImageUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		http.Error(rw, FailedToDecodeRequestBody, http.StatusBadRequest)
+		log.Error(fmt.Sprintf("[acco-handler]ach#97 Failed to decode request body: %v", err))
+		return
+	}
+
+	if update.Caption != nil && len([]rune(*update.Caption)) > maxCaptionLength {
+		http.Error(rw, fmt.Sprintf("Caption can have at most %d characters", maxCaptionLength), http.StatusBadRequest)
+		return
+	}
+	if update.Cover != nil && !*update.Cover {
+		http.Error(rw, "Cover can only be changed by making another image the cover", http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := ah.authorizeAccommodationHost(rw, r, accID); !ok {
+		return
+	}
+
+	if _, ok := ah.getImageRecord(rw, r, accID, imageID); !ok {
+		return
+	}
+
+	if update.Caption != nil {
+		if err := ah.repo.UpdateImageCaption(r.Context(), imageID, *update.Caption); err != nil {
+			http.Error(rw, "Failed to update image", http.StatusInternalServerError)
+			return
+		}
+	}
+	if update.Cover != nil {
+		if err := ah.repo.SetCoverImage(r.Context(), accID, imageID); err != nil {
+			http.Error(rw, "Failed to update image", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	ah.invalidateImageCache(accID)
+
+	record, ok := ah.getImageRecord(rw, r, accID, imageID)
+	if !ok {
+		return
+	}
+	rw.Header().Set(ContentType, ApplicationJson)
+	rw.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(rw).Encode(record); err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#98 Failed to encode image: %v", err))
+	}
+}
+
+// Deletes one image, remaining images close the gap in order.
+// When the cover image is deleted, the first remaining image becomes the cover.
+func (ah *AccommodationHandler) DeleteAccommodationImage(rw http.ResponseWriter, r *http.Request) {
+	accID, imageID, ok := imageIDsFromPath(rw, r)
+	if !ok {
+		return
+	}
+
+	log.Info(fmt.Sprintf("[acco-handler]ach#99 Recieved request from '%s' to delete image '%s'", r.RemoteAddr, imageID.Hex()))
+
+	if _, ok := ah.authorizeAccommodationHost(rw, r, accID); !ok {
+		return
+	}
+
+	record, ok := ah.getImageRecord(rw, r, accID, imageID)
+	if !ok {
+		return
+	}
+
+	if err := ah.repo.DeleteImage(r.Context(), imageID); err != nil {
+		http.Error(rw, "Failed to delete image", http.StatusInternalServerError)
+		return
+	}
+
+	// Files that fail to be deleted have no record anymore and are garbage collected later
+	for _, path := range record.Paths() {
+		_ = ah.images.Delete(path)
+	}
+
+	remaining, err := ah.repo.GetImagesForAccommodation(r.Context(), accID)
+	if err == nil && len(remaining) > 0 {
+		ids := make([]primitive.ObjectID, len(remaining))
+		for i, image := range remaining {
+			ids[i] = image.ID
+		}
+		err = ah.repo.ReorderImages(r.Context(), accID, ids)
+		if err == nil && record.Cover {
+			err = ah.repo.SetCoverImage(r.Context(), accID, ids[0])
+		}
+	}
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#100 Failed to update remaining images of accommodation '%s': %v", accID.Hex(), err))
+	}
+
+	ah.invalidateImageCache(accID)
+
+	rw.WriteHeader(http.StatusNoContent)
+	log.Info(fmt.Sprintf("[acco-handler]ach#101 Successfully deleted image '%s'", imageID.Hex()))
+}
+
+// Deletes images of accommodations, both stored files and records
+func (ah *AccommodationHandler) deleteImages(ctx context.Context, accIDs []primitive.ObjectID) {
+	for _, accID := range accIDs {
+		records, err := ah.repo.GetImagesForAccommodation(ctx, accID)
+		if err != nil {
+			continue
+		}
+		for _, record := range records {
+			for _, path := range record.Paths() {
+				_ = ah.images.Delete(path)
+			}
+			log.Info(fmt.Sprintf("[acco-handler]ach#33 %s deleted\n", record.Path))
+		}
+		ah.invalidateImageCache(accID)
+	}
+
+	if err := ah.repo.DeleteImagesForAccommodations(ctx, accIDs); err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#102 Failed to delete image records: %v", err))
+	}
+}
+
+// Removes stored files which are not referenced by any image record.
+// Images stored under legacy names for existing accommodations get a record instead of being removed.
+// Returns number of removed files.
+func (ah *AccommodationHandler) CollectImageGarbage(ctx context.Context) (int, error) {
+	referenced, err := ah.repo.GetAllImagePaths(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	files, err := ah.images.List("")
+	if err != nil {
+		return 0, err
+	}
+
+	for _, file := range files {
+		if referenced[file.Name] {
+			continue
+		}
+		record, err := ah.adoptLegacyImage(ctx, file.Name)
+		if err != nil {
+			// Legacy image may still belong to an accommodation, keep it until the next run
+			log.Warning(fmt.Sprintf("[acco-handler]ach#146 Keeping legacy image '%s': %v", file.Name, err))
+			referenced[file.Name] = true
+			continue
+		}
+		if record != nil {
+			referenced[record.Path] = true
+		}
+	}
+
+	removed := 0
+	for _, file := range files {
+		if referenced[file.Name] || time.Since(file.ModTime) < imageGCGracePeriod {
+			continue
+		}
+		if err := ah.images.Delete(file.Name); err != nil {
+			continue
+		}
+		removed++
+		log.Info(fmt.Sprintf("[acco-handler]ach#103 Removed unreferenced image file '%s'", file.Name))
+	}
+
+	return removed, nil
+}
+
+// Runs image garbage collection right away, so legacy images get their records,
+// and then periodically until context is cancelled
+func (ah *AccommodationHandler) RunImageGarbageCollector(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		removed, err := ah.CollectImageGarbage(ctx)
+		if err != nil {
+			log.Error(fmt.Sprintf("[acco-handler]ach#104 Image garbage collection failed: %v", err))
+		} else {
+			log.Info(fmt.Sprintf("[acco-handler]ach#105 Image garbage collection removed %d files", removed))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Creates record for image stored under legacy name, if its accommodation still exists.
+// Legacy images were stored without resized variants, so the record lists none and clients show the original.
+// Returns nil record and nil error if the file is not a legacy image or its accommodation was deleted,
+// and an error if it couldn't be decided or adopted, in which case the file must be kept.
+func (ah *AccommodationHandler) adoptLegacyImage(ctx context.Context, name string) (*data.ImageRecord, error) {
+	match := legacyImageName.FindStringSubmatch(name)
+	if match == nil {
+		return nil, nil
+	}
+
+	accID, _ := primitive.ObjectIDFromHex(match[1])
+	index, _ := strconv.Atoi(match[2])
+	if _, err := ah.repo.GetAccommodation(ctx, accID); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get accommodation: %w", err)
+	}
+
+	content, err := ah.images.Read(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	description, err := imaging.Describe(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe image: %w", err)
+	}
+
+	record := &data.ImageRecord{
+		ID:              primitive.NewObjectID(),
+		AccommodationID: accID,
+		Path:            name,
+		ContentType:     description.ContentType,
+		Order:           index,
+		Cover:           index == 0,
+		Width:           description.Width,
+		Height:          description.Height,
+		Size:            int64(len(content)),
+		CreatedAt:       time.Now(),
+	}
+	// Paths of image records are unique, so replicas collecting garbage at the same time adopt the image once
+	if err := ah.repo.CreateImages(ctx, []*data.ImageRecord{record}); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New("image was adopted by another replica")
+		}
+		return nil, fmt.Errorf("failed to create image record: %w", err)
+	}
+	log.Info(fmt.Sprintf("[acco-handler]ach#106 Created record for legacy image '%s'", name))
+	return record, nil
+}
+
+// Loads accommodation and checks that user sending the request is its host.
+// Writes error response and returns false if not.
+func (ah *AccommodationHandler) authorizeAccommodationHost(rw http.ResponseWriter, r *http.Request, accID primitive.ObjectID) (*data.Accommodation, bool) {
+	accommodation, err := ah.repo.GetAccommodation(r.Context(), accID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.NotFound(rw, r)
+			return nil, false
+		}
+		log.Error(fmt.Sprintf("[acco-handler]ach#81 Failed to retrieve accommodation '%s': %v", accID.Hex(), err))
+		http.Error(rw, "Failed to retrieve accommodation", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	isHost, err := ah.isAccommodationHost(r, accommodation)
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#82 Failed to verify host of accommodation '%s': %v", accID.Hex(), err))
+		http.Error(rw, "Failed to verify accommodation host", http.StatusBadRequest)
+		return nil, false
+	}
+	if !isHost {
+		log.Warning(fmt.Sprintf("[acco-handler]ach#83 User from '%s' tried to change accommodation '%s' they don't host", r.RemoteAddr, accID.Hex()))
+		http.Error(rw, "Forbidden", http.StatusForbidden)
+		return nil, false
+	}
+
+	return accommodation, true
+}
+
+// Checks if user sending the request is the host of accommodation
+func (ah *AccommodationHandler) isAccommodationHost(r *http.Request, accommodation *data.Accommodation) (bool, error) {
+	userID, err := ah.currentUserID(r)
+	if err != nil {
+		return false, err
+	}
+
+	return userID == accommodation.HostID, nil
+}
+
+func (ah *AccommodationHandler) getImageRecord(rw http.ResponseWriter, r *http.Request, accID, imageID primitive.ObjectID) (*data.ImageRecord, bool) {
+	record, err := ah.repo.GetImage(r.Context(), accID, imageID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.NotFound(rw, r)
+			return nil, false
+		}
+		http.Error(rw, "Failed to retrieve image", http.StatusInternalServerError)
+		return nil, false
+	}
+	return record, true
+}
+
+func (ah *AccommodationHandler) writeImageRecords(rw http.ResponseWriter, r *http.Request, accID primitive.ObjectID) {
+	records, err := ah.repo.GetImagesForAccommodation(r.Context(), accID)
+	if err != nil {
+		http.Error(rw, "Failed to retrieve images", http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set(ContentType, ApplicationJson)
+	rw.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(rw).Encode(records); err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#107 Failed to encode images: %v", err))
+	}
+}
+
+func (ah *AccommodationHandler) invalidateImageCache(accID primitive.ObjectID) {
+	if err := ah.imageCache.DeleteAll(accID.Hex()); err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#87 Unable to invalidate image cache: %v", err))
+	}
+}
+
+// Returns URL of image content, or of its variant
+func imageURL(record *data.ImageRecord, variant string) string {
+	url := fmt.Sprintf("%s/accommodation/%s/images/%s", imageBaseURL, record.AccommodationID.Hex(), record.ID.Hex())
+	if variant != "" {
+		url += "?variant=" + variant
+	}
+	return url
+}
+
+func imageIDsFromPath(rw http.ResponseWriter, r *http.Request) (primitive.ObjectID, primitive.ObjectID, bool) {
+	vars := mux.Vars(r)
+	accID, err := primitive.ObjectIDFromHex(vars["id"])
+	if err != nil {
+		http.Error(rw, InvalidID, http.StatusBadRequest)
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+	imageID, err := primitive.ObjectIDFromHex(vars["imageId"])
+	if err != nil {
+		http.Error(rw, "Invalid image ID", http.StatusBadRequest)
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+	return accID, imageID, true
+}
+
+func isImageVariant(name string) bool {
+	for _, variant := range imaging.Variants {
+		if variant.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func imageErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, imaging.ErrUnsupportedType):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, imaging.ErrImageTooLarge):
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
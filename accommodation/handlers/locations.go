@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"accommodation/data"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Returns locations for search box autocomplete, e.g. /locations/suggest?q=beo&limit=5.
+// Matching ignores letter case and accents.
+func (ah *AccommodationHandler) SuggestLocations(rw http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	prefix := strings.TrimSpace(query.Get("q"))
+	if prefix == "" {
+		http.Error(rw, "Query parameter q is required", http.StatusBadRequest)
+		return
+	}
+
+	limit := data.DefaultSuggestionLimit
+	if raw := query.Get("limit"); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 1 || value > data.MaxSuggestionLimit {
+			http.Error(rw, fmt.Sprintf("limit must be a number between 1 and %d", data.MaxSuggestionLimit), http.StatusBadRequest)
+			return
+		}
+		limit = value
+	}
+
+	suggestions, err := ah.repo.SuggestLocations(r.Context(), prefix, limit)
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#120 Failed to get location suggestions: %v", err))
+		http.Error(rw, "Failed to retrieve location suggestions", http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set(ContentType, ApplicationJson)
+	rw.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(rw).Encode(suggestions); err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#121 Failed to encode location suggestions: %v", err))
+	}
+}
+
+// Rebuilds location suggestions right away and then periodically until context is cancelled
+func (ah *AccommodationHandler) RunLocationSuggestionRebuilder(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		count, err := ah.repo.RebuildLocationSuggestions(ctx)
+		if err != nil {
+			log.Error(fmt.Sprintf("[acco-handler]ach#122 Failed to rebuild location suggestions: %v", err))
+		} else {
+			log.Info(fmt.Sprintf("[acco-handler]ach#123 Rebuilt %d location suggestions", count))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"accommodation/data"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	amenitiesModeAll = "all"
+	amenitiesModeAny = "any"
+)
+
+// Geo part of search query.
+// Point is set when searching around a location (lat, lon and optional radius in km),
+// Viewport is set when searching inside a map bounding box (swLat, swLon, neLat, neLon).
+type geoQuery struct {
+	Point    *data.GeoPoint
+	RadiusKm float64
+	Viewport *data.BoundingBox
+}
+
+func (gq *geoQuery) isEmpty() bool {
+	return gq.Point == nil && gq.Viewport == nil
+}
+
+// Reference point used for ordering results by distance
+func (gq *geoQuery) origin() *data.GeoPoint {
+	if gq.Point != nil {
+		return gq.Point
+	}
+	return gq.Viewport.Center()
+}
+
+func parseGeoQuery(query url.Values) (*geoQuery, error) {
+	gq := &geoQuery{}
+
+	lat, lon, radius := query.Get("lat"), query.Get("lon"), query.Get("radius")
+	if lat != "" || lon != "" {
+		if lat == "" || lon == "" {
+			return nil, errors.New("both lat and lon must be specified")
+		}
+		values, err := parseFloats(map[string]string{"lat": lat, "lon": lon})
+		if err != nil {
+			return nil, err
+		}
+		gq.Point, err = data.NewGeoPoint(values["lat"], values["lon"])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if radius != "" {
+		if gq.Point == nil {
+			return nil, errors.New("radius requires lat and lon")
+		}
+		value, err := strconv.ParseFloat(radius, 64)
+		if err != nil || value <= 0 {
+			return nil, errors.New("radius must be a positive number of kilometers")
+		}
+		gq.RadiusKm = value
+	}
+
+	corners := map[string]string{
+		"swLat": query.Get("swLat"),
+		"swLon": query.Get("swLon"),
+		"neLat": query.Get("neLat"),
+		"neLon": query.Get("neLon"),
+	}
+	specified := 0
+	for _, value := range corners {
+		if value != "" {
+			specified++
+		}
+	}
+	if specified > 0 {
+		if specified != len(corners) {
+			return nil, errors.New("viewport requires swLat, swLon, neLat and neLon")
+		}
+		values, err := parseFloats(corners)
+		if err != nil {
+			return nil, err
+		}
+		gq.Viewport, err = data.NewBoundingBox(values["swLat"], values["swLon"], values["neLat"], values["neLon"])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return gq, nil
+}
+
+// Parses amenities filter, e.g. amenities=wifi,parking&amenitiesMode=all.
+// Amenities are resolved with the catalog, so legacy numbers and English labels work as well.
+// Mode "all" (default) requires every listed amenity, mode "any" requires at least one.
+// Returns nil filter when no amenities are requested.
+func parseAmenityFilter(query url.Values, catalog *data.AmenityCatalog) (bson.M, error) {
+	raw := query.Get("amenities")
+	mode := query.Get("amenitiesMode")
+	if mode == "" {
+		mode = amenitiesModeAll
+	}
+	if mode != amenitiesModeAll && mode != amenitiesModeAny {
+		return nil, fmt.Errorf("invalid amenitiesMode '%s', expected '%s' or '%s'", mode, amenitiesModeAll, amenitiesModeAny)
+	}
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	var amenities []data.AmenityKey
+	for _, value := range strings.Split(raw, ",") {
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+		amenity, err := catalog.Resolve(value)
+		if err != nil {
+			return nil, err
+		}
+		amenities = append(amenities, amenity)
+	}
+	if len(amenities) == 0 {
+		return nil, nil
+	}
+
+	if mode == amenitiesModeAny {
+		return bson.M{"$in": amenities}, nil
+	}
+	return bson.M{"$all": amenities}, nil
+}
+
+// Adds filters on layout of the place to search filter, e.g.
+// propertyType=apartment,house&entirePlace=true&minBedrooms=2&minBeds=3&minBathrooms=1.
+// With entirePlace=true only types rented as entire places match, accommodations without type never do.
+func parseLayoutFilter(query url.Values, filter bson.M) error {
+	var types []data.PropertyType
+	for _, value := range strings.Split(query.Get("propertyType"), ",") {
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+		propertyType, err := data.ParsePropertyType(value)
+		if err != nil {
+			return err
+		}
+		types = append(types, propertyType)
+	}
+
+	switch entirePlace := query.Get("entirePlace"); entirePlace {
+	case "", "false":
+	case "true":
+		if types == nil {
+			types = data.EntirePlaceTypes()
+			break
+		}
+		entireTypes := []data.PropertyType{}
+		for _, propertyType := range types {
+			if propertyType.IsEntirePlace() {
+				entireTypes = append(entireTypes, propertyType)
+			}
+		}
+		types = entireTypes
+	default:
+		return fmt.Errorf("invalid entirePlace '%s', expected 'true' or 'false'", entirePlace)
+	}
+	if types != nil {
+		filter["propertyType"] = bson.M{"$in": types}
+	}
+
+	minimums := []struct{ param, field string }{
+		{"minBedrooms", "bedrooms"},
+		{"minBeds", "beds"},
+		{"minBathrooms", "bathrooms"},
+	}
+	for _, minimum := range minimums {
+		raw := query.Get(minimum.param)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 0 {
+			return fmt.Errorf("%s must be a non-negative whole number", minimum.param)
+		}
+		if value > 0 {
+			filter[minimum.field] = bson.M{"$gte": value}
+		}
+	}
+
+	return nil
+}
+
+func parseFloats(params map[string]string) (map[string]float64, error) {
+	values := make(map[string]float64, len(params))
+	for name, raw := range params {
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %s", name, raw)
+		}
+		values[name] = value
+	}
+	return values, nil
+}
@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"accommodation/data"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const maxImportBody = 5 << 20
+
+// Exports all accommodations of the host sending the request, with amenities and image URLs.
+// Format is selected with format query parameter, json (default) or csv.
+func (ah *AccommodationHandler) ExportAccommodations(rw http.ResponseWriter, r *http.Request) {
+	format, ok := transferFormat(rw, r.URL.Query().Get("format"), data.FormatJSON)
+	if !ok {
+		return
+	}
+
+	hostID, err := ah.currentUserID(r)
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#124 Failed to get ID of host: %v", err))
+		http.Error(rw, "Failed to get HostID from username", http.StatusBadRequest)
+		return
+	}
+
+	log.Info(fmt.Sprintf("[acco-handler]ach#125 Recieved request from '%s' to export accommodations of host '%s'", r.RemoteAddr, hostID.Hex()))
+
+	accommodations, err := ah.repo.GetAllAccommodationsForHost(r.Context(), hostID)
+	if err != nil {
+		http.Error(rw, "Failed to retrieve accommodations", http.StatusInternalServerError)
+		return
+	}
+
+	accIDs := make([]primitive.ObjectID, len(accommodations))
+	for i, accommodation := range accommodations {
+		accIDs[i] = accommodation.ID
+	}
+	images, err := ah.repo.GetImagesForAccommodations(r.Context(), accIDs)
+	if err != nil {
+		http.Error(rw, "Failed to retrieve images", http.StatusInternalServerError)
+		return
+	}
+
+	records := make([]*data.ListingRecord, len(accommodations))
+	for i, accommodation := range accommodations {
+		var urls []string
+		for _, image := range images[accommodation.ID] {
+			urls = append(urls, imageURL(image, ""))
+		}
+		records[i] = data.NewListingRecord(accommodation, urls)
+	}
+
+	// Encoded to buffer first, so encoding failure can still be reported with an error status
+	var body bytes.Buffer
+	contentType := ApplicationJson
+	if format == data.FormatCSV {
+		contentType = "text/csv; charset=utf-8"
+		err = data.WriteListingsCSV(&body, records)
+	} else {
+		err = data.WriteListingsJSON(&body, records)
+	}
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#126 Failed to encode exported accommodations: %v", err))
+		http.Error(rw, FailedToEncodeAccommodation, http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set(ContentType, contentType)
+	rw.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="accommodations.%s"`, format))
+	rw.WriteHeader(http.StatusOK)
+	if _, err := body.WriteTo(rw); err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#127 Failed to write exported accommodations: %v", err))
+	}
+	log.Info(fmt.Sprintf("[acco-handler]ach#128 Exported %d accommodations of host '%s'", len(records), hostID.Hex()))
+}
+
+// Imports accommodations of the host sending the request from CSV or JSON file in request body.
+// Accommodations are matched by externalRef, or by exported id among the host's own when record has no externalRef.
+// Matched ones are updated, others created as drafts.
+// Invalid records are reported per row and don't prevent importing valid ones.
+// With dryRun=true nothing is saved, the report shows what would happen.
+func (ah *AccommodationHandler) ImportAccommodations(rw http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	format, ok := transferFormat(rw, query.Get("format"), importFormatFromContentType(r))
+	if !ok {
+		return
+	}
+	dryRun := query.Get("dryRun") == "true"
+
+	hostID, err := ah.currentUserID(r)
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#129 Failed to get ID of host: %v", err))
+		http.Error(rw, "Failed to get HostID from username", http.StatusBadRequest)
+		return
+	}
+
+	log.Info(fmt.Sprintf("[acco-handler]ach#130 Recieved request from '%s' to import accommodations of host '%s' (dry run: %t)", r.RemoteAddr, hostID.Hex(), dryRun))
+
+	r.Body = http.MaxBytesReader(rw, r.Body, maxImportBody)
+	var records []*data.ListingRecord
+	var rowErrors map[int]data.ValidationErrors
+	if format == data.FormatCSV {
+		records, rowErrors, err = data.ReadListingsCSV(r.Body)
+	} else {
+		records, err = data.ReadListingsJSON(r.Body)
+	}
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#131 Failed to read import file: %v", err))
+		http.Error(rw, fmt.Sprintf("Failed to read %s file: %v", format, err), http.StatusBadRequest)
+		return
+	}
+
+	refs := make([]string, 0, len(records))
+	ids := make([]primitive.ObjectID, 0, len(records))
+	occurrences := make(map[string]int, len(records))
+	for _, record := range records {
+		key := importKey(record)
+		if key == "" {
+			continue
+		}
+		occurrences[key]++
+		if ref := strings.TrimSpace(record.ExternalRef); ref != "" {
+			refs = append(refs, ref)
+		} else if id, err := primitive.ObjectIDFromHex(strings.TrimSpace(record.ID)); err == nil {
+			ids = append(ids, id)
+		}
+	}
+
+	existing, err := ah.repo.GetAccommodationsByExternalRefs(r.Context(), hostID, refs)
+	if err != nil {
+		http.Error(rw, "Failed to retrieve accommodations", http.StatusInternalServerError)
+		return
+	}
+	existingByID, err := ah.repo.GetAccommodationsForHostByIDs(r.Context(), hostID, ids)
+	if err != nil {
+		http.Error(rw, "Failed to retrieve accommodations", http.StatusInternalServerError)
+		return
+	}
+
+	report := &data.ImportReport{DryRun: dryRun, Rows: []*data.ImportRowResult{}}
+	var changed []primitive.ObjectID
+	for i, record := range records {
+		errs := rowErrors[i]
+		if key := importKey(record); occurrences[key] > 1 {
+			if strings.TrimSpace(record.ExternalRef) != "" {
+				errs = append(errs, data.FieldError{Field: "externalRef", Message: "is used by more than one record"})
+			} else {
+				errs = append(errs, data.FieldError{Field: "id", Message: "is used by more than one record"})
+			}
+		}
+
+		result := ah.importRecord(r, hostID, record, existing, existingByID, errs, dryRun)
+		result.Row = i + 1
+		report.Add(result)
+		if !dryRun && result.ID != "" {
+			id, _ := primitive.ObjectIDFromHex(result.ID)
+			changed = append(changed, id)
+		}
+	}
+
+	if len(changed) > 0 {
+		ah.invalidateAccommodations(hostID, changed...)
+	}
+
+	rw.Header().Set(ContentType, ApplicationJson)
+	rw.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(rw).Encode(report); err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#133 Failed to encode import report: %v", err))
+	}
+	log.Info(fmt.Sprintf("[acco-handler]ach#134 Import for host '%s': %d created, %d updated, %d failed", hostID.Hex(), report.Created, report.Updated, report.Failed))
+}
+
+// Validates record and creates or updates its accommodation, unless it is a dry run.
+// Errors found while reading the record are passed in errs and fail it as well.
+func (ah *AccommodationHandler) importRecord(r *http.Request, hostID primitive.ObjectID, record *data.ListingRecord,
+	existing map[string]*data.Accommodation, existingByID map[primitive.ObjectID]*data.Accommodation,
+	errs data.ValidationErrors, dryRun bool) *data.ImportRowResult {
+	result := &data.ImportRowResult{ExternalRef: strings.TrimSpace(record.ExternalRef), Action: data.ImportFailed}
+
+	accommodation, recordErrs := record.Accommodation(ah.amenityCatalog())
+	errs = append(errs, recordErrs...)
+
+	previous, ok := existing[accommodation.ExternalRef]
+	if accommodation.ExternalRef == "" && len(recordErrs) == 0 {
+		id, _ := primitive.ObjectIDFromHex(strings.TrimSpace(record.ID))
+		if previous, ok = existingByID[id]; !ok {
+			errs = append(errs, data.FieldError{Field: "id", Message: "doesn't match any of your accommodations"})
+		}
+	}
+	if len(errs) > 0 {
+		result.Errors = errs
+		return result
+	}
+
+	var err error
+	accommodation.HostID = hostID
+	if ok {
+		// Matched by ID, the accommodation keeps its reference, if it has one
+		if accommodation.ExternalRef == "" {
+			accommodation.ExternalRef = previous.ExternalRef
+		}
+		accommodation.ID = previous.ID
+		result.ID = previous.ID.Hex()
+		result.Action = data.ImportUpdate
+		if !dryRun {
+			err = ah.repo.UpdateAccommodation(r.Context(), accommodation)
+		}
+	} else {
+		accommodation.ID = primitive.NewObjectID()
+		accommodation.Status = data.StatusDraft
+		result.Action = data.ImportCreate
+		if !dryRun {
+			err = ah.repo.CreateAccommodation(r.Context(), accommodation)
+		}
+	}
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#132 Failed to import accommodation '%s': %v", result.ExternalRef, err))
+		result.Action = data.ImportFailed
+		result.ID = ""
+		result.Errors = data.ValidationErrors{{Message: "failed to save accommodation"}}
+		return result
+	}
+
+	if !dryRun {
+		result.ID = accommodation.ID.Hex()
+	}
+	return result
+}
+
+// Key by which record is matched to an existing accommodation, externalRef or id when there is no reference
+func importKey(record *data.ListingRecord) string {
+	if ref := strings.TrimSpace(record.ExternalRef); ref != "" {
+		return "ref:" + ref
+	}
+	if id := strings.TrimSpace(record.ID); id != "" {
+		return "id:" + id
+	}
+	return ""
+}
+
+func transferFormat(rw http.ResponseWriter, format, defaultFormat string) (string, bool) {
+	if format == "" {
+		format = defaultFormat
+	}
+	if format != data.FormatCSV && format != data.FormatJSON {
+		http.Error(rw, fmt.Sprintf("Invalid format '%s', expected '%s' or '%s'", format, data.FormatCSV, data.FormatJSON), http.StatusBadRequest)
+		return "", false
+	}
+	return format, true
+}
+
+func importFormatFromContentType(r *http.Request) string {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get(ContentType))
+	if mediaType == "text/csv" {
+		return data.FormatCSV
+	}
+	return data.FormatJSON
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"accommodation/data"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type validationResponse struct {
+	Errors data.ValidationErrors `json:"errors"`
+}
+
+// JSON names of accommodation fields, by lower case name since JSON keys are matched case-insensitively.
+// amenityKeys is not a field of its own, it is decoded into amenities by data.Accommodation.UnmarshalJSON.
+var accommodationFields = func() map[string]string {
+	names := jsonFieldNames(reflect.TypeOf(data.Accommodation{}))
+	names["amenitykeys"] = "amenityKeys"
+	return names
+}()
+
+// Decodes accommodation payload and validates it, amenities against the catalog.
+// Fields are decoded one by one, so every unknown field, value of wrong type and invalid value is reported.
+// Returns data.ValidationErrors for invalid content and other errors for malformed JSON.
+func decodeAccommodation(r io.Reader, accommodation *data.Accommodation, catalog *data.AmenityCatalog) error {
+	var fields map[string]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&fields); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs data.ValidationErrors
+	undecoded := make(map[string]bool)
+	for _, name := range names {
+		field, ok := accommodationFields[strings.ToLower(name)]
+		if !ok {
+			errs.Add(name, "unknown field")
+			continue
+		}
+		if fieldErr := decodeField(accommodation, field, fields[name]); fieldErr != nil {
+			errs = append(errs, *fieldErr)
+			undecoded[field] = true
+		}
+	}
+
+	// Fields that failed to decode are already reported, their zero values are not
+	for _, fieldErr := range append(accommodation.Validate(), catalog.Validate(accommodation.Amenities)...) {
+		if !undecoded[topLevelField(fieldErr.Field)] {
+			errs = append(errs, fieldErr)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Decodes value of one field into accommodation, returns the violation if it can't be decoded
+func decodeField(accommodation *data.Accommodation, field string, value json.RawMessage) *data.FieldError {
+	single, err := json.Marshal(map[string]json.RawMessage{field: value})
+	if err != nil {
+		return &data.FieldError{Field: field, Message: "is invalid"}
+	}
+
+	err = json.Unmarshal(single, accommodation)
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return &data.FieldError{Field: typeErr.Field, Message: fmt.Sprintf("must be %s", typeErr.Type)}
+	case errors.As(err, &typeErr):
+		return &data.FieldError{Field: field, Message: fmt.Sprintf("must be %s", typeErr.Type)}
+	}
+	return &data.FieldError{Field: field, Message: err.Error()}
+}
+
+func jsonFieldNames(t reflect.Type) map[string]string {
+	names := make(map[string]string, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = t.Field(i).Name
+		}
+		names[strings.ToLower(name)] = name
+	}
+	return names
+}
+
+// Returns name of the payload field a violation is reported for, e.g. rooms for rooms[0].beds
+func topLevelField(field string) string {
+	if i := strings.IndexAny(field, ".["); i >= 0 {
+		return field[:i]
+	}
+	return field
+}
+
+// Writes decoding error of accommodation payload, 422 with field errors when payload is invalid
+func writeDecodeError(rw http.ResponseWriter, err error) {
+	var errs data.ValidationErrors
+	if !errors.As(err, &errs) {
+		http.Error(rw, FailedToDecodeRequestBody, http.StatusBadRequest)
+		return
+	}
+
+	rw.Header().Set(ContentType, ApplicationJson)
+	rw.WriteHeader(http.StatusUnprocessableEntity)
+	if err := json.NewEncoder(rw).Encode(validationResponse{Errors: errs}); err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#118 Failed to encode validation errors: %v", err))
+	}
+}
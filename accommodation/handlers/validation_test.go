@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"accommodation/data"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeAccommodation(t *testing.T) {
+	catalog := data.NewAmenityCatalog(data.DefaultAmenities())
+
+	tests := []struct {
+		name    string
+		payload string
+		want    []string
+	}{
+		{
+			name:    "valid payload",
+			payload: `{"name":"Loft","location":"Novi Sad","amenities":["wifi"],"minGuests":1,"maxGuests":2}`,
+		},
+		{
+			name:    "keys are matched case-insensitively",
+			payload: `{"Name":"Loft","LOCATION":"Novi Sad","amenityKeys":["wifi"],"minGuests":1,"maxGuests":2}`,
+		},
+		{
+			name:    "unknown field",
+			payload: `{"name":"Loft","location":"Novi Sad","minGuests":1,"maxGuests":2,"pets":true}`,
+			want:    []string{"pets: unknown field"},
+		},
+		{
+			name:    "value of wrong type",
+			payload: `{"name":"Loft","location":"Novi Sad","minGuests":1,"maxGuests":"two"}`,
+			want:    []string{"maxGuests: must be int"},
+		},
+		{
+			name:    "nested value of wrong type",
+			payload: `{"name":"Loft","location":"Novi Sad","minGuests":1,"maxGuests":2,"rooms":[{"name":"Bedroom","beds":{}}]}`,
+			want:    []string{"rooms.0.beds: must be []data.BedCount"},
+		},
+		{
+			name:    "unknown amenity",
+			payload: `{"name":"Loft","location":"Novi Sad","amenities":["helipad"],"minGuests":1,"maxGuests":2}`,
+			want:    []string{"amenities[0]: unknown amenity 'helipad'"},
+		},
+		{
+			name:    "every violation is reported together",
+			payload: `{"name":"","location":7,"minGuests":3,"maxGuests":2,"floor":1,"bookingMode":"later"}`,
+			want: []string{
+				"floor: unknown field",
+				"location: must be string",
+				"name: is required",
+				"minGuests: must not be greater than maxGuests",
+				"bookingMode: must be instant or request",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var accommodation data.Accommodation
+			err := decodeAccommodation(strings.NewReader(tt.payload), &accommodation, catalog)
+
+			var errs data.ValidationErrors
+			if err != nil && !errors.As(err, &errs) {
+				t.Fatalf("error %v, want validation errors", err)
+			}
+			var got []string
+			for _, fieldErr := range errs {
+				got = append(got, fieldErr.Field+": "+fieldErr.Message)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("errors %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteDecodeError(t *testing.T) {
+	catalog := data.NewAmenityCatalog(data.DefaultAmenities())
+
+	tests := []struct {
+		name    string
+		payload string
+		status  int
+		fields  []string
+	}{
+		{
+			name:    "malformed JSON",
+			payload: `{"name":`,
+			status:  http.StatusBadRequest,
+		},
+		{
+			name:    "invalid payload",
+			payload: `{"name":"","location":"Novi Sad","minGuests":"one","maxGuests":2,"pets":true}`,
+			status:  http.StatusUnprocessableEntity,
+			fields:  []string{"minGuests", "pets", "name"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var accommodation data.Accommodation
+			err := decodeAccommodation(strings.NewReader(tt.payload), &accommodation, catalog)
+			if err == nil {
+				t.Fatal("payload decoded without error")
+			}
+
+			rec := httptest.NewRecorder()
+			writeDecodeError(rec, err)
+
+			if rec.Code != tt.status {
+				t.Errorf("status %v, want %v", rec.Code, tt.status)
+			}
+			if tt.status != http.StatusUnprocessableEntity {
+				return
+			}
+
+			var response validationResponse
+			if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			var fields []string
+			for _, fieldErr := range response.Errors {
+				fields = append(fields, fieldErr.Field)
+			}
+			if !reflect.DeepEqual(fields, tt.fields) {
+				t.Errorf("fields %v, want %v", fields, tt.fields)
+			}
+		})
+	}
+}
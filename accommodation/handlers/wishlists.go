@@ -0,0 +1,307 @@
+package handlers
+
+import (
+	"accommodation/data"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Lists wishlists of the guest sending the request, with their accommodations
+func (ah *AccommodationHandler) GetWishlists(rw http.ResponseWriter, r *http.Request) {
+	guestID, ok := ah.wishlistGuest(rw, r)
+	if !ok {
+		return
+	}
+
+	log.Info(fmt.Sprintf("[acco-handler]ach#135 Recieved request from '%s' for wishlists of guest '%s'", r.RemoteAddr, guestID.Hex()))
+
+	wishlists, err := ah.repo.GetWishlistsForGuest(r.Context(), guestID)
+	if err != nil {
+		http.Error(rw, "Failed to retrieve wishlists", http.StatusInternalServerError)
+		return
+	}
+
+	var accIDs []primitive.ObjectID
+	for _, wishlist := range wishlists {
+		accIDs = append(accIDs, wishlist.AccommodationIDs...)
+	}
+	accommodations, err := ah.repo.FindAccommodationsByIDs(r.Context(), accIDs)
+	if err != nil {
+		http.Error(rw, "Failed to retrieve accommodations", http.StatusInternalServerError)
+		return
+	}
+
+	details := make([]*data.WishlistDetails, len(wishlists))
+	for i, wishlist := range wishlists {
+		details[i] = data.NewWishlistDetails(wishlist, *accommodations)
+	}
+
+	writeWishlistResponse(rw, http.StatusOK, details)
+}
+
+func (ah *AccommodationHandler) GetWishlist(rw http.ResponseWriter, r *http.Request) {
+	guestID, ok := ah.wishlistGuest(rw, r)
+	if !ok {
+		return
+	}
+	wishlist, ok := ah.getWishlist(rw, r, guestID)
+	if !ok {
+		return
+	}
+
+	ah.writeWishlistDetails(rw, r, http.StatusOK, wishlist)
+}
+
+func (ah *AccommodationHandler) CreateWishlist(rw http.ResponseWriter, r *http.Request) {
+	guestID, ok := ah.wishlistGuest(rw, r)
+	if !ok {
+		return
+	}
+
+	log.Info(fmt.Sprintf("[acco-handler]ach#136 Recieved request from '%s' to create wishlist", r.RemoteAddr))
+
+	name, ok := decodeWishlistName(rw, r)
+	if !ok {
+		return
+	}
+
+	count, err := ah.repo.CountWishlistsForGuest(r.Context(), guestID)
+	if err != nil {
+		http.Error(rw, "Failed to create wishlist", http.StatusInternalServerError)
+		return
+	}
+	if count >= data.MaxWishlistsPerGuest {
+		http.Error(rw, fmt.Sprintf("Guest can have at most %d wishlists", data.MaxWishlistsPerGuest), http.StatusConflict)
+		return
+	}
+
+	now := time.Now()
+	wishlist := &data.Wishlist{
+		ID:               primitive.NewObjectID(),
+		GuestID:          guestID,
+		Name:             name,
+		AccommodationIDs: []primitive.ObjectID{},
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	if err := ah.repo.CreateWishlist(r.Context(), wishlist); err != nil {
+		http.Error(rw, "Failed to create wishlist", http.StatusInternalServerError)
+		return
+	}
+
+	writeWishlistResponse(rw, http.StatusCreated, data.NewWishlistDetails(wishlist, nil))
+	log.Info(fmt.Sprintf("[acco-handler]ach#137 Successfully created wishlist '%s'", wishlist.ID.Hex()))
+}
+
+func (ah *AccommodationHandler) RenameWishlist(rw http.ResponseWriter, r *http.Request) {
+	guestID, ok := ah.wishlistGuest(rw, r)
+	if !ok {
+		return
+	}
+	id, ok := wishlistIDFromPath(rw, r)
+	if !ok {
+		return
+	}
+
+	name, ok := decodeWishlistName(rw, r)
+	if !ok {
+		return
+	}
+
+	if err := ah.repo.RenameWishlist(r.Context(), guestID, id, name); err != nil {
+		writeWishlistError(rw, r, err)
+		return
+	}
+
+	wishlist, ok := ah.getWishlist(rw, r, guestID)
+	if !ok {
+		return
+	}
+	ah.writeWishlistDetails(rw, r, http.StatusOK, wishlist)
+}
+
+func (ah *AccommodationHandler) DeleteWishlist(rw http.ResponseWriter, r *http.Request) {
+	guestID, ok := ah.wishlistGuest(rw, r)
+	if !ok {
+		return
+	}
+	id, ok := wishlistIDFromPath(rw, r)
+	if !ok {
+		return
+	}
+
+	if err := ah.repo.DeleteWishlist(r.Context(), guestID, id); err != nil {
+		writeWishlistError(rw, r, err)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+	log.Info(fmt.Sprintf("[acco-handler]ach#138 Successfully deleted wishlist '%s'", id.Hex()))
+}
+
+// Saves published accommodation to wishlist, saving it again changes nothing
+func (ah *AccommodationHandler) AddToWishlist(rw http.ResponseWriter, r *http.Request) {
+	guestID, ok := ah.wishlistGuest(rw, r)
+	if !ok {
+		return
+	}
+	wishlist, ok := ah.getWishlist(rw, r, guestID)
+	if !ok {
+		return
+	}
+	accID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(rw, InvalidID, http.StatusBadRequest)
+		return
+	}
+
+	accommodation, err := ah.repo.GetAccommodation(r.Context(), accID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			http.NotFound(rw, r)
+			return
+		}
+		http.Error(rw, "Failed to retrieve accommodation", http.StatusInternalServerError)
+		return
+	}
+	if accommodation.CurrentStatus() != data.StatusPublished {
+		http.Error(rw, "Only published accommodations can be saved", http.StatusConflict)
+		return
+	}
+
+	err = ah.repo.AddToWishlist(r.Context(), guestID, wishlist.ID, accID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// Wishlist exists, so it has been filled up
+		http.Error(rw, fmt.Sprintf("Wishlist can have at most %d accommodations", data.MaxWishlistAccommodations), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(rw, "Failed to update wishlist", http.StatusInternalServerError)
+		return
+	}
+
+	wishlist, ok = ah.getWishlist(rw, r, guestID)
+	if !ok {
+		return
+	}
+	ah.writeWishlistDetails(rw, r, http.StatusOK, wishlist)
+}
+
+func (ah *AccommodationHandler) RemoveFromWishlist(rw http.ResponseWriter, r *http.Request) {
+	guestID, ok := ah.wishlistGuest(rw, r)
+	if !ok {
+		return
+	}
+	id, ok := wishlistIDFromPath(rw, r)
+	if !ok {
+		return
+	}
+	accID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(rw, InvalidID, http.StatusBadRequest)
+		return
+	}
+
+	if err := ah.repo.RemoveFromWishlist(r.Context(), guestID, id, accID); err != nil {
+		writeWishlistError(rw, r, err)
+		return
+	}
+
+	wishlist, ok := ah.getWishlist(rw, r, guestID)
+	if !ok {
+		return
+	}
+	ah.writeWishlistDetails(rw, r, http.StatusOK, wishlist)
+}
+
+// Removes deleted accommodations from wishlists of all guests
+func (ah *AccommodationHandler) removeFromWishlists(r *http.Request, accIDs []primitive.ObjectID) {
+	if err := ah.repo.RemoveAccommodationsFromWishlists(r.Context(), accIDs); err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#139 Failed to remove deleted accommodations from wishlists: %v", err))
+	}
+}
+
+func (ah *AccommodationHandler) wishlistGuest(rw http.ResponseWriter, r *http.Request) (primitive.ObjectID, bool) {
+	guestID, err := ah.currentUserID(r)
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#140 Failed to get ID of guest: %v", err))
+		http.Error(rw, "Failed to get GuestID from username", http.StatusBadRequest)
+		return primitive.NilObjectID, false
+	}
+	return guestID, true
+}
+
+func (ah *AccommodationHandler) getWishlist(rw http.ResponseWriter, r *http.Request, guestID primitive.ObjectID) (*data.Wishlist, bool) {
+	id, ok := wishlistIDFromPath(rw, r)
+	if !ok {
+		return nil, false
+	}
+
+	wishlist, err := ah.repo.GetWishlist(r.Context(), guestID, id)
+	if err != nil {
+		writeWishlistError(rw, r, err)
+		return nil, false
+	}
+	return wishlist, true
+}
+
+func (ah *AccommodationHandler) writeWishlistDetails(rw http.ResponseWriter, r *http.Request, status int, wishlist *data.Wishlist) {
+	accommodations, err := ah.repo.FindAccommodationsByIDs(r.Context(), wishlist.AccommodationIDs)
+	if err != nil {
+		http.Error(rw, "Failed to retrieve accommodations", http.StatusInternalServerError)
+		return
+	}
+
+	writeWishlistResponse(rw, status, data.NewWishlistDetails(wishlist, *accommodations))
+}
+
+func writeWishlistResponse(rw http.ResponseWriter, status int, response interface{}) {
+	rw.Header().Set(ContentType, ApplicationJson)
+	rw.WriteHeader(status)
+	if err := json.NewEncoder(rw).Encode(response); err != nil {
+		log.Error(fmt.Sprintf("[acco-handler]ach#141 Failed to encode wishlist: %v", err))
+	}
+}
+
+// Wishlists of other guests are reported as not found
+func writeWishlistError(rw http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		http.NotFound(rw, r)
+		return
+	}
+	http.Error(rw, "Failed to update wishlist", http.StatusInternalServerError)
+}
+
+func wishlistIDFromPath(rw http.ResponseWriter, r *http.Request) (primitive.ObjectID, bool) {
+	id, err := primitive.ObjectIDFromHex(mux.Vars(r)["wishlistId"])
+	if err != nil {
+		http.Error(rw, "Invalid wishlist ID", http.StatusBadRequest)
+		return primitive.NilObjectID, false
+	}
+	return id, true
+}
+
+func decodeWishlistName(rw http.ResponseWriter, r *http.Request) (string, bool) {
+	var body data.WishlistName
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&body); err != nil {
+		http.Error(rw, FailedToDecodeRequestBody, http.StatusBadRequest)
+		return "", false
+	}
+
+	if errs := body.Validate(); len(errs) > 0 {
+		writeDecodeError(rw, errs)
+		return "", false
+	}
+	return body.Name, true
+}
@@ -0,0 +1,220 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/webp"
+)
+
+const (
+	ContentTypeJPEG = "image/jpeg"
+	ContentTypePNG  = "image/png"
+	ContentTypeWebP = "image/webp"
+
+	MaxImageSize        = 10 << 20 // 10 MB per image
+	MaxListingSize      = 30 << 20 // 30 MB for all images of one accommodation
+	MaxImagesPerListing = 10
+
+	// Guards against decompression bombs, checked before pixels are decoded
+	maxPixels = 50_000_000
+
+	jpegQuality = 85
+)
+
+var (
+	ErrUnsupportedType = errors.New("unsupported image type, allowed types are JPEG, PNG and WebP")
+	ErrImageTooLarge   = errors.New("image is too large")
+	ErrInvalidImage    = errors.New("invalid image")
+)
+
+// Resized copy of uploaded image, longer side is at most MaxDimension pixels
+type Variant struct {
+	Name         string
+	MaxDimension int
+}
+
+var Variants = []Variant{
+	{Name: "thumbnail", MaxDimension: 320},
+	{Name: "card", MaxDimension: 800},
+	{Name: "full", MaxDimension: 1920},
+}
+
+type EncodedImage struct {
+	ContentType string
+	Data        []byte
+	Width       int
+	Height      int
+}
+
+type ProcessedImage struct {
+	// Uploaded image with metadata removed
+	Original *EncodedImage
+	// Resized images by variant name
+	Variants map[string]*EncodedImage
+}
+
+// Validates uploaded image, removes its metadata and generates all variants
+func Process(data []byte) (*ProcessedImage, error) {
+	if len(data) > MaxImageSize {
+		return nil, fmt.Errorf("%w: %d bytes, maximum is %d", ErrImageTooLarge, len(data), MaxImageSize)
+	}
+
+	// Content is sniffed, declared type and file extension can't be trusted
+	contentType := http.DetectContentType(data)
+	if contentType != ContentTypeJPEG && contentType != ContentTypePNG && contentType != ContentTypeWebP {
+		return nil, fmt.Errorf("%w, got %s", ErrUnsupportedType, contentType)
+	}
+
+	config, err := decodeConfig(data, contentType)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	if config.Width*config.Height > maxPixels {
+		return nil, fmt.Errorf("%w: %dx%d pixels", ErrImageTooLarge, config.Width, config.Height)
+	}
+
+	img, err := decode(data, contentType)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+
+	stripped, err := stripMetadata(data, contentType)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+
+	processed := &ProcessedImage{
+		Original: &EncodedImage{
+			ContentType: contentType,
+			Data:        stripped,
+			Width:       config.Width,
+			Height:      config.Height,
+		},
+		Variants: make(map[string]*EncodedImage, len(Variants)),
+	}
+
+	// Orientation is lost with metadata, so rotated photos are turned upright instead.
+	// The original is re-encoded only then, other originals keep their pixels untouched.
+	if contentType == ContentTypeJPEG {
+		if orientation := jpegOrientation(data); orientation > 1 {
+			img = applyOrientation(img, orientation)
+
+			var buf bytes.Buffer
+			if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+				return nil, err
+			}
+			processed.Original.Data = buf.Bytes()
+			processed.Original.Width = img.Bounds().Dx()
+			processed.Original.Height = img.Bounds().Dy()
+		}
+	}
+
+	for _, variant := range Variants {
+		encoded, err := encode(resize(img, variant.MaxDimension))
+		if err != nil {
+			return nil, err
+		}
+		processed.Variants[variant.Name] = encoded
+	}
+
+	return processed, nil
+}
+
+// Returns type and dimensions of already stored image without decoding its pixels
+func Describe(data []byte) (*EncodedImage, error) {
+	contentType := http.DetectContentType(data)
+	if contentType != ContentTypeJPEG && contentType != ContentTypePNG && contentType != ContentTypeWebP {
+		return nil, fmt.Errorf("%w, got %s", ErrUnsupportedType, contentType)
+	}
+
+	config, err := decodeConfig(data, contentType)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+
+	return &EncodedImage{
+		ContentType: contentType,
+		Width:       config.Width,
+		Height:      config.Height,
+	}, nil
+}
+
+func decodeConfig(data []byte, contentType string) (image.Config, error) {
+	reader := bytes.NewReader(data)
+	switch contentType {
+	case ContentTypeJPEG:
+		return jpeg.DecodeConfig(reader)
+	case ContentTypePNG:
+		return png.DecodeConfig(reader)
+	}
+	return webp.DecodeConfig(reader)
+}
+
+func decode(data []byte, contentType string) (image.Image, error) {
+	reader := bytes.NewReader(data)
+	switch contentType {
+	case ContentTypeJPEG:
+		return jpeg.Decode(reader)
+	case ContentTypePNG:
+		return png.Decode(reader)
+	}
+	return webp.Decode(reader)
+}
+
+// Scales image down so its longer side fits maxDimension, smaller images are kept as they are
+func resize(img image.Image, maxDimension int) image.Image {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w <= maxDimension && h <= maxDimension {
+		return img
+	}
+
+	if w >= h {
+		h = h * maxDimension / w
+		w = maxDimension
+	} else {
+		w = w * maxDimension / h
+		h = maxDimension
+	}
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+	return dst
+}
+
+// Images with transparency are encoded as PNG, all others as JPEG
+func encode(img image.Image) (*EncodedImage, error) {
+	var buf bytes.Buffer
+	encoded := &EncodedImage{
+		Width:  img.Bounds().Dx(),
+		Height: img.Bounds().Dy(),
+	}
+
+	if opaque, ok := img.(interface{ Opaque() bool }); ok && !opaque.Opaque() {
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, err
+		}
+		encoded.ContentType = ContentTypePNG
+	} else {
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, err
+		}
+		encoded.ContentType = ContentTypeJPEG
+	}
+
+	encoded.Data = buf.Bytes()
+	return encoded, nil
+}
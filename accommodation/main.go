@@ -54,21 +54,31 @@ func main() {
 	}
 	defer store.Disconnect(timeoutContext)
 	store.Ping()
+	if err := store.CreateIndexes(timeoutContext); err != nil {
+		log.Error(fmt.Sprintf("[acco-service]acs#13 Failed to create indexes: %v", err))
+	}
+	if err := store.SeedAmenityCatalog(timeoutContext); err != nil {
+		log.Error(fmt.Sprintf("[acco-service]acs#14 Failed to seed amenity catalog: %v", err))
+	}
+	if migrated, err := store.MigrateLegacyAmenities(timeoutContext); err != nil {
+		log.Error(fmt.Sprintf("[acco-service]acs#15 Failed to migrate legacy amenities: %v", err))
+	} else if migrated > 0 {
+		log.Info(fmt.Sprintf("[acco-service]acs#16 Migrated amenities of %d accommodations to amenity keys", migrated))
+	}
 
 	// Redis
 	imageCache := cache.New()
 	imageCache.Ping()
+	accommodationCache := cache.NewAccommodationCache()
 
-	// HDFS
-	images, err := storage.New()
+	// Image storage, HDFS or local directory
+	images, err := storage.NewBlobStorage()
 	if err != nil {
-		log.Fatal(fmt.Sprintf("[acco-service]acs#2 Failed to initialize HDFS: %v", err))
+		log.Fatal(fmt.Sprintf("[acco-service]acs#2 Failed to initialize image storage: %v", err))
 	}
 
 	defer images.Close()
 
-	_ = images.CreateDirectories()
-
 	// CBs
 	reservationClient := &http.Client{
 		Transport: &http.Transport{
@@ -133,7 +143,20 @@ func main() {
 	reservation := clients.NewReservationClient(reservationClient, os.Getenv("RESERVATION_SERVICE_URI"), reservationBreaker)
 	profile := clients.NewProfileClient(profileClient, os.Getenv("PROFILE_SERVICE_URI"), profileBreaker)
 
-	accommodationsHandler := handlers.NewAccommodationsHandler(store, reservation, profile, imageCache, images)
+	accommodationsHandler := handlers.NewAccommodationsHandler(store, reservation, profile, imageCache, accommodationCache, images)
+	if err := accommodationsHandler.LoadAmenityCatalog(timeoutContext); err != nil {
+		log.Error(fmt.Sprintf("[acco-service]acs#17 Failed to load amenity catalog, using default amenities: %v", err))
+	}
+
+	// Background jobs: removing stored image files without records, left by failed uploads and deletes,
+	// rebuilding location suggestions and reloading amenity catalog
+	jobsContext, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go accommodationsHandler.RunImageGarbageCollector(jobsContext, 6*time.Hour)
+
+	// Location suggestions follow published accommodations with a delay of at most one rebuild interval
+	go accommodationsHandler.RunLocationSuggestionRebuilder(jobsContext, 10*time.Minute)
+	go accommodationsHandler.RunAmenityCatalogRefresher(jobsContext, 5*time.Minute)
 
 	// Router init
 	router := mux.NewRouter()
@@ -146,9 +169,28 @@ func main() {
 	createAccommodationImagesRouter.HandleFunc("", accommodationsHandler.CreateAccommodationImages)
 	createAccommodationImagesRouter.Use(accommodationsHandler.AuthorizeRoles("HOST"))
 
+	uploadAccommodationImagesRouter := router.Methods(http.MethodPost).Path(AccommodationPath + "/images").Subrouter()
+	uploadAccommodationImagesRouter.HandleFunc("", accommodationsHandler.UploadAccommodationImages)
+	uploadAccommodationImagesRouter.Use(accommodationsHandler.AuthorizeRoles("HOST"))
+
 	getAccommodationImagesRouter := router.Methods(http.MethodGet).Path(AccommodationPath + "/images").Subrouter()
 	getAccommodationImagesRouter.HandleFunc("", accommodationsHandler.GetAccommodationImages)
-	getAccommodationImagesRouter.Use(accommodationsHandler.MiddlewareCacheAllHit)
+	getAccommodationImagesRouter.Use(accommodationsHandler.MiddlewareAccommodationVisible, accommodationsHandler.MiddlewareCacheAllHit)
+
+	serveAccommodationImageRouter := router.Methods(http.MethodGet, http.MethodHead).Path(AccommodationPath + "/images/{imageId}").Subrouter()
+	serveAccommodationImageRouter.HandleFunc("", accommodationsHandler.ServeAccommodationImage)
+
+	reorderAccommodationImagesRouter := router.Methods(http.MethodPut).Path(AccommodationPath + "/images/order").Subrouter()
+	reorderAccommodationImagesRouter.HandleFunc("", accommodationsHandler.ReorderAccommodationImages)
+	reorderAccommodationImagesRouter.Use(accommodationsHandler.AuthorizeRoles("HOST"))
+
+	updateAccommodationImageRouter := router.Methods(http.MethodPatch).Path(AccommodationPath + "/images/{imageId}").Subrouter()
+	updateAccommodationImageRouter.HandleFunc("", accommodationsHandler.UpdateAccommodationImage)
+	updateAccommodationImageRouter.Use(accommodationsHandler.AuthorizeRoles("HOST"))
+
+	deleteAccommodationImageRouter := router.Methods(http.MethodDelete).Path(AccommodationPath + "/images/{imageId}").Subrouter()
+	deleteAccommodationImageRouter.HandleFunc("", accommodationsHandler.DeleteAccommodationImage)
+	deleteAccommodationImageRouter.Use(accommodationsHandler.AuthorizeRoles("HOST"))
 
 	getAllAccommodationRouter := router.Methods(http.MethodGet).Path("/accommodation").Subrouter()
 	getAllAccommodationRouter.HandleFunc("", accommodationsHandler.GetAllAccommodations)
@@ -156,6 +198,9 @@ func main() {
 	getAccommodationRouter := router.Methods(http.MethodGet).Path(AccommodationPath).Subrouter()
 	getAccommodationRouter.HandleFunc("", accommodationsHandler.GetAccommodation)
 
+	getAccommodationUnitsRouter := router.Methods(http.MethodGet).Path(AccommodationPath + "/units").Subrouter()
+	getAccommodationUnitsRouter.HandleFunc("", accommodationsHandler.GetAccommodationUnits)
+
 	getAccommodationsForUserRouter := router.Methods(http.MethodGet).Path("/user/{username}/accommodations").Subrouter()
 	getAccommodationsForUserRouter.HandleFunc("", accommodationsHandler.GetAccommodationsForUser)
 
@@ -163,6 +208,10 @@ func main() {
 	updateAccommodationRouter.HandleFunc("", accommodationsHandler.UpdateAccommodation)
 	updateAccommodationRouter.Use(accommodationsHandler.AuthorizeRoles("HOST"))
 
+	changeAccommodationStatusRouter := router.Methods(http.MethodPut).Path(AccommodationPath + "/status").Subrouter()
+	changeAccommodationStatusRouter.HandleFunc("", accommodationsHandler.ChangeAccommodationStatus)
+	changeAccommodationStatusRouter.Use(accommodationsHandler.AuthorizeRoles("HOST"))
+
 	deleteAccommodationRouter := router.Methods(http.MethodDelete).Path(AccommodationPath).Subrouter()
 	deleteAccommodationRouter.HandleFunc("", accommodationsHandler.DeleteAccommodation)
 	deleteAccommodationRouter.Use(accommodationsHandler.AuthorizeRoles("HOST"))
@@ -171,24 +220,67 @@ func main() {
 	deleteUserAccommodationsRouter.HandleFunc("", accommodationsHandler.DeleteUserAccommodations)
 	deleteUserAccommodationsRouter.Use(accommodationsHandler.AuthorizeRoles("HOST"))
 
+	exportAccommodationsRouter := router.Methods(http.MethodGet).Path("/accommodations/export").Subrouter()
+	exportAccommodationsRouter.HandleFunc("", accommodationsHandler.ExportAccommodations)
+	exportAccommodationsRouter.Use(accommodationsHandler.AuthorizeRoles("HOST"))
+
+	importAccommodationsRouter := router.Methods(http.MethodPost).Path("/accommodations/import").Subrouter()
+	importAccommodationsRouter.HandleFunc("", accommodationsHandler.ImportAccommodations)
+	importAccommodationsRouter.Use(accommodationsHandler.AuthorizeRoles("HOST"))
+
 	// Search part
 
 	searchAccommodationRouter := router.Methods(http.MethodGet).Path("/search").Subrouter()
 	searchAccommodationRouter.HandleFunc("", accommodationsHandler.SearchAccommodations)
 
+	suggestLocationsRouter := router.Methods(http.MethodGet).Path("/locations/suggest").Subrouter()
+	suggestLocationsRouter.HandleFunc("", accommodationsHandler.SuggestLocations)
+
+	amenityCatalogRouter := router.Methods(http.MethodGet).Path("/amenities").Subrouter()
+	amenityCatalogRouter.HandleFunc("", accommodationsHandler.GetAmenityCatalog)
+
+	getWishlistsRouter := router.Methods(http.MethodGet).Path("/wishlists").Subrouter()
+	getWishlistsRouter.HandleFunc("", accommodationsHandler.GetWishlists)
+	getWishlistsRouter.Use(accommodationsHandler.AuthorizeRoles("GUEST"))
+
+	createWishlistRouter := router.Methods(http.MethodPost).Path("/wishlists").Subrouter()
+	createWishlistRouter.HandleFunc("", accommodationsHandler.CreateWishlist)
+	createWishlistRouter.Use(accommodationsHandler.AuthorizeRoles("GUEST"))
+
+	getWishlistRouter := router.Methods(http.MethodGet).Path("/wishlists/{wishlistId}").Subrouter()
+	getWishlistRouter.HandleFunc("", accommodationsHandler.GetWishlist)
+	getWishlistRouter.Use(accommodationsHandler.AuthorizeRoles("GUEST"))
+
+	renameWishlistRouter := router.Methods(http.MethodPut).Path("/wishlists/{wishlistId}").Subrouter()
+	renameWishlistRouter.HandleFunc("", accommodationsHandler.RenameWishlist)
+	renameWishlistRouter.Use(accommodationsHandler.AuthorizeRoles("GUEST"))
+
+	deleteWishlistRouter := router.Methods(http.MethodDelete).Path("/wishlists/{wishlistId}").Subrouter()
+	deleteWishlistRouter.HandleFunc("", accommodationsHandler.DeleteWishlist)
+	deleteWishlistRouter.Use(accommodationsHandler.AuthorizeRoles("GUEST"))
+
+	addToWishlistRouter := router.Methods(http.MethodPut).Path("/wishlists/{wishlistId}/accommodations/{id}").Subrouter()
+	addToWishlistRouter.HandleFunc("", accommodationsHandler.AddToWishlist)
+	addToWishlistRouter.Use(accommodationsHandler.AuthorizeRoles("GUEST"))
+
+	removeFromWishlistRouter := router.Methods(http.MethodDelete).Path("/wishlists/{wishlistId}/accommodations/{id}").Subrouter()
+	removeFromWishlistRouter.HandleFunc("", accommodationsHandler.RemoveFromWishlist)
+	removeFromWishlistRouter.Use(accommodationsHandler.AuthorizeRoles("GUEST"))
+
 	// CORS middleware
 	cors := gorillaHandlers.CORS(
 		gorillaHandlers.AllowedOrigins([]string{"*"}),
-		gorillaHandlers.AllowedMethods([]string{"GET", "HEAD", "POST", "PUT", "DELETE", "OPTIONS"}),
+		gorillaHandlers.AllowedMethods([]string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}),
 		gorillaHandlers.AllowedHeaders([]string{"Content-Type"}),
 	)
 
+	// Read and write timeouts leave time for receiving and processing image uploads
 	server := http.Server{
 		Addr:         ":" + port,
 		Handler:      cors(router),
 		IdleTimeout:  120 * time.Second,
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 5 * time.Second,
+		ReadTimeout:  30 * time.Second,
+		WriteTimeout: 30 * time.Second,
 	}
 
 	log.Info(fmt.Sprintf("[acco-service]acs#5 Server listening on port %s", port))
@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	BackendHDFS  = "hdfs"
+	BackendLocal = "local"
+
+	defaultLocalDir = "/data/images"
+
+	// Files are written under a temporary name first and renamed once complete
+	tempFilePrefix = ".upload-"
+)
+
+// BlobStorage stores files (accommodation images) by name.
+// Missing files are reported with errors matching os.ErrNotExist.
+type BlobStorage interface {
+	// Writes file, replacing the file with the same name if there is one.
+	// Readers see either the previous or the new content, never a partially written file.
+	Write(name string, data []byte) error
+	Read(name string) ([]byte, error)
+	// Opens file for streaming, seeking allows serving byte ranges
+	Open(name string) (io.ReadSeekCloser, error)
+	Delete(name string) error
+	// Returns info of all stored files whose names start with prefix
+	List(prefix string) ([]*BlobInfo, error)
+	Stat(name string) (*BlobInfo, error)
+	Close()
+}
+
+var (
+	_ BlobStorage = (*FileStorage)(nil)
+	_ BlobStorage = (*LocalStorage)(nil)
+)
+
+type BlobInfo struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+}
+
+func newBlobInfo(info os.FileInfo) *BlobInfo {
+	return &BlobInfo{
+		Name:    info.Name(),
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}
+}
+
+// Constructs storage selected by IMAGE_STORAGE env variable, "hdfs" (default) or "local".
+// Local storage keeps files in IMAGE_STORAGE_DIR.
+func NewBlobStorage() (BlobStorage, error) {
+	backend := os.Getenv("IMAGE_STORAGE")
+	if backend == "" {
+		backend = BackendHDFS
+	}
+
+	switch backend {
+	case BackendHDFS:
+		fs, err := New()
+		if err != nil {
+			return nil, err
+		}
+		_ = fs.CreateDirectories()
+		return fs, nil
+	case BackendLocal:
+		dir := os.Getenv("IMAGE_STORAGE_DIR")
+		if dir == "" {
+			dir = defaultLocalDir
+		}
+		log.Info(fmt.Sprintf("[acco-storage]acst#1 Using local image storage in '%s'", dir))
+		return NewLocalStorage(dir)
+	}
+
+	return nil, fmt.Errorf("unknown image storage '%s', expected '%s' or '%s'", backend, BackendHDFS, BackendLocal)
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/colinmarc/hdfs/v2"
 	log "github.com/sirupsen/logrus"
@@ -20,7 +22,7 @@ func New() (*FileStorage, error) {
 
 	client, err := hdfs.New(hdfsUri)
 	if err != nil {
-		log.Panic(fmt.Sprintf("[acco-hdfs]acfs#1 Failed to create client: %v", err))
+		log.Error(fmt.Sprintf("[acco-hdfs]acfs#1 Failed to create client: %v", err))
 		return nil, err
 	}
 
@@ -227,3 +229,62 @@ func (fs *FileStorage) DeleteFile(fileName string, isCopied bool) error {
 
 	return nil
 }
+
+// BlobStorage implementation, files are kept in the write directory
+
+// HDFS fails to create an existing file, so content is written to a temporary file and renamed over it
+func (fs *FileStorage) Write(name string, data []byte) error {
+	tmp := fmt.Sprintf("%s%d-%s", tempFilePrefix, time.Now().UnixNano(), name)
+	if err := fs.WriteFileBytes(data, tmp); err != nil {
+		_ = fs.client.Remove(hdfsWriteDir + tmp)
+		return err
+	}
+
+	if err := fs.client.Rename(hdfsWriteDir+tmp, hdfsWriteDir+name); err != nil {
+		log.Error(fmt.Sprintf("[acco-hdfs]acfs#20 Failed to replace file '%s' on HDFS: %v", name, err))
+		_ = fs.client.Remove(hdfsWriteDir + tmp)
+		return err
+	}
+	return nil
+}
+
+func (fs *FileStorage) Read(name string) ([]byte, error) {
+	return fs.ReadFileBytes(name, false)
+}
+
+func (fs *FileStorage) Open(name string) (io.ReadSeekCloser, error) {
+	file, err := fs.client.Open(hdfsWriteDir + name)
+	if err != nil {
+		log.Warning(fmt.Sprintf("[acco-hdfs]acfs#19 Failed to open file '%s' on HDFS: %v", name, err))
+		return nil, err
+	}
+	return file, nil
+}
+
+func (fs *FileStorage) Delete(name string) error {
+	return fs.DeleteFile(name, false)
+}
+
+func (fs *FileStorage) List(prefix string) ([]*BlobInfo, error) {
+	entries, err := fs.client.ReadDir(hdfsWriteDir)
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-hdfs]acfs#18 Failed to list write directory on HDFS: %v", err))
+		return nil, err
+	}
+
+	var infos []*BlobInfo
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasPrefix(entry.Name(), prefix) {
+			infos = append(infos, newBlobInfo(entry))
+		}
+	}
+	return infos, nil
+}
+
+func (fs *FileStorage) Stat(name string) (*BlobInfo, error) {
+	info, err := fs.client.Stat(hdfsWriteDir + name)
+	if err != nil {
+		return nil, err
+	}
+	return newBlobInfo(info), nil
+}
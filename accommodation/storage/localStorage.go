@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// LocalStorage keeps files in a directory on local filesystem.
+// Meant for development and tests, where HDFS cluster is not available.
+type LocalStorage struct {
+	dir string
+}
+
+var ErrInvalidName = errors.New("invalid file name")
+
+func NewLocalStorage(dir string) (*LocalStorage, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Error(fmt.Sprintf("[acco-storage]acst#2 Failed to create storage directory: %v", err))
+		return nil, err
+	}
+
+	return &LocalStorage{
+		dir: dir,
+	}, nil
+}
+
+func (ls *LocalStorage) Close() {}
+
+func (ls *LocalStorage) Write(name string, data []byte) error {
+	path, err := ls.path(name)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(ls.dir, tempFilePrefix+"*")
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-storage]acst#3 Failed to create temporary file: %v", err))
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		log.Error(fmt.Sprintf("[acco-storage]acst#4 Failed to write file '%s': %v", name, err))
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		log.Error(fmt.Sprintf("[acco-storage]acst#5 Failed to close file '%s': %v", name, err))
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func (ls *LocalStorage) Read(name string) ([]byte, error) {
+	path, err := ls.path(name)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Warning(fmt.Sprintf("[acco-storage]acst#6 Failed to read file '%s': %v", name, err))
+		return nil, err
+	}
+	return data, nil
+}
+
+func (ls *LocalStorage) Open(name string) (io.ReadSeekCloser, error) {
+	path, err := ls.path(name)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		log.Warning(fmt.Sprintf("[acco-storage]acst#9 Failed to open file '%s': %v", name, err))
+		return nil, err
+	}
+	return file, nil
+}
+
+func (ls *LocalStorage) Delete(name string) error {
+	path, err := ls.path(name)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil {
+		log.Error(fmt.Sprintf("[acco-storage]acst#7 Failed to delete file '%s': %v", name, err))
+		return err
+	}
+	return nil
+}
+
+func (ls *LocalStorage) List(prefix string) ([]*BlobInfo, error) {
+	entries, err := os.ReadDir(ls.dir)
+	if err != nil {
+		log.Error(fmt.Sprintf("[acco-storage]acst#8 Failed to list storage directory: %v", err))
+		return nil, err
+	}
+
+	var infos []*BlobInfo
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") || !strings.HasPrefix(entry.Name(), prefix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		infos = append(infos, newBlobInfo(info))
+	}
+	return infos, nil
+}
+
+func (ls *LocalStorage) Stat(name string) (*BlobInfo, error) {
+	path, err := ls.path(name)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return newBlobInfo(info), nil
+}
+
+// Names are flat, anything that could point outside of storage directory is rejected
+func (ls *LocalStorage) path(name string) (string, error) {
+	if name == "" || strings.HasPrefix(name, ".") || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("%w: '%s'", ErrInvalidName, name)
+	}
+	return filepath.Join(ls.dir, name), nil
+}
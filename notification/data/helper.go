@@ -29,6 +29,18 @@ func SendNotificationEmail(providedEmail, intention string) (bool, error) {
 	} else if intention == "reservation-deleted" {
 		subject = "StayInn Notification - Reservation canceled"
 		body = "An user has deleted the reservation for your accommodation. Login to StayInn to see the details."
+	} else if intention == "reservation-requested" {
+		subject = "StayInn Notification - New Reservation Request"
+		body = "A guest requested to book your accommodation. Login to StayInn to accept or decline the request before it expires!"
+	} else if intention == "reservation-request-accepted" {
+		subject = "StayInn Notification - Reservation Request Accepted"
+		body = "The host accepted your reservation request. Login to StayInn to see the details."
+	} else if intention == "reservation-request-declined" {
+		subject = "StayInn Notification - Reservation Request Declined"
+		body = "The host declined your reservation request. Login to StayInn to look for other accommodations."
+	} else if intention == "reservation-request-expired" {
+		subject = "StayInn Notification - Reservation Request Expired"
+		body = "The host didn't answer your reservation request in time, so it has expired. Login to StayInn to look for other accommodations."
 	} else if intention == "rating-host" {
 		subject = "StayInn Notification - New Host Rating"
 		body = "An user has rated you. Login to StayInn to see the details."
@@ -8,13 +8,39 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Intents of reservation notifications, select the email sent with them
+const (
+	IntentReservationNew       = "reservation-new"
+	IntentReservationDeleted   = "reservation-deleted"
+	IntentReservationRequested = "reservation-requested"
+	IntentRequestAccepted      = "reservation-request-accepted"
+	IntentRequestDeclined      = "reservation-request-declined"
+	IntentRequestExpired       = "reservation-request-expired"
+)
+
 type Notification struct {
 	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	HostID       primitive.ObjectID `bson:"hostID" json:"hostID"`
 	HostUsername string             `bson:"hostUsername" json:"hostUsername"`
 	HostEmail    string             `bson:"hostEmail" json:"hostEmail"`
 	Text         string             `bson:"text" json:"text"`
+	Intent       string             `bson:"intent,omitempty" json:"intent,omitempty"`
 	Time         time.Time          `bson:"time" json:"time"`
+
+	// User the notification is for, the host in notifications from producers which predate these fields
+	RecipientID       primitive.ObjectID `bson:"recipientID" json:"recipientID"`
+	RecipientUsername string             `bson:"recipientUsername" json:"recipientUsername"`
+	RecipientEmail    string             `bson:"recipientEmail" json:"recipientEmail"`
+}
+
+// Makes the host recipient of notification created without one, like rating notifications
+func (n *Notification) DefaultRecipient() {
+	if n.RecipientUsername != "" {
+		return
+	}
+	n.RecipientID = n.HostID
+	n.RecipientUsername = n.HostUsername
+	n.RecipientEmail = n.HostEmail
 }
 
 func (n *Notification) ToJSON(w io.Writer) error {
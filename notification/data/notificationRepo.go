@@ -74,6 +74,7 @@ func (nr *NotificationsRepo) Ping() {
 func (nr *NotificationsRepo) CreateNotification(ctx context.Context, notification *Notification) error {
 	collection := nr.getNotificationsCollection()
 
+	notification.DefaultRecipient()
 	_, err := collection.InsertOne(ctx, notification)
 	if err != nil {
 		log.Error(fmt.Sprintf("[noti-repo]nr#5 Failed to create notification: %v", err))
@@ -86,7 +87,12 @@ func (nr *NotificationsRepo) CreateNotification(ctx context.Context, notificatio
 func (nr *NotificationsRepo) GetAllNotifications(ctx context.Context, username string) ([]Notification, error) {
 	notificationsCollection := nr.getNotificationsCollection()
 
-	cursor, err := notificationsCollection.Find(ctx, bson.M{"hostUsername": username})
+	// Notifications stored before recipients were introduced are all for the host
+	filter := bson.M{"$or": bson.A{
+		bson.M{"recipientUsername": username},
+		bson.M{"hostUsername": username, "recipientUsername": bson.M{"$exists": false}},
+	}}
+	cursor, err := notificationsCollection.Find(ctx, filter)
 	if err != nil {
 		log.Error(fmt.Sprintf("[noti-repo]nr#6 Failed to find all notification: %v", err))
 		return nil, err
@@ -909,6 +909,33 @@ func (nh *NotificationsHandler) DeleteRatingAccommodationHandler(w http.Response
 	w.Write([]byte("Document deleted successfully"))
 }
 
+var reservationIntents = map[string]bool{
+	data.IntentReservationNew:       true,
+	data.IntentReservationDeleted:   true,
+	data.IntentReservationRequested: true,
+	data.IntentRequestAccepted:      true,
+	data.IntentRequestDeclined:      true,
+	data.IntentRequestExpired:       true,
+}
+
+// Intent of notification sent without one, guessed from its text the way it was before intents
+func intentFromText(text string) string {
+	switch {
+	case strings.Contains(text, "requested"):
+		return data.IntentReservationRequested
+	case strings.Contains(text, "accepted"):
+		return data.IntentRequestAccepted
+	case strings.Contains(text, "declined"):
+		return data.IntentRequestDeclined
+	case strings.Contains(text, "expired"):
+		return data.IntentRequestExpired
+	case strings.Contains(text, "created"):
+		return data.IntentReservationNew
+	default:
+		return data.IntentReservationDeleted
+	}
+}
+
 func (nh *NotificationsHandler) NotifyForReservation(w http.ResponseWriter, r *http.Request) {
 	var notification data.Notification
 	if err := json.NewDecoder(r.Body).Decode(&notification); err != nil {
@@ -919,6 +946,16 @@ func (nh *NotificationsHandler) NotifyForReservation(w http.ResponseWriter, r *h
 
 	log.Info(fmt.Sprintf("[noti-handler]nh#131 Recieved request from '%s' to notify user for reservation accommodation", r.RemoteAddr))
 
+	// Producers which predate intents only send the text
+	if notification.Intent == "" {
+		notification.Intent = intentFromText(notification.Text)
+	}
+	if !reservationIntents[notification.Intent] {
+		log.Error(fmt.Sprintf("[noti-handler]nh#133 Unknown reservation notification intent '%s'", notification.Intent))
+		http.Error(w, "Invalid notification intent", http.StatusBadRequest)
+		return
+	}
+
 	err := nh.repo.CreateNotification(r.Context(), &notification)
 	if err != nil {
 		log.Error(fmt.Sprintf("[noti-handler]nh#96 Failed to create notification: %v", err))
@@ -926,14 +963,7 @@ func (nh *NotificationsHandler) NotifyForReservation(w http.ResponseWriter, r *h
 		return
 	}
 
-	var intent string
-	if strings.Contains(notification.Text, "created") {
-		intent = "reservation-new"
-	} else {
-		intent = "reservation-deleted"
-	}
-
-	success, err := data.SendNotificationEmail(notification.HostEmail, intent)
+	success, err := data.SendNotificationEmail(notification.RecipientEmail, notification.Intent)
 	if !success {
 		log.Error(("[noti-handler]nh#97 Failed to send notification mail"))
 	}
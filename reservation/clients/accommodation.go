@@ -97,3 +97,42 @@ func (ac *AccommodationClient) GetAccommodationByID(ctx context.Context, accID p
 
 	return serviceResponse, nil
 }
+
+// Gets number of units of accommodation whatever its status, hidden accommodations are not found by GetAccommodationByID
+func (ac *AccommodationClient) GetAccommodationUnits(ctx context.Context, accID primitive.ObjectID) (int, error) {
+	var timeout time.Duration
+	deadline, reqHasDeadline := ctx.Deadline()
+	if reqHasDeadline {
+		timeout = time.Until(deadline)
+	}
+
+	url := ac.address + AccommodationPath + accID.Hex() + "/units"
+	cbResp, err := ac.cb.Execute(func() (interface{}, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		return ac.client.Do(req)
+	})
+	if err != nil {
+		return 0, handleHttpReqErr(err, url, http.MethodGet, timeout)
+	}
+
+	resp := cbResp.(*http.Response)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, domain.ErrResp{
+			URL:        resp.Request.URL.String(),
+			Method:     resp.Request.Method,
+			StatusCode: resp.StatusCode,
+		}
+	}
+
+	var units struct {
+		Units int `json:"units"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&units); err != nil {
+		return 0, fmt.Errorf("failed to decode JSON response: %v", err)
+	}
+	return units.Units, nil
+}
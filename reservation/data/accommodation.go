@@ -3,6 +3,7 @@ package data
 import (
 	"encoding/json"
 	"io"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -15,6 +16,37 @@ type Accommodation struct {
 	Amenities []AmenityEnum      `json:"amenities" bson:"amenities"`
 	MinGuests int                `json:"minGuests" bson:"minGuests"`
 	MaxGuests int                `json:"maxGuests" bson:"maxGuests"`
+	// Number of identical units which can be reserved for the same night, zero counts as one
+	Units int `json:"units,omitempty" bson:"units,omitempty"`
+	// "request" when host accepts or declines each reservation, instant booking otherwise
+	BookingMode      string `json:"bookingMode,omitempty" bson:"bookingMode,omitempty"`
+	RequestHoldHours int    `json:"requestHoldHours,omitempty" bson:"requestHoldHours,omitempty"`
+	// Keys of amenities in the amenity catalog, including those added after AmenityEnum was frozen
+	AmenityKeys []string `json:"amenityKeys,omitempty" bson:"-"`
+}
+
+const (
+	BookingRequest          = "request"
+	DefaultRequestHoldHours = 24
+)
+
+func (a *Accommodation) UnitCount() int {
+	if a.Units < 1 {
+		return 1
+	}
+	return a.Units
+}
+
+func (a *Accommodation) RequiresApproval() bool {
+	return a.BookingMode == BookingRequest
+}
+
+// How long a reservation request holds the dates while waiting for the host
+func (a *Accommodation) RequestHold() time.Duration {
+	if a.RequestHoldHours < 1 {
+		return DefaultRequestHoldHours * time.Hour
+	}
+	return time.Duration(a.RequestHoldHours) * time.Hour
 }
 
 type AmenityEnum int
@@ -0,0 +1,132 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/gocql/gocql"
+)
+
+// Date holds keep dates for a guest during checkout. Holds are written to date_holds_by_accommodation
+// and take unit slots in reserved_nights_by_accommodation, both with a TTL, so an abandoned hold
+// frees the dates on its own. A reservation confirming the hold keeps the slots and removes the hold.
+
+const DateHoldDuration = 10 * time.Minute
+
+var ErrHoldExpired = errors.New("date hold has expired")
+
+func (rr *ReservationRepo) createDateHoldsTable() error {
+	err := rr.session.Query(
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s
+        (id_accommodation TEXT, id UUID, id_available_period UUID, id_user TEXT,
+        start_date TIMESTAMP, end_date TIMESTAMP, guest_number INT, expires_at TIMESTAMP,
+        PRIMARY KEY ((id_accommodation), id))`,
+			"date_holds_by_accommodation")).Exec()
+	if err != nil {
+		log.Error(fmt.Sprintf("[rese-repo]rr#88 Error while creating database tables: %v", err))
+		return err
+	}
+
+	return nil
+}
+
+// Holds nights of the stay for the guest making reservation, for DateHoldDuration.
+// Returns ErrNoUnitsLeft if some night has no free unit.
+func (rr *ReservationRepo) HoldDates(reservation *ReservationByAvailablePeriod, units int) (*DateHold, error) {
+	if _, err := rr.findPeriodOfStay(reservation); err != nil {
+		return nil, err
+	}
+
+	reservation.ID, _ = gocql.RandomUUID()
+	if err := rr.claimNights(reservation, units, DateHoldDuration); err != nil {
+		return nil, err
+	}
+
+	hold := &DateHold{
+		ID:                reservation.ID,
+		IDAccommodation:   reservation.IDAccommodation,
+		IDAvailablePeriod: reservation.IDAvailablePeriod,
+		IDUser:            reservation.IDUser,
+		StartDate:         reservation.StartDate,
+		EndDate:           reservation.EndDate,
+		GuestNumber:       reservation.GuestNumber,
+		ExpiresAt:         time.Now().Add(DateHoldDuration),
+	}
+	err := rr.session.Query(
+		`INSERT INTO date_holds_by_accommodation
+		(id_accommodation, id, id_available_period, id_user, start_date, end_date, guest_number, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?) USING TTL ?`,
+		hold.IDAccommodation.Hex(), hold.ID, hold.IDAvailablePeriod, hold.IDUser.Hex(),
+		hold.StartDate, hold.EndDate, hold.GuestNumber, hold.ExpiresAt, int(DateHoldDuration.Seconds())).Exec()
+	if err != nil {
+		log.Error(fmt.Sprintf("[rese-repo]rr#89 Error while inserting in database: %v", err))
+		if releaseErr := rr.releaseReservation(reservation); releaseErr != nil {
+			log.Error(fmt.Sprintf("[rese-repo]rr#90 Error while freeing nights of failed hold '%s': %v", hold.ID, releaseErr))
+		}
+		return nil, err
+	}
+
+	return hold, nil
+}
+
+// Returns ErrHoldExpired if hold doesn't exist anymore
+func (rr *ReservationRepo) FindDateHold(accommodationID string, id gocql.UUID) (*DateHold, error) {
+	holds, err := rr.scanDateHolds(rr.session.Query(
+		`SELECT id_accommodation, id, id_available_period, id_user, start_date, end_date, guest_number, expires_at
+		FROM date_holds_by_accommodation WHERE id_accommodation = ? AND id = ?`,
+		accommodationID, id))
+	if err != nil {
+		log.Error(fmt.Sprintf("[rese-repo]rr#91 Error while finding date hold '%s': %v", id, err))
+		return nil, err
+	}
+	if len(holds) == 0 {
+		return nil, ErrHoldExpired
+	}
+
+	return holds[0], nil
+}
+
+func (rr *ReservationRepo) findDateHoldsByAccommodation(accommodationID string) ([]*DateHold, error) {
+	return rr.scanDateHolds(rr.session.Query(
+		`SELECT id_accommodation, id, id_available_period, id_user, start_date, end_date, guest_number, expires_at
+		FROM date_holds_by_accommodation WHERE id_accommodation = ?`,
+		accommodationID))
+}
+
+func (rr *ReservationRepo) scanDateHolds(query *gocql.Query) ([]*DateHold, error) {
+	scanner := query.Iter().Scanner()
+
+	var holds []*DateHold
+	for scanner.Next() {
+		var (
+			hold               DateHold
+			idAccommodationStr string
+			idUserStr          string
+		)
+		err := scanner.Scan(&idAccommodationStr, &hold.ID, &hold.IDAvailablePeriod, &idUserStr,
+			&hold.StartDate, &hold.EndDate, &hold.GuestNumber, &hold.ExpiresAt)
+		if err != nil {
+			log.Error(fmt.Sprintf("[rese-repo]rr#92 Error while scanning from database: %v", err))
+			return nil, err
+		}
+		hold.IDAccommodation, _ = primitive.ObjectIDFromHex(idAccommodationStr)
+		hold.IDUser, _ = primitive.ObjectIDFromHex(idUserStr)
+		holds = append(holds, &hold)
+	}
+
+	if err := scanner.Err(); err != nil {
+		log.Error(fmt.Sprintf("[rese-repo]rr#93 Error while scanning from database: %v", err))
+		return nil, err
+	}
+
+	return holds, nil
+}
+
+func removeDateHold(batch *gocql.Batch, reservation *ReservationByAvailablePeriod) {
+	batch.Query(`DELETE FROM date_holds_by_accommodation WHERE id_accommodation = ? AND id = ?`,
+		reservation.IDAccommodation.Hex(), *reservation.HoldID)
+}
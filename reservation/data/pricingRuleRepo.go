@@ -0,0 +1,187 @@
+package data
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"reservation/pricing"
+
+	"github.com/gocql/gocql"
+)
+
+// Pricing rules of an accommodation are kept in pricing_rules_by_accommodation, one row per rule.
+// Reservations keep the itemized price they were charged in price_breakdown, as JSON.
+
+func (rr *ReservationRepo) createPricingTables() error {
+	err := rr.session.Query(
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s
+        (id_accommodation TEXT, id UUID, kind TEXT, name TEXT, price DOUBLE, percent DOUBLE,
+        weekdays SET<INT>, start_date TIMESTAMP, end_date TIMESTAMP, min_nights INT, days_before INT,
+        PRIMARY KEY ((id_accommodation), id))`,
+			"pricing_rules_by_accommodation")).Exec()
+	if err != nil {
+		log.Error(fmt.Sprintf("[rese-repo]rr#95 Error while creating database tables: %v", err))
+		return err
+	}
+
+	for _, table := range reservationTables {
+		if err := rr.addColumnIfMissing(table, "price_breakdown", "TEXT"); err != nil {
+			log.Error(fmt.Sprintf("[rese-repo]rr#96 Error while adding column 'price_breakdown' to '%s': %v", table, err))
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (rr *ReservationRepo) FindPricingRules(accommodationID string) (pricing.Rules, error) {
+	scanner := rr.session.Query(`
+		SELECT kind, name, price, percent, weekdays, start_date, end_date, min_nights, days_before
+		FROM pricing_rules_by_accommodation WHERE id_accommodation = ?`,
+		accommodationID).Iter().Scanner()
+
+	rules := pricing.Rules{}
+	for scanner.Next() {
+		var (
+			rule     pricing.Rule
+			kind     string
+			weekdays []int
+		)
+		err := scanner.Scan(&kind, &rule.Name, &rule.Price, &rule.Percent, &weekdays,
+			&rule.StartDate, &rule.EndDate, &rule.MinNights, &rule.DaysBefore)
+		if err != nil {
+			log.Error(fmt.Sprintf("[rese-repo]rr#97 Error while scanning from database: %v", err))
+			return nil, err
+		}
+		rule.Kind = pricing.RuleKind(kind)
+		for _, day := range weekdays {
+			rule.Weekdays = append(rule.Weekdays, time.Weekday(day))
+		}
+		rules = append(rules, rule)
+	}
+
+	if err := scanner.Err(); err != nil {
+		log.Error(fmt.Sprintf("[rese-repo]rr#98 Error while scanning from database: %v", err))
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+// Replaces all pricing rules of the accommodation with rules
+func (rr *ReservationRepo) ReplacePricingRules(accommodationID string, rules pricing.Rules) error {
+	if err := rules.Validate(); err != nil {
+		return err
+	}
+
+	// Statements of a batch share a timestamp and deletes win ties, so old rules are deleted just before
+	written := time.Now().UnixMicro()
+	batch := rr.session.NewBatch(gocql.LoggedBatch)
+	batch.Query(`DELETE FROM pricing_rules_by_accommodation USING TIMESTAMP ? WHERE id_accommodation = ?`,
+		written-1, accommodationID)
+	for _, rule := range rules {
+		id, _ := gocql.RandomUUID()
+		weekdays := make([]int, 0, len(rule.Weekdays))
+		for _, day := range rule.Weekdays {
+			weekdays = append(weekdays, int(day))
+		}
+		batch.Query(`INSERT INTO pricing_rules_by_accommodation
+			(id_accommodation, id, kind, name, price, percent, weekdays, start_date, end_date, min_nights, days_before)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) USING TIMESTAMP ?`,
+			accommodationID, id, string(rule.Kind), rule.Name, rule.Price, rule.Percent, weekdays,
+			rule.StartDate, rule.EndDate, rule.MinNights, rule.DaysBefore, written)
+	}
+
+	if err := rr.session.ExecuteBatch(batch); err != nil {
+		log.Error(fmt.Sprintf("[rese-repo]rr#99 Error while saving pricing rules of accommodation '%s': %v", accommodationID, err))
+		return err
+	}
+
+	return nil
+}
+
+var (
+	ErrNoAvailablePeriod = errors.New("stay doesn't fit any available period of the accommodation")
+	ErrQuoteMismatch     = errors.New("reservation doesn't match the quoted stay")
+)
+
+// Prices stay without reserving anything. Available period of the stay is looked up
+// among periods of the accommodation if reservation doesn't name one.
+func (rr *ReservationRepo) QuoteStay(reservation *ReservationByAvailablePeriod) (*pricing.Breakdown, error) {
+	var zeroID gocql.UUID
+	if reservation.IDAvailablePeriod == zeroID {
+		periods, err := rr.FindAvailablePeriodsByAccommodationId(reservation.IDAccommodation.Hex())
+		if err != nil {
+			log.Error(fmt.Sprintf("[rese-repo]rr#102 Error while finding available periods of accommodation: %v", err))
+			return nil, err
+		}
+		for _, period := range periods {
+			if !reservation.StartDate.Before(period.StartDate) && !reservation.EndDate.After(period.EndDate) {
+				reservation.IDAvailablePeriod = period.ID
+				break
+			}
+		}
+		if reservation.IDAvailablePeriod == zeroID {
+			return nil, ErrNoAvailablePeriod
+		}
+	}
+
+	availablePeriod, err := rr.findPeriodOfStay(reservation)
+	if err != nil {
+		return nil, err
+	}
+
+	return rr.priceStay(reservation, availablePeriod)
+}
+
+// Checks that reservation is for the stay the quote was made for
+func (r *ReservationByAvailablePeriod) matchesQuote(quote *pricing.Quote) bool {
+	return quote.IDAccommodation == r.IDAccommodation.Hex() &&
+		quote.IDAvailablePeriod == r.IDAvailablePeriod.String() &&
+		quote.StartDate.Equal(r.StartDate) &&
+		quote.EndDate.Equal(r.EndDate) &&
+		quote.Guests == int(r.GuestNumber)
+}
+
+// Prices stay in available period with pricing rules of the accommodation
+func (rr *ReservationRepo) priceStay(reservation *ReservationByAvailablePeriod, availablePeriod *AvailablePeriodByAccommodation) (*pricing.Breakdown, error) {
+	rules, err := rr.FindPricingRules(reservation.IDAccommodation.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	breakdown := pricing.Evaluate(pricing.Stay{
+		StartDate:     reservation.StartDate,
+		EndDate:       reservation.EndDate,
+		Guests:        int(reservation.GuestNumber),
+		BasePrice:     availablePeriod.Price,
+		PricePerGuest: availablePeriod.PricePerGuest,
+		BookedAt:      time.Now(),
+	}, rules)
+
+	return &breakdown, nil
+}
+
+func marshalBreakdown(breakdown *pricing.Breakdown) string {
+	if breakdown == nil {
+		return ""
+	}
+	encoded, _ := json.Marshal(breakdown)
+	return string(encoded)
+}
+
+// Reservations made before pricing rules were introduced have no breakdown
+func unmarshalBreakdown(encoded string) *pricing.Breakdown {
+	if encoded == "" {
+		return nil
+	}
+	var breakdown pricing.Breakdown
+	if err := json.Unmarshal([]byte(encoded), &breakdown); err != nil {
+		return nil
+	}
+	return &breakdown
+}
@@ -5,6 +5,8 @@ import (
 	"io"
 	"time"
 
+	"reservation/pricing"
+
 	"github.com/gocql/gocql"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -28,16 +30,90 @@ type ReservationByAvailablePeriod struct {
 	EndDate           time.Time
 	GuestNumber       int16
 	Price             float64
+	Status            ReservationStatus
+	StatusChangedAt   time.Time
+	// ID of user who made the last status change, or SystemActor
+	StatusChangedBy string
+	// Time pending reservation request expires at, unless host answers it
+	ExpiresAt time.Time
+	// Itemized price the guest was charged, missing on reservations made before pricing rules
+	PriceBreakdown *pricing.Breakdown `json:",omitempty"`
+	// Date hold the reservation confirms, not stored with the reservation
+	HoldID *gocql.UUID `json:",omitempty"`
+	// Signed quote the reservation is charged by, not stored with the reservation
+	QuoteToken string `json:",omitempty"`
+}
+
+// Dates held for a guest during checkout, freed automatically at ExpiresAt
+// unless a reservation confirms the hold before
+type DateHold struct {
+	ID                gocql.UUID
+	IDAccommodation   primitive.ObjectID // Partition key
+	IDAvailablePeriod gocql.UUID
+	IDUser            primitive.ObjectID
+	StartDate         time.Time
+	EndDate           time.Time
+	GuestNumber       int16
+	ExpiresAt         time.Time
+}
+
+// Pending reservation request, with guest contact and accommodation name kept
+// for notifying the guest of the outcome when no user is making the request
+type ReservationRequest struct {
+	ID                gocql.UUID
+	IDAvailablePeriod gocql.UUID
+	ExpiresAt         time.Time
+	GuestUsername     string
+	GuestEmail        string
+	AccommodationName string
 }
 
 type Dates struct {
 	AccommodationIds []primitive.ObjectID `json:"accommodationIds"`
 	StartDate        time.Time            `json:"startDate"`
 	EndDate          time.Time            `json:"endDate"`
+	// Units of multi-unit accommodations by hex ID, accommodations left out have one unit
+	Units map[string]int `json:"units,omitempty"`
 }
 
 type ListOfObjectIds struct {
 	ObjectIds []primitive.ObjectID `json:"objectIds"`
+	// Units still free for the whole stay, by hex ID of accommodation
+	RemainingUnits map[string]int `json:"remainingUnits,omitempty"`
+}
+
+func (d *Dates) UnitCount(id primitive.ObjectID) int {
+	if units, ok := d.Units[id.Hex()]; ok && units > 1 {
+		return units
+	}
+	return 1
+}
+
+// Returns the highest number of reservations staying on the same night between start and end.
+// Reservation occupies nights from its start date up to, but not including, its end date.
+func (r Reservations) OccupiedUnits(start, end time.Time) int {
+	occupied := 0
+	for night := start; night.Before(end); night = night.Add(24 * time.Hour) {
+		count := 0
+		for _, reservation := range r {
+			if !night.Before(reservation.StartDate) && night.Before(reservation.EndDate) {
+				count++
+			}
+		}
+		if count > occupied {
+			occupied = count
+		}
+	}
+	return occupied
+}
+
+// Returns nights of the stay, each truncated to the day it starts on
+func (r *ReservationByAvailablePeriod) Nights() []time.Time {
+	var nights []time.Time
+	for night := r.StartDate; night.Before(r.EndDate); night = night.Add(24 * time.Hour) {
+		nights = append(nights, night.UTC().Truncate(24*time.Hour))
+	}
+	return nights
 }
 
 type AvailablePeriodsByAccommodation []*AvailablePeriodByAccommodation
@@ -53,6 +129,19 @@ func (r *ReservationByAvailablePeriod) FromJSON(re io.Reader) error {
 	return d.Decode(r)
 }
 
+func (d *DateHold) ToJSON(w io.Writer) error {
+	e := json.NewEncoder(w)
+	return e.Encode(d)
+}
+
+// Checks that reservation is made by the guest who holds the dates, for the same stay
+func (d *DateHold) Matches(reservation *ReservationByAvailablePeriod) bool {
+	return d.IDUser == reservation.IDUser &&
+		d.IDAvailablePeriod == reservation.IDAvailablePeriod &&
+		d.StartDate.Equal(reservation.StartDate) &&
+		d.EndDate.Equal(reservation.EndDate)
+}
+
 func (r *Dates) ToJSON(w io.Writer) error {
 	e := json.NewEncoder(w)
 	return e.Encode(r)
@@ -0,0 +1,107 @@
+package data
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/gocql/gocql"
+)
+
+// Reservations are stored in reservations_by_available_period and copied to reservations_by_user and
+// reservations_by_accommodation, so they can be found by guest and by accommodation without ALLOW FILTERING.
+// All three tables are written together in logged batches.
+
+var reservationTables = []string{
+	"reservations_by_available_period",
+	"reservations_by_user",
+	"reservations_by_accommodation",
+}
+
+func (rr *ReservationRepo) createReservationLookupTables() error {
+	err := rr.session.Query(
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s
+        (id UUID, id_accommodation TEXT, id_available_period UUID, id_user TEXT,
+        start_date TIMESTAMP, end_date TIMESTAMP, guest_number INT, price DOUBLE,
+        PRIMARY KEY ((id_user), end_date, id))
+        WITH CLUSTERING ORDER BY (end_date DESC, id ASC)`,
+			"reservations_by_user")).Exec()
+	if err != nil {
+		log.Error(fmt.Sprintf("[rese-repo]rr#71 Error while creating database tables: %v", err))
+		return err
+	}
+
+	err = rr.session.Query(
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s
+        (id UUID, id_accommodation TEXT, id_available_period UUID, id_user TEXT,
+        start_date TIMESTAMP, end_date TIMESTAMP, guest_number INT, price DOUBLE,
+        PRIMARY KEY ((id_accommodation), start_date, id))
+        WITH CLUSTERING ORDER BY (start_date ASC, id ASC)`,
+			"reservations_by_accommodation")).Exec()
+	if err != nil {
+		log.Error(fmt.Sprintf("[rese-repo]rr#72 Error while creating database tables: %v", err))
+		return err
+	}
+
+	return nil
+}
+
+// Writes reservation to all reservation tables and records its initial status,
+// along with request waiting for the host if reservation is a reservation request.
+// Date hold the reservation confirms is removed.
+func (rr *ReservationRepo) insertReservation(reservation *ReservationByAvailablePeriod, request *ReservationRequest) error {
+	batch := rr.session.NewBatch(gocql.LoggedBatch)
+	for _, table := range reservationTables {
+		batch.Query(fmt.Sprintf(`INSERT INTO %s (%s) VALUES (%s)`, table, reservationColumns, reservationPlaceholders),
+			reservationValues(reservation)...)
+	}
+	addStatusHistory(batch, reservation)
+	if request != nil {
+		addReservationRequest(batch, request)
+	}
+	if reservation.HoldID != nil {
+		removeDateHold(batch, reservation)
+	}
+
+	return rr.session.ExecuteBatch(batch)
+}
+
+func (rr *ReservationRepo) FindAllReservationsByAccommodation(accommodationID string) (Reservations, error) {
+	return rr.scanReservations(rr.session.Query(
+		fmt.Sprintf(`SELECT %s FROM reservations_by_accommodation WHERE id_accommodation = ?`, reservationColumns),
+		accommodationID))
+}
+
+// Copies all reservations from reservations_by_available_period to the lookup tables.
+// Rows are upserted, so running it again is harmless. Returns number of copied reservations.
+func (rr *ReservationRepo) BackfillReservationLookupTables() (int, error) {
+	scanner := rr.session.Query(
+		fmt.Sprintf(`SELECT %s FROM reservations_by_available_period`, reservationColumns)).Iter().Scanner()
+
+	copied := 0
+	for scanner.Next() {
+		reservation, err := scanReservation(scanner)
+		if err != nil {
+			log.Error(fmt.Sprintf("[rese-repo]rr#75 Error while scanning from database: %v", err))
+			return copied, err
+		}
+
+		batch := rr.session.NewBatch(gocql.LoggedBatch)
+		for _, table := range reservationTables[1:] {
+			batch.Query(fmt.Sprintf(`INSERT INTO %s (%s) VALUES (%s)`, table, reservationColumns, reservationPlaceholders),
+				reservationValues(reservation)...)
+		}
+		if err := rr.session.ExecuteBatch(batch); err != nil {
+			log.Error(fmt.Sprintf("[rese-repo]rr#76 Error while copying reservation '%s': %v", reservation.ID, err))
+			return copied, err
+		}
+		copied++
+	}
+
+	if err := scanner.Err(); err != nil {
+		log.Error(fmt.Sprintf("[rese-repo]rr#77 Error while scanning from database: %v", err))
+		return copied, err
+	}
+
+	return copied, nil
+}
@@ -8,9 +8,35 @@ import (
 	log "github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
+	"reservation/pricing"
+
 	"github.com/gocql/gocql"
 )
 
+var ErrNoUnitsLeft = errors.New("no units of the accommodation are left for the requested dates")
+
+const reservationColumns = `id, id_accommodation, id_available_period, id_user, start_date, end_date, guest_number, price,
+	status, status_changed_at, status_changed_by, expires_at, price_breakdown`
+
+const reservationPlaceholders = `?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?`
+
+// Tables read by time, like unsynced_status_changes, are partitioned by day,
+// so they are read one partition at a time instead of being scanned
+const dayBucket = 24 * time.Hour
+
+func dayBucketOf(t time.Time) time.Time {
+	return t.UTC().Truncate(dayBucket)
+}
+
+// Returns day partitions from the one holding from up to the one holding to
+func dayBuckets(from, to time.Time) []time.Time {
+	var buckets []time.Time
+	for bucket := dayBucketOf(from); !bucket.After(to); bucket = bucket.Add(dayBucket) {
+		buckets = append(buckets, bucket)
+	}
+	return buckets
+}
+
 type ReservationRepo struct {
 	session *gocql.Session
 }
@@ -52,7 +78,27 @@ func (rr *ReservationRepo) CreateTables() error {
 		return err
 	}
 
-	return nil
+	if err := rr.createReservationLookupTables(); err != nil {
+		return err
+	}
+
+	if err := rr.createReservationStatusColumns(); err != nil {
+		return err
+	}
+
+	if err := rr.createReservationRequestsTable(); err != nil {
+		return err
+	}
+
+	if err := rr.createDateHoldsTable(); err != nil {
+		return err
+	}
+
+	if err := rr.createPricingTables(); err != nil {
+		return err
+	}
+
+	return rr.createReservedNightsTable()
 }
 
 func (rr *ReservationRepo) GetAvailablePeriodsByAccommodation(id string) (AvailablePeriodsByAccommodation, error) {
@@ -98,41 +144,11 @@ func (rr *ReservationRepo) GetAvailablePeriodsByAccommodation(id string) (Availa
 }
 
 func (rr *ReservationRepo) GetReservationsByAvailablePeriod(idAvailablePeriod string) (Reservations, error) {
-	scanner := rr.session.Query(`
-		SELECT id, id_accommodation, id_available_period, id_user, start_date, end_date, guest_number, price
-		FROM reservations_by_available_period WHERE id_available_period = ?`,
-		idAvailablePeriod).Iter().Scanner()
-
-	var reservations Reservations
-	for scanner.Next() {
-		var reservation ReservationByAvailablePeriod
-		var idAccommodationStr, idUserStr string
-
-		err := scanner.Scan(&reservation.ID, &idAccommodationStr, &reservation.IDAvailablePeriod, &idUserStr, &reservation.StartDate, &reservation.EndDate, &reservation.GuestNumber, &reservation.Price)
-		if err != nil {
-			log.Fatal(fmt.Sprintf("[rese-repo]rr#7 Error while scanning from database: %v", err))
-			return nil, err
-		}
-
-		// Convert idAccommodationStr and idUserStr strings to primitive.ObjectID
-		idAccommodation, err := primitive.ObjectIDFromHex(idAccommodationStr)
-		if err != nil {
-			log.Error(fmt.Sprintf("[rese-repo]rr#8 Error while parsing id: %v", err))
-			return nil, err
-		}
-		reservation.IDAccommodation = idAccommodation
-
-		idUser, err := primitive.ObjectIDFromHex(idUserStr)
-		if err != nil {
-			log.Error(fmt.Sprintf("[rese-repo]rr#9 Error while parsing id: %v", err))
-			return nil, err
-		}
-		reservation.IDUser = idUser
-
-		reservations = append(reservations, &reservation)
-	}
-	if err := scanner.Err(); err != nil {
-		log.Fatal(fmt.Sprintf("[rese-repo]rr#10 Error while scanning from database: %v", err))
+	reservations, err := rr.scanReservations(rr.session.Query(
+		fmt.Sprintf(`SELECT %s FROM reservations_by_available_period WHERE id_available_period = ?`, reservationColumns),
+		idAvailablePeriod))
+	if err != nil {
+		log.Error(fmt.Sprintf("[rese-repo]rr#7 Error while finding reservations by available period: %v", err))
 		return nil, err
 	}
 
@@ -185,54 +201,109 @@ func (rr *ReservationRepo) InsertAvailablePeriodByAccommodation(availablePeriod
 	return nil
 }
 
-func (rr *ReservationRepo) InsertReservationByAvailablePeriod(reservation *ReservationByAvailablePeriod) error {
-	reservationId, _ := gocql.RandomUUID()
+// Inserts reservation if fewer than units reservations of the accommodation stay on any of its nights.
+// Nights are reserved before the reservation is stored, so concurrent reservations can't both take the last unit.
+// Accommodations booked on request get a pending reservation holding the nights until the host answers,
+// guest contact is kept with the request for notifying the guest of the outcome.
+// A reservation confirming a date hold takes over the nights held for the guest.
+// Reservation made with a quote is charged the quoted price, otherwise it is priced with current pricing rules.
+func (rr *ReservationRepo) InsertReservationByAvailablePeriod(reservation *ReservationByAvailablePeriod, accommodation *Accommodation,
+	guest *User, quote *pricing.Quote) error {
+	units := accommodation.UnitCount()
+	if quote != nil && !reservation.matchesQuote(quote) {
+		return ErrQuoteMismatch
+	}
+	reservation.ID, _ = gocql.RandomUUID()
+	if reservation.HoldID != nil {
+		hold, err := rr.FindDateHold(reservation.IDAccommodation.Hex(), *reservation.HoldID)
+		if err != nil {
+			return err
+		}
+		if !hold.Matches(reservation) {
+			return errors.New("reservation doesn't match the held dates")
+		}
+		// Reservation takes the ID of the hold, so it owns the held nights
+		reservation.ID = hold.ID
+	}
 
-	// Check if the reservation is within the appropriate range of the available period
-	availablePeriod, err := rr.FindAvailablePeriodById(reservation.IDAvailablePeriod.String(), reservation.IDAccommodation.Hex())
+	availablePeriod, err := rr.findPeriodOfStay(reservation)
 	if err != nil {
-		log.Error(fmt.Sprintf("[rese-repo]rr#13 Error while finding available period by id: %v", err))
 		return err
 	}
-	if reservation.StartDate.Before(availablePeriod.StartDate) || reservation.EndDate.After(availablePeriod.EndDate) {
-		log.Error(fmt.Sprintf("[rese-repo]rr#14 Error while comparing two dates: %v", err))
-		return errors.New("reservation is not within the appropriate range of the available period")
-	}
 
-	if reservation.EndDate.Sub(reservation.StartDate) < 24*time.Hour {
-		log.Error(fmt.Sprintf("[rese-repo]rr#15 Error while creating reservation: %v", err))
-		return errors.New("EndDate must be at least one day after StartDate")
+	// Reserve a unit on every night of the stay
+	if err := rr.claimNights(reservation, units, 0); err != nil {
+		if errors.Is(err, ErrNoUnitsLeft) {
+			log.Error(fmt.Sprintf("[rese-repo]rr#17 All %d units are reserved on some of the requested nights", units))
+		} else {
+			log.Error(fmt.Sprintf("[rese-repo]rr#16 Error while reserving nights: %v", err))
+		}
+		return err
 	}
 
-	// Retrieve existing reservations for the available period
-	existingReservations, err := rr.FindAllReservationsByAvailablePeriod(availablePeriod.ID.String())
+	reservation.Status = StatusConfirmed
+	reservation.StatusChangedAt = time.Now()
+	reservation.StatusChangedBy = reservation.IDUser.Hex()
+	var request *ReservationRequest
+	if accommodation.RequiresApproval() {
+		reservation.Status = StatusPending
+		reservation.ExpiresAt = reservation.StatusChangedAt.Add(accommodation.RequestHold())
+		request = &ReservationRequest{
+			ID:                reservation.ID,
+			IDAvailablePeriod: reservation.IDAvailablePeriod,
+			ExpiresAt:         reservation.ExpiresAt,
+			AccommodationName: accommodation.Name,
+		}
+		if guest != nil {
+			request.GuestUsername = guest.Username
+			request.GuestEmail = guest.Email
+		}
+	}
+	var breakdown *pricing.Breakdown
+	if quote != nil {
+		breakdown = &quote.Breakdown
+	} else {
+		breakdown, err = rr.priceStay(reservation, availablePeriod)
+	}
 	if err != nil {
-		log.Fatal(fmt.Sprintf("[rese-repo]rr#16 Error while getting all available periods: %v", err))
+		log.Error(fmt.Sprintf("[rese-repo]rr#100 Error while pricing reservation: %v", err))
+		if releaseErr := rr.releaseReservation(reservation); releaseErr != nil {
+			log.Error(fmt.Sprintf("[rese-repo]rr#101 Error while freeing nights of failed reservation '%s': %v", reservation.ID, releaseErr))
+		}
 		return err
 	}
-
-	// Check for overlapping reservations
-	for _, existingReservation := range existingReservations {
-		if (reservation.StartDate.Before(existingReservation.EndDate) || reservation.StartDate.Equal(existingReservation.EndDate)) &&
-			(reservation.EndDate.After(existingReservation.StartDate) || reservation.EndDate.Equal(existingReservation.StartDate)) {
-			log.Error(fmt.Sprintf("[rese-repo]rr#17 Error while checking for reservation overlap: %v", err))
-			return errors.New("new reservation overlaps with an existing reservation")
+	reservation.Price = breakdown.Total
+	reservation.PriceBreakdown = breakdown
+	if err := rr.insertReservation(reservation, request); err != nil {
+		log.Error(fmt.Sprintf("[rese-repo]rr#18 Error while inserting in database: %v", err))
+		if releaseErr := rr.releaseReservation(reservation); releaseErr != nil {
+			log.Error(fmt.Sprintf("[rese-repo]rr#69 Error while freeing nights of failed reservation '%s': %v", reservation.ID, releaseErr))
 		}
+		return err
 	}
 
-	calculatedPrice := rr.calculatePrice(availablePeriod.Price, availablePeriod.PricePerGuest, reservation.StartDate, reservation.EndDate, int16(reservation.GuestNumber))
-	err = rr.session.Query(
-		`INSERT INTO reservations_by_available_period 
-			(id, id_accommodation, id_available_period, id_user, start_date, end_date, guest_number, price) 
-			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
-		reservationId, reservation.IDAccommodation.Hex(), reservation.IDAvailablePeriod, reservation.IDUser.Hex(),
-		reservation.StartDate, reservation.EndDate, reservation.GuestNumber, calculatedPrice).Exec()
+	return nil
+}
+
+// Returns available period of the stay, if the stay is within it and lasts at least one night
+func (rr *ReservationRepo) findPeriodOfStay(reservation *ReservationByAvailablePeriod) (*AvailablePeriodByAccommodation, error) {
+	// Check if the reservation is within the appropriate range of the available period
+	availablePeriod, err := rr.FindAvailablePeriodById(reservation.IDAvailablePeriod.String(), reservation.IDAccommodation.Hex())
 	if err != nil {
-		log.Fatal(fmt.Sprintf("[rese-repo]rr#18 Error while inserting in database: %v", err))
-		return err
+		log.Error(fmt.Sprintf("[rese-repo]rr#13 Error while finding available period by id: %v", err))
+		return nil, err
+	}
+	if reservation.StartDate.Before(availablePeriod.StartDate) || reservation.EndDate.After(availablePeriod.EndDate) {
+		log.Error(fmt.Sprintf("[rese-repo]rr#14 Error while comparing two dates: %v", err))
+		return nil, errors.New("reservation is not within the appropriate range of the available period")
 	}
 
-	return nil
+	if reservation.EndDate.Sub(reservation.StartDate) < 24*time.Hour {
+		log.Error(fmt.Sprintf("[rese-repo]rr#15 Error while creating reservation: %v", err))
+		return nil, errors.New("EndDate must be at least one day after StartDate")
+	}
+
+	return availablePeriod, nil
 }
 
 // Add so only user who make period can update it, extract username from token and communicate with profile service
@@ -257,7 +328,7 @@ func (rr *ReservationRepo) UpdateAvailablePeriodByAccommodation(availablePeriod
 		return err
 	}
 
-	if len(reservations) != 0 {
+	if len(reservations.HoldingInventory()) != 0 {
 		log.Error(fmt.Sprintf("[rese-repo]rr#22 Error while chaning period with reservations: %v", err))
 		err = errors.New("cannot change period with reservations")
 		return err
@@ -407,36 +478,11 @@ func (rr *ReservationRepo) FindAvailablePeriodById(id, accommodationID string) (
 }
 
 func (rr *ReservationRepo) FindAllReservationsByAvailablePeriod(periodId string) (Reservations, error) {
-	scanner := rr.session.Query(`
-        SELECT id, id_accommodation, id_available_period, id_user, start_date, end_date, guest_number, price
-        FROM reservations_by_available_period
-        WHERE id_available_period = ?`, periodId).Iter().Scanner()
-
-	var reservations Reservations
-	for scanner.Next() {
-		var (
-			idAccommodationStr string
-			idUserStr          string
-			reservation        ReservationByAvailablePeriod
-		)
-
-		err := scanner.Scan(&reservation.ID, &idAccommodationStr, &reservation.IDAvailablePeriod, &idUserStr,
-			&reservation.StartDate, &reservation.EndDate, &reservation.GuestNumber, &reservation.Price)
-
-		if err != nil {
-			log.Fatal(fmt.Sprintf("[rese-repo]rr#34 Error while scanning from database: %v", err))
-			return nil, err
-		}
-
-		// Convert strings to primitive.ObjectID
-		reservation.IDAccommodation, _ = primitive.ObjectIDFromHex(idAccommodationStr)
-		reservation.IDUser, _ = primitive.ObjectIDFromHex(idUserStr)
-
-		reservations = append(reservations, &reservation)
-	}
-
-	if err := scanner.Err(); err != nil {
-		log.Fatal(fmt.Sprintf("[rese-repo]rr#35 Error while scanning from database: %v", err))
+	reservations, err := rr.scanReservations(rr.session.Query(
+		fmt.Sprintf(`SELECT %s FROM reservations_by_available_period WHERE id_available_period = ?`, reservationColumns),
+		periodId))
+	if err != nil {
+		log.Error(fmt.Sprintf("[rese-repo]rr#34 Error while finding reservations by available period: %v", err))
 		return nil, err
 	}
 
@@ -444,36 +490,10 @@ func (rr *ReservationRepo) FindAllReservationsByAvailablePeriod(periodId string)
 }
 
 func (rr *ReservationRepo) FindAllReservationsByUserID(userID string) (Reservations, error) {
-	scanner := rr.session.Query(`
-        SELECT id, id_accommodation, id_available_period, id_user, start_date, end_date, guest_number, price
-        FROM reservations_by_available_period
-        WHERE id_user = ? ALLOW FILTERING`, userID).Iter().Scanner()
-
-	var reservations Reservations
-	for scanner.Next() {
-		var (
-			idAccommodationStr string
-			idUserStr          string
-			reservation        ReservationByAvailablePeriod
-		)
-
-		err := scanner.Scan(&reservation.ID, &idAccommodationStr, &reservation.IDAvailablePeriod, &idUserStr,
-			&reservation.StartDate, &reservation.EndDate, &reservation.GuestNumber, &reservation.Price)
-
-		if err != nil {
-			log.Fatal(fmt.Sprintf("[rese-repo]rr#36 Error while scanning from database: %v", err))
-			return nil, err
-		}
-
-		// Convert strings to primitive.ObjectID
-		reservation.IDAccommodation, _ = primitive.ObjectIDFromHex(idAccommodationStr)
-		reservation.IDUser, _ = primitive.ObjectIDFromHex(idUserStr)
-
-		reservations = append(reservations, &reservation)
-	}
-
-	if err := scanner.Err(); err != nil {
-		log.Fatal(fmt.Sprintf("[rese-repo]rr#37 Error while scanning from database: %v", err))
+	reservations, err := rr.scanReservations(rr.session.Query(
+		fmt.Sprintf(`SELECT %s FROM reservations_by_user WHERE id_user = ?`, reservationColumns), userID))
+	if err != nil {
+		log.Error(fmt.Sprintf("[rese-repo]rr#36 Error while finding reservations of user: %v", err))
 		return nil, err
 	}
 
@@ -481,136 +501,95 @@ func (rr *ReservationRepo) FindAllReservationsByUserID(userID string) (Reservati
 }
 
 func (rr *ReservationRepo) FindAllReservationsByUserIDExpired(userID string) (Reservations, error) {
-	scanner := rr.session.Query(`
-        SELECT id, id_accommodation, id_available_period, id_user, start_date, end_date, guest_number, price
-        FROM reservations_by_available_period
-        WHERE id_user = ? AND end_date < ?
-        ALLOW FILTERING`, userID, time.Now()).Iter().Scanner()
-
-	var reservations Reservations
-	for scanner.Next() {
-		var (
-			idAccommodationStr string
-			idUserStr          string
-			reservation        ReservationByAvailablePeriod
-		)
-
-		err := scanner.Scan(&reservation.ID, &idAccommodationStr, &reservation.IDAvailablePeriod, &idUserStr,
-			&reservation.StartDate, &reservation.EndDate, &reservation.GuestNumber, &reservation.Price)
-
-		if err != nil {
-			log.Fatal(fmt.Sprintf("[rese-repo]rr#38 Error while scanning from database: %v", err))
-			return nil, err
-		}
-
-		// Convert strings to primitive.ObjectID
-		reservation.IDAccommodation, _ = primitive.ObjectIDFromHex(idAccommodationStr)
-		reservation.IDUser, _ = primitive.ObjectIDFromHex(idUserStr)
-
-		reservations = append(reservations, &reservation)
+	reservations, err := rr.scanReservations(rr.session.Query(
+		fmt.Sprintf(`SELECT %s FROM reservations_by_user WHERE id_user = ? AND end_date < ?`, reservationColumns),
+		userID, time.Now()))
+	if err != nil {
+		log.Error(fmt.Sprintf("[rese-repo]rr#38 Error while finding expired reservations of user: %v", err))
+		return nil, err
 	}
 
-	if err := scanner.Err(); err != nil {
-		log.Fatal(fmt.Sprintf("[rese-repo]rr#39 Error while scanning from database: %v", err))
-		return nil, err
+	// Only stays which took place, cancelled and no-show reservations are left out
+	var stayed Reservations
+	for _, reservation := range reservations {
+		if status := reservation.CurrentStatus(); status == StatusConfirmed || status == StatusCompleted {
+			stayed = append(stayed, reservation)
+		}
 	}
 
-	return reservations, nil
+	return stayed, nil
 }
 
 func (rr *ReservationRepo) FindReservationByIdAndAvailablePeriod(id, periodID string) (*ReservationByAvailablePeriod, error) {
-	query := `SELECT id, id_accommodation, id_available_period, id_user, start_date, 
-               end_date, guest_number, price 
-               FROM reservations_by_available_period 
-               WHERE id = ? AND id_available_period = ? LIMIT 1`
-
-	var (
-		idAccommodationStr string
-		idUserStr          string
-		reservation        ReservationByAvailablePeriod
-	)
-
-	err := rr.session.Query(query, id, periodID).Consistency(gocql.One).Scan(
-		&reservation.ID, &idAccommodationStr, &reservation.IDAvailablePeriod, &idUserStr,
-		&reservation.StartDate, &reservation.EndDate, &reservation.GuestNumber, &reservation.Price,
-	)
-
+	reservations, err := rr.scanReservations(rr.session.Query(
+		fmt.Sprintf(`SELECT %s FROM reservations_by_available_period WHERE id = ? AND id_available_period = ? LIMIT 1`, reservationColumns),
+		id, periodID).Consistency(gocql.One))
 	if err != nil {
-		log.Fatal(fmt.Sprintf("[rese-repo]rr#40 Error while scanning from database: %v", err))
+		log.Error(fmt.Sprintf("[rese-repo]rr#40 Error while scanning from database: %v", err))
 		return nil, err
 	}
+	if len(reservations) == 0 {
+		return nil, gocql.ErrNotFound
+	}
 
-	// Convert strings to primitive.ObjectID
-	reservation.IDAccommodation, _ = primitive.ObjectIDFromHex(idAccommodationStr)
-	reservation.IDUser, _ = primitive.ObjectIDFromHex(idUserStr)
-
-	return &reservation, nil
+	return reservations[0], nil
 }
 
-func (rr *ReservationRepo) DeleteReservationByIdAndAvailablePeriodID(id, periodID, ownerId string) error {
+// Cancels reservation on behalf of the guest who made it, the reservation is kept as cancelled_by_guest
+func (rr *ReservationRepo) CancelReservationByGuest(id, periodID, guestID string) (*ReservationByAvailablePeriod, error) {
 	reservation, err := rr.FindReservationByIdAndAvailablePeriod(id, periodID)
 	if err != nil {
-		log.Fatal(fmt.Sprintf("[rese-repo]rr#41 Error while finging reservation by id and period: %v", err))
-		return err
+		log.Error(fmt.Sprintf("[rese-repo]rr#41 Error while finging reservation by id and period: %v", err))
+		return nil, err
 	}
 
-	if reservation.IDUser.Hex() != ownerId {
+	if reservation.IDUser.Hex() != guestID {
 		log.Error(fmt.Sprintf("[rese-repo]rr#42 Error while comparing userid and ownerid: %v", err))
-		return errors.New("you are not owner of reservation")
+		return nil, errors.New("you are not owner of reservation")
 	}
 
 	if time.Now().After(reservation.StartDate) {
 		log.Error(fmt.Sprintf("[rese-repo]rr#43 Error while comparing present with reservation start date: %v", err))
-		return errors.New("cannot delete reservation after start date has passed")
+		return nil, errors.New("cannot cancel reservation after start date has passed")
 	}
 
-	query := `DELETE FROM reservations_by_available_period
-              WHERE id = ? AND id_available_period = ?`
-
-	if err := rr.session.Query(query, id, periodID).Exec(); err != nil {
-		log.Fatal(fmt.Sprintf("[rese-repo]rr#44 Error while retriving data from database: %v", err))
-		return err
+	if err := rr.ChangeReservationStatus(reservation, StatusCancelledByGuest, guestID); err != nil {
+		log.Error(fmt.Sprintf("[rese-repo]rr#44 Error while cancelling reservation: %v", err))
+		return nil, err
 	}
 
-	return nil
+	return reservation, nil
 }
 
-func (rr *ReservationRepo) CheckAndDeleteReservationsByUserID(userID primitive.ObjectID) error {
+// Fails if user has upcoming or ongoing reservations which hold their nights.
+// Past and cancelled reservations are kept as history.
+func (rr *ReservationRepo) CheckActiveReservationsByUserID(userID primitive.ObjectID) error {
 	reservations, err := rr.FindAllReservationsByUserID(userID.Hex())
 	if err != nil {
-		log.Fatal(fmt.Sprintf("[rese-repo]rr#45 Error while finding reservations by userid: %v", err))
+		log.Error(fmt.Sprintf("[rese-repo]rr#45 Error while finding reservations by userid: %v", err))
 		return err
 	}
 
-	processedAccommodations := make(map[primitive.ObjectID]bool)
 	// Check if any reservation has an end date in the future
-	for _, reservation := range reservations {
+	for _, reservation := range reservations.HoldingInventory() {
 		if time.Now().Before(reservation.EndDate) {
 			log.Error(fmt.Sprintf("[rese-repo]rr#46 Error while finding user active reservation: %v", err))
 			return errors.New("user has active reservations")
 		}
-		// Mark the accommodation as processed
-		processedAccommodations[reservation.IDAccommodation] = true
-	}
-
-	for accommodationID := range processedAccommodations {
-		query := `DELETE FROM reservations_by_available_period
-              WHERE id_accommodation = ? AND id_user = ?`
-
-		if err := rr.session.Query(query, accommodationID.Hex(), userID.Hex()).Exec(); err != nil {
-			log.Fatal(fmt.Sprintf("[rese-repo]rr#47 Error while retriving data from database: %v", err))
-			return err
-		}
 	}
 
 	return nil
 }
 
+// Deletes available periods of accommodations, so they can't be booked any more.
+// Fails if any of them has reservations holding upcoming nights. Past and cancelled reservations are kept as history.
 func (rr *ReservationRepo) DeletePeriodsForAccommodations(accIDs []primitive.ObjectID) error {
+	// All periods are checked first, so nothing is deleted when one of them can't be
+	periodsByAccommodation := make(map[primitive.ObjectID]AvailablePeriodsByAccommodation, len(accIDs))
 	for _, accID := range accIDs {
-		periods, err := rr.FindAvailablePeriodsByAccommodationId(accID.String())
+		periods, err := rr.FindAvailablePeriodsByAccommodationId(accID.Hex())
 		if err != nil {
-			log.Fatal(fmt.Sprintf("[rese-repo]rr#48 Error while finding periods by accommodation id: %v", err))
+			log.Error(fmt.Sprintf("[rese-repo]rr#48 Error while finding periods by accommodation id: %v", err))
 			return err
 		}
 
@@ -621,31 +600,23 @@ func (rr *ReservationRepo) DeletePeriodsForAccommodations(accIDs []primitive.Obj
 				return err
 			}
 
-			var reservationIDs []gocql.UUID
-			for _, reservation := range reservations {
+			for _, reservation := range reservations.HoldingInventory() {
 				if !time.Now().After(reservation.EndDate) {
 					// If the end date has not passed, disallow deletion and return an error
-					log.Error(fmt.Sprintf("[rese-repo]rr#50 Error while deleting period with active reservations: %v", err))
+					log.Error(fmt.Sprintf("[rese-repo]rr#50 Error while deleting period '%s' with active reservations", period.ID))
 					return errors.New("cannot delete period, there are active reservations")
 				}
-
-				reservationIDs = append(reservationIDs, reservation.ID)
-			}
-
-			// Batch deletion of reservations
-			if len(reservationIDs) > 0 {
-				query := `DELETE FROM reservations_by_available_period WHERE id IN ?`
-
-				if err := rr.session.Query(query, reservationIDs).Exec(); err != nil {
-					log.Error(fmt.Sprintf("[rese-repo]rr#51 Error while deleting data from databse: %v", err))
-					return err
-				}
 			}
+		}
+		periodsByAccommodation[accID] = periods
+	}
 
-			query := `DELETE FROM available_periods_by_accommodation WHERE id = ?`
+	for accID, periods := range periodsByAccommodation {
+		for _, period := range periods {
+			query := `DELETE FROM available_periods_by_accommodation WHERE id_accommodation = ? AND id = ?`
 
-			if err := rr.session.Query(query, period.ID).Exec(); err != nil {
-				log.Fatal(fmt.Sprintf("[rese-repo]rr#52 Error while deleting data from databse: %v", err))
+			if err := rr.session.Query(query, accID.Hex(), period.ID).Exec(); err != nil {
+				log.Error(fmt.Sprintf("[rese-repo]rr#52 Error while deleting data from databse: %v", err))
 				return err
 			}
 		}
@@ -699,7 +670,7 @@ func (rr *ReservationRepo) FindAccommodationIdsByDates(dates *Dates) (ListOfObje
 		accommodationIds = append(accommodationIds, id)
 	}
 
-	listOfInvalidIds, err := rr.FindReservationForSearch(periodIDs, accommodationIds, dates.StartDate, dates.EndDate)
+	listOfInvalidIds, err := rr.FindReservationForSearch(periodIDs, accommodationIds, dates)
 	if err != nil {
 		log.Error(fmt.Sprintf("[rese-repo]rr#55 Error while finding reservation for search: %v", err))
 		return ListOfObjectIds{}, err
@@ -708,7 +679,9 @@ func (rr *ReservationRepo) FindAccommodationIdsByDates(dates *Dates) (ListOfObje
 	return listOfInvalidIds, nil
 }
 
-func (rr *ReservationRepo) FindReservationForSearch(periodsIds []gocql.UUID, listOfAccommodationIds []primitive.ObjectID, startDate, endDate time.Time) (ListOfObjectIds, error) {
+// Returns accommodations with at least one unit free on every night between start and end date of dates,
+// together with the number of units free. Reservations and date holds both take units.
+func (rr *ReservationRepo) FindReservationForSearch(periodsIds []gocql.UUID, listOfAccommodationIds []primitive.ObjectID, dates *Dates) (ListOfObjectIds, error) {
 	idAccommodationsMap := make(map[primitive.ObjectID]Reservations)
 
 	for _, id := range listOfAccommodationIds {
@@ -716,65 +689,44 @@ func (rr *ReservationRepo) FindReservationForSearch(periodsIds []gocql.UUID, lis
 	}
 
 	for _, id := range periodsIds {
-		query := `SELECT id, id_accommodation, id_available_period, id_user, start_date, 
-       end_date, guest_number, price 
-       FROM reservations_by_available_period 
-       WHERE id_available_period = ? `
-
-		var (
-			idAccommodationStr string
-			idUserStr          string
-			reservation        ReservationByAvailablePeriod
-		)
-
-		iter := rr.session.Query(query, id).Consistency(gocql.One).Iter()
-
-		for iter.Scan(
-			&reservation.ID, &idAccommodationStr, &reservation.IDAvailablePeriod, &idUserStr,
-			&reservation.StartDate, &reservation.EndDate, &reservation.GuestNumber, &reservation.Price,
-		) {
-			reservation.IDAccommodation, _ = primitive.ObjectIDFromHex(idAccommodationStr)
-			reservation.IDUser, _ = primitive.ObjectIDFromHex(idUserStr)
-
-			newReservation := ReservationByAvailablePeriod{
-				ID:                reservation.ID,
-				IDAccommodation:   reservation.IDAccommodation,
-				IDAvailablePeriod: reservation.IDAvailablePeriod,
-				IDUser:            reservation.IDUser,
-				StartDate:         reservation.StartDate,
-				EndDate:           reservation.EndDate,
-				GuestNumber:       reservation.GuestNumber,
-				Price:             reservation.Price,
-			}
+		reservations, err := rr.scanReservations(rr.session.Query(
+			fmt.Sprintf(`SELECT %s FROM reservations_by_available_period WHERE id_available_period = ?`, reservationColumns),
+			id).Consistency(gocql.One))
+		if err != nil {
+			log.Error(fmt.Sprintf("[rese-repo]rr#56 Error while itering over objects: %v", err))
+			return ListOfObjectIds{}, err
+		}
 
-			idAccommodation := newReservation.IDAccommodation
-			idAccommodationsMap[idAccommodation] = append(idAccommodationsMap[idAccommodation], &newReservation)
+		// Cancelled and finished reservations don't take units
+		for _, reservation := range reservations.HoldingInventory() {
+			idAccommodationsMap[reservation.IDAccommodation] = append(idAccommodationsMap[reservation.IDAccommodation], reservation)
 		}
+	}
 
-		if err := iter.Close(); err != nil {
-			log.Error(fmt.Sprintf("[rese-repo]rr#56 Error while itering over objects: %v", err))
+	// Dates held during checkout take units as well until the holds expire
+	for id := range idAccommodationsMap {
+		holds, err := rr.findDateHoldsByAccommodation(id.Hex())
+		if err != nil {
+			log.Error(fmt.Sprintf("[rese-repo]rr#94 Error while finding date holds of accommodation '%s': %v", id.Hex(), err))
 			return ListOfObjectIds{}, err
 		}
+		for _, hold := range holds {
+			idAccommodationsMap[id] = append(idAccommodationsMap[id], &ReservationByAvailablePeriod{
+				ID: hold.ID, IDAccommodation: hold.IDAccommodation, StartDate: hold.StartDate, EndDate: hold.EndDate,
+			})
+		}
 	}
 
-	idAccommodations := ListOfObjectIds{}
+	idAccommodations := ListOfObjectIds{RemainingUnits: make(map[string]int)}
 
-	for _, reservations := range idAccommodationsMap {
-		for _, reservation := range reservations {
-			if (startDate.After(reservation.StartDate) && startDate.Before(reservation.EndDate)) ||
-				(endDate.After(reservation.StartDate) && endDate.Before(reservation.EndDate)) ||
-				startDate.Equal(reservation.EndDate) || endDate.Equal(reservation.StartDate) ||
-				endDate.Equal(reservation.EndDate) || startDate.Equal(reservation.StartDate) {
-				delete(idAccommodationsMap, reservation.IDAccommodation)
-				break
-			}
+	for id, reservations := range idAccommodationsMap {
+		remaining := dates.UnitCount(id) - reservations.OccupiedUnits(dates.StartDate, dates.EndDate)
+		if remaining > 0 {
+			idAccommodations.ObjectIds = append(idAccommodations.ObjectIds, id)
+			idAccommodations.RemainingUnits[id.Hex()] = remaining
 		}
 	}
 
-	for id, _ := range idAccommodationsMap {
-		idAccommodations.ObjectIds = append(idAccommodations.ObjectIds, id)
-	}
-
 	return idAccommodations, nil
 }
 
@@ -833,14 +785,59 @@ func (rr *ReservationRepo) isAvailablePeriodOverlap(currentPeriod AvailablePerio
 	return false
 }
 
-func (rr *ReservationRepo) calculatePrice(price float64, pricePerGuest bool, startDate, endDate time.Time, numberOfGuest int16) float64 {
-	dateDifference := endDate.Sub(startDate)
+func (rr *ReservationRepo) scanReservations(query *gocql.Query) (Reservations, error) {
+	scanner := query.Iter().Scanner()
 
-	daysDifference := float64(dateDifference.Hours() / 24)
+	var reservations Reservations
+	for scanner.Next() {
+		reservation, err := scanReservation(scanner)
+		if err != nil {
+			log.Error(fmt.Sprintf("[rese-repo]rr#73 Error while scanning from database: %v", err))
+			return nil, err
+		}
+		reservations = append(reservations, reservation)
+	}
+
+	if err := scanner.Err(); err != nil {
+		log.Error(fmt.Sprintf("[rese-repo]rr#74 Error while scanning from database: %v", err))
+		return nil, err
+	}
+
+	return reservations, nil
+}
+
+// Scans row selected with reservationColumns
+func scanReservation(scanner gocql.Scanner) (*ReservationByAvailablePeriod, error) {
+	var (
+		idAccommodationStr string
+		idUserStr          string
+		status             string
+		breakdown          string
+		reservation        ReservationByAvailablePeriod
+	)
 
-	if pricePerGuest {
-		return daysDifference * price * float64(numberOfGuest)
+	err := scanner.Scan(&reservation.ID, &idAccommodationStr, &reservation.IDAvailablePeriod, &idUserStr,
+		&reservation.StartDate, &reservation.EndDate, &reservation.GuestNumber, &reservation.Price,
+		&status, &reservation.StatusChangedAt, &reservation.StatusChangedBy, &reservation.ExpiresAt, &breakdown)
+	if err != nil {
+		return nil, err
 	}
 
-	return daysDifference * price
+	// Convert strings to primitive.ObjectID
+	reservation.IDAccommodation, _ = primitive.ObjectIDFromHex(idAccommodationStr)
+	reservation.IDUser, _ = primitive.ObjectIDFromHex(idUserStr)
+	reservation.Status = ReservationStatus(status)
+	reservation.PriceBreakdown = unmarshalBreakdown(breakdown)
+
+	return &reservation, nil
+}
+
+// Values of reservation in order of reservationColumns
+func reservationValues(reservation *ReservationByAvailablePeriod) []interface{} {
+	return []interface{}{
+		reservation.ID, reservation.IDAccommodation.Hex(), reservation.IDAvailablePeriod, reservation.IDUser.Hex(),
+		reservation.StartDate, reservation.EndDate, reservation.GuestNumber, reservation.Price,
+		reservation.Status, reservation.StatusChangedAt, reservation.StatusChangedBy, reservation.ExpiresAt,
+		marshalBreakdown(reservation.PriceBreakdown),
+	}
 }
@@ -0,0 +1,140 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/gocql/gocql"
+)
+
+// Pending reservation requests are also kept in reservation_requests until the host answers them or they expire.
+// Expired ones are found in reservation_requests_by_expiry, partitioned by day of expiry. Its entries are not
+// removed with the request, but by FindExpiredReservationRequests once they expire and their request is gone.
+
+// Requests which expired earlier are no longer looked for
+const requestExpiryLookback = 7 * 24 * time.Hour
+
+func (rr *ReservationRepo) createReservationRequestsTable() error {
+	err := rr.session.Query(
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s
+        (id UUID, id_available_period UUID, expires_at TIMESTAMP,
+        guest_username TEXT, guest_email TEXT, accommodation_name TEXT,
+        PRIMARY KEY (id))`,
+			"reservation_requests")).Exec()
+	if err != nil {
+		log.Error(fmt.Sprintf("[rese-repo]rr#83 Error while creating database tables: %v", err))
+		return err
+	}
+
+	err = rr.session.Query(
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s
+        (bucket TIMESTAMP, expires_at TIMESTAMP, id UUID,
+        PRIMARY KEY ((bucket), expires_at, id))
+        WITH CLUSTERING ORDER BY (expires_at ASC, id ASC)`,
+			"reservation_requests_by_expiry")).Exec()
+	if err != nil {
+		log.Error(fmt.Sprintf("[rese-repo]rr#112 Error while creating database tables: %v", err))
+		return err
+	}
+
+	return rr.indexReservationRequestsByExpiry()
+}
+
+// Adds requests made before reservation_requests_by_expiry was introduced to it. The table with pending
+// requests is small, and entries are upserted, so it is done on every start.
+func (rr *ReservationRepo) indexReservationRequestsByExpiry() error {
+	scanner := rr.session.Query(`SELECT id, expires_at FROM reservation_requests`).Iter().Scanner()
+	for scanner.Next() {
+		var request ReservationRequest
+		if err := scanner.Scan(&request.ID, &request.ExpiresAt); err != nil {
+			log.Error(fmt.Sprintf("[rese-repo]rr#113 Error while scanning from database: %v", err))
+			return err
+		}
+		if err := rr.session.Query(insertRequestExpiry, requestExpiryValues(&request)...).Exec(); err != nil {
+			log.Error(fmt.Sprintf("[rese-repo]rr#114 Error while indexing reservation request '%s' by expiry: %v", request.ID, err))
+			return err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		log.Error(fmt.Sprintf("[rese-repo]rr#115 Error while scanning from database: %v", err))
+		return err
+	}
+	return nil
+}
+
+const insertRequestExpiry = `INSERT INTO reservation_requests_by_expiry (bucket, expires_at, id) VALUES (?, ?, ?)`
+
+func requestExpiryValues(request *ReservationRequest) []interface{} {
+	return []interface{}{dayBucketOf(request.ExpiresAt), request.ExpiresAt, request.ID}
+}
+
+func addReservationRequest(batch *gocql.Batch, request *ReservationRequest) {
+	batch.Query(`INSERT INTO reservation_requests
+		(id, id_available_period, expires_at, guest_username, guest_email, accommodation_name) VALUES (?, ?, ?, ?, ?, ?)`,
+		request.ID, request.IDAvailablePeriod, request.ExpiresAt, request.GuestUsername, request.GuestEmail, request.AccommodationName)
+	batch.Query(insertRequestExpiry, requestExpiryValues(request)...)
+}
+
+func removeReservationRequest(batch *gocql.Batch, reservationID gocql.UUID) {
+	batch.Query(`DELETE FROM reservation_requests WHERE id = ?`, reservationID)
+}
+
+func (rr *ReservationRepo) FindReservationRequest(reservationID gocql.UUID) (*ReservationRequest, error) {
+	var request ReservationRequest
+	err := rr.session.Query(`
+		SELECT id, id_available_period, expires_at, guest_username, guest_email, accommodation_name
+		FROM reservation_requests WHERE id = ?`, reservationID).
+		Scan(&request.ID, &request.IDAvailablePeriod, &request.ExpiresAt, &request.GuestUsername, &request.GuestEmail, &request.AccommodationName)
+	if err != nil {
+		log.Error(fmt.Sprintf("[rese-repo]rr#84 Error while finding reservation request '%s': %v", reservationID, err))
+		return nil, err
+	}
+
+	return &request, nil
+}
+
+// Returns requests the host didn't answer before given time. Entries of requests which were answered,
+// or expired by an earlier call, are removed.
+func (rr *ReservationRepo) FindExpiredReservationRequests(now time.Time) ([]*ReservationRequest, error) {
+	var expired []*ReservationRequest
+	for _, bucket := range dayBuckets(now.Add(-requestExpiryLookback), now) {
+		scanner := rr.session.Query(`SELECT expires_at, id FROM reservation_requests_by_expiry WHERE bucket = ? AND expires_at <= ?`,
+			bucket, now).Iter().Scanner()
+		for scanner.Next() {
+			var request ReservationRequest
+			if err := scanner.Scan(&request.ExpiresAt, &request.ID); err != nil {
+				log.Error(fmt.Sprintf("[rese-repo]rr#85 Error while scanning from database: %v", err))
+				return nil, err
+			}
+			expired = append(expired, &request)
+		}
+
+		if err := scanner.Err(); err != nil {
+			log.Error(fmt.Sprintf("[rese-repo]rr#86 Error while scanning from database: %v", err))
+			return nil, err
+		}
+	}
+
+	var requests []*ReservationRequest
+	for _, entry := range expired {
+		request, err := rr.FindReservationRequest(entry.ID)
+		if errors.Is(err, gocql.ErrNotFound) {
+			err = rr.session.Query(`DELETE FROM reservation_requests_by_expiry WHERE bucket = ? AND expires_at = ? AND id = ?`,
+				requestExpiryValues(entry)...).Exec()
+			if err != nil {
+				log.Error(fmt.Sprintf("[rese-repo]rr#116 Error while removing expiry of reservation request '%s': %v", entry.ID, err))
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, request)
+	}
+
+	return requests, nil
+}
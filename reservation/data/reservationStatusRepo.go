@@ -0,0 +1,236 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/gocql/gocql"
+)
+
+// Adds status columns to reservation tables created before statuses were introduced,
+// and creates table keeping every status change of a reservation
+func (rr *ReservationRepo) createReservationStatusColumns() error {
+	columns := []struct{ name, cqlType string }{
+		{"status", "TEXT"},
+		{"status_changed_at", "TIMESTAMP"},
+		{"status_changed_by", "TEXT"},
+		{"expires_at", "TIMESTAMP"},
+	}
+	for _, table := range reservationTables {
+		for _, column := range columns {
+			if err := rr.addColumnIfMissing(table, column.name, column.cqlType); err != nil {
+				log.Error(fmt.Sprintf("[rese-repo]rr#78 Error while adding column '%s' to '%s': %v", column.name, table, err))
+				return err
+			}
+		}
+	}
+
+	err := rr.session.Query(
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s
+        (id_reservation UUID, changed_at TIMESTAMP, status TEXT, changed_by TEXT,
+        PRIMARY KEY ((id_reservation), changed_at))
+        WITH CLUSTERING ORDER BY (changed_at ASC)`,
+			"reservation_status_history")).Exec()
+	if err != nil {
+		log.Error(fmt.Sprintf("[rese-repo]rr#79 Error while creating database tables: %v", err))
+		return err
+	}
+
+	err = rr.session.Query(
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s
+        (bucket TIMESTAMP, id_available_period UUID, id UUID, changed_at TIMESTAMP,
+        PRIMARY KEY ((bucket), id_available_period, id))`,
+			"unsynced_status_changes")).Exec()
+	if err != nil {
+		log.Error(fmt.Sprintf("[rese-repo]rr#105 Error while creating database tables: %v", err))
+		return err
+	}
+
+	return nil
+}
+
+func (rr *ReservationRepo) addColumnIfMissing(table, column, cqlType string) error {
+	query := rr.session.Query(`SELECT column_name FROM system_schema.columns
+		WHERE keyspace_name = ? AND table_name = ? AND column_name = ?`)
+
+	var existing string
+	err := query.Bind(query.Keyspace(), table, column).Scan(&existing)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, gocql.ErrNotFound) {
+		return err
+	}
+
+	return rr.session.Query(fmt.Sprintf(`ALTER TABLE %s ADD %s %s`, table, column, cqlType)).Exec()
+}
+
+func addStatusHistory(batch *gocql.Batch, reservation *ReservationByAvailablePeriod) {
+	batch.Query(`INSERT INTO reservation_status_history (id_reservation, changed_at, status, changed_by) VALUES (?, ?, ?, ?)`,
+		reservation.ID, reservation.StatusChangedAt, reservation.Status, reservation.StatusChangedBy)
+}
+
+// Status is changed with a lightweight transaction on reservations_by_available_period, which can't be
+// batched with writes to other tables. Every change is therefore first recorded in unsynced_status_changes,
+// partitioned by day, and removed once lookup tables, history and reserved nights follow the new status.
+// Changes left there by a failed write or a stopped service are completed by SyncReservationStatusChanges.
+const (
+	// Changes recorded earlier are no longer looked at
+	statusSyncLookback = 7 * 24 * time.Hour
+	// Younger changes are most likely still being completed by the request which made them
+	statusSyncGrace = time.Minute
+)
+
+// Moves reservation to target status on behalf of actor and records the change.
+// Nights are freed when reservation stops holding them. Once the status is changed, failing to update
+// the lookup tables or to free the nights doesn't fail the change, it is completed later by status sync.
+// Returns ErrStatusChanged if the status was changed since reservation was read.
+func (rr *ReservationRepo) ChangeReservationStatus(reservation *ReservationByAvailablePeriod, target ReservationStatus, actor string) error {
+	if err := reservation.ValidateTransition(target); err != nil {
+		return err
+	}
+
+	current := reservation.CurrentStatus()
+	// Stored timestamps have millisecond precision, status sync writes the change with the stored one
+	changedAt := time.Now().Truncate(time.Millisecond)
+
+	err := rr.session.Query(`INSERT INTO unsynced_status_changes (bucket, id_available_period, id, changed_at) VALUES (?, ?, ?, ?)`,
+		dayBucketOf(changedAt), reservation.IDAvailablePeriod, reservation.ID, changedAt).Exec()
+	if err != nil {
+		log.Error(fmt.Sprintf("[rese-repo]rr#106 Error while recording status change of reservation '%s': %v", reservation.ID, err))
+		return err
+	}
+
+	// Reservations made before statuses were introduced have no status stored
+	condition, conditionValues := `IF status = ?`, []interface{}{reservation.Status}
+	if reservation.Status == "" {
+		condition, conditionValues = `IF status = null`, nil
+	}
+
+	values := append([]interface{}{target, changedAt, actor, reservation.IDAvailablePeriod, reservation.ID}, conditionValues...)
+	applied, err := rr.session.Query(
+		`UPDATE reservations_by_available_period SET status = ?, status_changed_at = ?, status_changed_by = ?
+		WHERE id_available_period = ? AND id = ? `+condition,
+		values...).MapScanCAS(map[string]interface{}{})
+	if err != nil {
+		// The change may still have been applied, status sync finds out
+		log.Error(fmt.Sprintf("[rese-repo]rr#80 Error while changing status of reservation '%s': %v", reservation.ID, err))
+		return err
+	}
+	if !applied {
+		rr.removeUnsyncedStatusChange(reservation, changedAt)
+		return ErrStatusChanged
+	}
+
+	reservation.Status = target
+	reservation.StatusChangedAt = changedAt
+	reservation.StatusChangedBy = actor
+	if current == StatusPending {
+		reservation.ExpiresAt = time.Time{}
+	}
+
+	if err := rr.syncStatusChange(reservation); err != nil {
+		log.Error(fmt.Sprintf("[rese-repo]rr#81 Status change of reservation '%s' to '%s' is left to status sync: %v", reservation.ID, target, err))
+		return nil
+	}
+	rr.removeUnsyncedStatusChange(reservation, changedAt)
+
+	return nil
+}
+
+// Brings lookup tables, status history, pending requests and reserved nights in line with the status of
+// reservation as stored in reservations_by_available_period. Every write can be repeated, and lookup tables
+// are written with the time of the change, so a repeated older change never overwrites a newer one.
+func (rr *ReservationRepo) syncStatusChange(reservation *ReservationByAvailablePeriod) error {
+	status := reservation.CurrentStatus()
+	changedAt := reservation.StatusChangedAt.UnixMicro()
+
+	batch := rr.session.NewBatch(gocql.LoggedBatch)
+	batch.Query(`UPDATE reservations_by_user USING TIMESTAMP ? SET status = ?, status_changed_at = ?, status_changed_by = ?
+		WHERE id_user = ? AND end_date = ? AND id = ?`,
+		changedAt, status, reservation.StatusChangedAt, reservation.StatusChangedBy, reservation.IDUser.Hex(), reservation.EndDate, reservation.ID)
+	batch.Query(`UPDATE reservations_by_accommodation USING TIMESTAMP ? SET status = ?, status_changed_at = ?, status_changed_by = ?
+		WHERE id_accommodation = ? AND start_date = ? AND id = ?`,
+		changedAt, status, reservation.StatusChangedAt, reservation.StatusChangedBy, reservation.IDAccommodation.Hex(), reservation.StartDate, reservation.ID)
+	addStatusHistory(batch, reservation)
+	if status != StatusPending {
+		removeReservationRequest(batch, reservation.ID)
+	}
+	if err := rr.session.ExecuteBatch(batch); err != nil {
+		return err
+	}
+
+	if !status.HoldsInventory() {
+		if err := rr.releaseReservation(reservation); err != nil {
+			log.Error(fmt.Sprintf("[rese-repo]rr#82 Error while freeing nights of reservation '%s': %v", reservation.ID, err))
+			return err
+		}
+	}
+	return nil
+}
+
+func (rr *ReservationRepo) removeUnsyncedStatusChange(reservation *ReservationByAvailablePeriod, changedAt time.Time) {
+	err := rr.session.Query(`DELETE FROM unsynced_status_changes WHERE bucket = ? AND id_available_period = ? AND id = ?`,
+		dayBucketOf(changedAt), reservation.IDAvailablePeriod, reservation.ID).Exec()
+	if err != nil {
+		log.Warning(fmt.Sprintf("[rese-repo]rr#107 Status change of reservation '%s' is left to status sync: %v", reservation.ID, err))
+	}
+}
+
+type unsyncedStatusChange struct {
+	idAvailablePeriod gocql.UUID
+	id                gocql.UUID
+	changedAt         time.Time
+}
+
+// Completes status changes left unsynced by ChangeReservationStatus, recorded before now less a grace period
+// given to requests still completing them. Returns number of completed changes.
+func (rr *ReservationRepo) SyncReservationStatusChanges(now time.Time) (int, error) {
+	var changes []unsyncedStatusChange
+	for _, bucket := range dayBuckets(now.Add(-statusSyncLookback), now) {
+		scanner := rr.session.Query(`SELECT id_available_period, id, changed_at FROM unsynced_status_changes WHERE bucket = ?`,
+			bucket).Iter().Scanner()
+		for scanner.Next() {
+			var change unsyncedStatusChange
+			if err := scanner.Scan(&change.idAvailablePeriod, &change.id, &change.changedAt); err != nil {
+				log.Error(fmt.Sprintf("[rese-repo]rr#108 Error while scanning from database: %v", err))
+				return 0, err
+			}
+			if change.changedAt.Before(now.Add(-statusSyncGrace)) {
+				changes = append(changes, change)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			log.Error(fmt.Sprintf("[rese-repo]rr#109 Error while scanning from database: %v", err))
+			return 0, err
+		}
+	}
+
+	synced := 0
+	for _, change := range changes {
+		// Status is read the way it was written, by a lightweight transaction
+		reservations, err := rr.scanReservations(rr.session.Query(
+			fmt.Sprintf(`SELECT %s FROM reservations_by_available_period WHERE id_available_period = ? AND id = ?`, reservationColumns),
+			change.idAvailablePeriod, change.id).Consistency(gocql.Quorum))
+		if err != nil {
+			log.Error(fmt.Sprintf("[rese-repo]rr#110 Error while finding reservation '%s': %v", change.id, err))
+			continue
+		}
+
+		// Nothing to sync when reservation was deleted or its status was never changed
+		if len(reservations) == 1 && reservations[0].Status != "" {
+			if err := rr.syncStatusChange(reservations[0]); err != nil {
+				log.Error(fmt.Sprintf("[rese-repo]rr#111 Error while syncing status change of reservation '%s': %v", change.id, err))
+				continue
+			}
+		}
+
+		rr.removeUnsyncedStatusChange(&ReservationByAvailablePeriod{ID: change.id, IDAvailablePeriod: change.idAvailablePeriod}, change.changedAt)
+		synced++
+	}
+
+	return synced, nil
+}
@@ -0,0 +1,96 @@
+package data
+
+import (
+	"testing"
+	"time"
+)
+
+// Day of July 2030
+func day(d int) time.Time {
+	return time.Date(2030, 7, d, 0, 0, 0, 0, time.UTC)
+}
+
+func stay(start, end int) *ReservationByAvailablePeriod {
+	return &ReservationByAvailablePeriod{StartDate: day(start), EndDate: day(end)}
+}
+
+func TestOccupiedUnits(t *testing.T) {
+	tests := []struct {
+		name         string
+		reservations Reservations
+		start        int
+		end          int
+		want         int
+	}{
+		{
+			name:  "no reservations",
+			start: 1,
+			end:   5,
+			want:  0,
+		},
+		{
+			name:         "back-to-back stays share no night",
+			reservations: Reservations{stay(1, 3), stay(3, 5)},
+			start:        1,
+			end:          5,
+			want:         1,
+		},
+		{
+			name:         "overlapping stays",
+			reservations: Reservations{stay(1, 4), stay(3, 6)},
+			start:        1,
+			end:          6,
+			want:         2,
+		},
+		{
+			name:         "stay overlapping start of the range",
+			reservations: Reservations{stay(1, 4)},
+			start:        3,
+			end:          6,
+			want:         1,
+		},
+		{
+			name:         "stay ending on the first day of the range",
+			reservations: Reservations{stay(1, 3)},
+			start:        3,
+			end:          5,
+			want:         0,
+		},
+		{
+			name:         "stay starting on the last day of the range",
+			reservations: Reservations{stay(5, 7)},
+			start:        3,
+			end:          5,
+			want:         0,
+		},
+		{
+			name:         "chained overlaps count the busiest night",
+			reservations: Reservations{stay(1, 3), stay(2, 4), stay(3, 5)},
+			start:        1,
+			end:          5,
+			want:         2,
+		},
+		{
+			name:         "stays within a longer stay",
+			reservations: Reservations{stay(1, 10), stay(4, 5), stay(4, 6)},
+			start:        1,
+			end:          10,
+			want:         3,
+		},
+		{
+			name:         "overlap outside the range is not counted",
+			reservations: Reservations{stay(1, 4), stay(2, 4), stay(3, 8)},
+			start:        4,
+			end:          8,
+			want:         1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.reservations.OccupiedUnits(day(tt.start), day(tt.end)); got != tt.want {
+				t.Errorf("OccupiedUnits() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
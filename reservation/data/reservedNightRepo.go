@@ -0,0 +1,225 @@
+package data
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/gocql/gocql"
+)
+
+// Every night of a reservation holds one unit slot of the accommodation in reserved_nights_by_accommodation.
+// Slots are taken with lightweight transactions, so two reservations can never take the same unit of the same night.
+// Date holds take slots with a TTL, the reservation confirming a hold keeps its slots for good.
+
+func (rr *ReservationRepo) createReservedNightsTable() error {
+	err := rr.session.Query(
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s
+        (id_accommodation TEXT, night TIMESTAMP, unit INT, id_reservation UUID,
+        PRIMARY KEY ((id_accommodation), night, unit))
+        WITH CLUSTERING ORDER BY (night ASC, unit ASC)`,
+			"reserved_nights_by_accommodation")).Exec()
+	if err != nil {
+		log.Error(fmt.Sprintf("[rese-repo]rr#60 Error while creating database tables: %v", err))
+		return err
+	}
+
+	return nil
+}
+
+// Takes a unit slot on every night of reservation, out of units slots of the accommodation.
+// Slots are freed automatically after ttl, unless ttl is zero.
+// Returns ErrNoUnitsLeft and frees slots taken so far if some night has no free slot.
+func (rr *ReservationRepo) claimNights(reservation *ReservationByAvailablePeriod, units int, ttl time.Duration) error {
+	nights := reservation.Nights()
+	for i, night := range nights {
+		if err := rr.claimNight(reservation.IDAccommodation.Hex(), night, reservation.ID, units, ttl); err != nil {
+			if releaseErr := rr.releaseNights(reservation.IDAccommodation.Hex(), reservation.ID, nights[:i]); releaseErr != nil {
+				log.Error(fmt.Sprintf("[rese-repo]rr#61 Error while freeing nights of failed reservation '%s': %v", reservation.ID, releaseErr))
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (rr *ReservationRepo) claimNight(accommodationID string, night time.Time, reservationID gocql.UUID, units int, ttl time.Duration) error {
+	taken, err := rr.findReservedUnits(accommodationID, night)
+	if err != nil {
+		return err
+	}
+
+	// Night is already reserved if reservation takes any slot of it, even one above units
+	for unit, slot := range taken {
+		if slot.owner != reservationID {
+			continue
+		}
+		if !slot.held || ttl > 0 {
+			return nil
+		}
+
+		// Slot is held for the reservation, keep it for good
+		applied, err := rr.session.Query(
+			`UPDATE reserved_nights_by_accommodation USING TTL 0 SET id_reservation = ?
+			WHERE id_accommodation = ? AND night = ? AND unit = ? IF id_reservation = ?`,
+			reservationID, accommodationID, night, unit, reservationID).MapScanCAS(map[string]interface{}{})
+		if err != nil {
+			log.Error(fmt.Sprintf("[rese-repo]rr#87 Error while keeping held night in database: %v", err))
+			return err
+		}
+		if applied {
+			return nil
+		}
+		// Hold expired in the meantime, the slot is free again
+		delete(taken, unit)
+		break
+	}
+
+	for unit := 0; unit < units; unit++ {
+		if _, ok := taken[unit]; ok {
+			continue
+		}
+
+		applied, err := rr.session.Query(
+			`INSERT INTO reserved_nights_by_accommodation (id_accommodation, night, unit, id_reservation)
+			VALUES (?, ?, ?, ?) IF NOT EXISTS USING TTL ?`,
+			accommodationID, night, unit, reservationID, int(ttl.Seconds())).MapScanCAS(map[string]interface{}{})
+		if err != nil {
+			log.Error(fmt.Sprintf("[rese-repo]rr#62 Error while reserving night in database: %v", err))
+			return err
+		}
+		if applied {
+			return nil
+		}
+		// Another reservation took the unit in the meantime, try the next one
+	}
+
+	return ErrNoUnitsLeft
+}
+
+// Frees unit slots reservation holds on given nights
+func (rr *ReservationRepo) releaseNights(accommodationID string, reservationID gocql.UUID, nights []time.Time) error {
+	for _, night := range nights {
+		taken, err := rr.findReservedUnits(accommodationID, night)
+		if err != nil {
+			return err
+		}
+
+		for unit, slot := range taken {
+			if slot.owner != reservationID {
+				continue
+			}
+
+			_, err := rr.session.Query(
+				`DELETE FROM reserved_nights_by_accommodation
+				WHERE id_accommodation = ? AND night = ? AND unit = ? IF id_reservation = ?`,
+				accommodationID, night, unit, reservationID).MapScanCAS(map[string]interface{}{})
+			if err != nil {
+				log.Error(fmt.Sprintf("[rese-repo]rr#63 Error while freeing reserved night in database: %v", err))
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (rr *ReservationRepo) releaseReservation(reservation *ReservationByAvailablePeriod) error {
+	return rr.releaseNights(reservation.IDAccommodation.Hex(), reservation.ID, reservation.Nights())
+}
+
+type reservedUnit struct {
+	owner gocql.UUID
+	// Taken by a date hold and freed once it expires
+	held bool
+}
+
+// Returns reservation or hold taking each taken unit slot of the night
+func (rr *ReservationRepo) findReservedUnits(accommodationID string, night time.Time) (map[int]reservedUnit, error) {
+	scanner := rr.session.Query(
+		`SELECT unit, id_reservation, TTL(id_reservation) FROM reserved_nights_by_accommodation
+		WHERE id_accommodation = ? AND night = ?`,
+		accommodationID, night).Consistency(gocql.Quorum).Iter().Scanner()
+
+	taken := make(map[int]reservedUnit)
+	for scanner.Next() {
+		var (
+			unit          int
+			reservationID gocql.UUID
+			ttl           int
+		)
+		if err := scanner.Scan(&unit, &reservationID, &ttl); err != nil {
+			log.Error(fmt.Sprintf("[rese-repo]rr#64 Error while scanning from database: %v", err))
+			return nil, err
+		}
+		taken[unit] = reservedUnit{owner: reservationID, held: ttl > 0}
+	}
+
+	if err := scanner.Err(); err != nil {
+		log.Error(fmt.Sprintf("[rese-repo]rr#65 Error while scanning from database: %v", err))
+		return nil, err
+	}
+
+	return taken, nil
+}
+
+// Reserves nights of upcoming reservations made before nights were reserved with them, out of units of
+// each accommodation as returned by unitsOf. Reservations already holding their nights are skipped, so
+// running it again is harmless. Reservations are read from reservations_by_user, which has to be backfilled
+// first with -backfill-reservation-lookups. Reservations that can't be given their nights don't stop the others,
+// but they are all listed in the returned error, with accommodations whose units couldn't be resolved.
+// Returns number of reservations holding their nights.
+func (rr *ReservationRepo) ReserveNightsOfUpcomingReservations(unitsOf func(accommodationID primitive.ObjectID) (int, error)) (int, error) {
+	// Reservations of each guest are ordered by end date, so upcoming ones are read without scanning
+	userIDs, err := rr.GetDistinctIds("id_user", "reservations_by_user")
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	byAccommodation := make(map[primitive.ObjectID]Reservations)
+	for _, userID := range userIDs {
+		reservations, err := rr.scanReservations(rr.session.Query(
+			fmt.Sprintf(`SELECT %s FROM reservations_by_user WHERE id_user = ? AND end_date > ?`, reservationColumns),
+			userID, now))
+		if err != nil {
+			log.Error(fmt.Sprintf("[rese-repo]rr#66 Error while finding upcoming reservations of user '%s': %v", userID, err))
+			return 0, err
+		}
+		for _, reservation := range reservations {
+			if reservation.CurrentStatus().HoldsInventory() {
+				byAccommodation[reservation.IDAccommodation] = append(byAccommodation[reservation.IDAccommodation], reservation)
+			}
+		}
+	}
+
+	reserved := 0
+	var unresolved, failed []string
+	for accommodationID, reservations := range byAccommodation {
+		units, err := unitsOf(accommodationID)
+		if err != nil {
+			log.Error(fmt.Sprintf("[rese-repo]rr#103 Skipping %d reservations of accommodation '%s', failed to get its units: %v",
+				len(reservations), accommodationID.Hex(), err))
+			unresolved = append(unresolved, accommodationID.Hex())
+			continue
+		}
+
+		for _, reservation := range reservations {
+			if err := rr.claimNights(reservation, units, 0); err != nil {
+				log.Error(fmt.Sprintf("[rese-repo]rr#68 Error while reserving nights of reservation '%s': %v", reservation.ID, err))
+				failed = append(failed, reservation.ID.String())
+				continue
+			}
+			reserved++
+		}
+	}
+
+	if len(unresolved) > 0 || len(failed) > 0 {
+		return reserved, fmt.Errorf("units of accommodations %v couldn't be resolved, nights of reservations %v couldn't be reserved",
+			unresolved, failed)
+	}
+	return reserved, nil
+}
@@ -0,0 +1,119 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ReservationStatus is the lifecycle state of a reservation.
+// Only pending and confirmed reservations hold their nights, all other states free them.
+type ReservationStatus string
+
+const (
+	StatusPending          ReservationStatus = "pending"
+	StatusConfirmed        ReservationStatus = "confirmed"
+	StatusCancelledByGuest ReservationStatus = "cancelled_by_guest"
+	StatusCancelledByHost  ReservationStatus = "cancelled_by_host"
+	StatusCompleted        ReservationStatus = "completed"
+	StatusNoShow           ReservationStatus = "no_show"
+	// Outcomes of reservation requests the host didn't accept
+	StatusDeclined ReservationStatus = "declined"
+	StatusExpired  ReservationStatus = "expired"
+)
+
+// Actor recorded for status changes made by the service itself
+const SystemActor = "system"
+
+var (
+	ErrInvalidStatusTransition = errors.New("invalid status transition")
+	ErrStatusChanged           = errors.New("reservation status was changed in the meantime")
+)
+
+// Allowed transitions from each status, only pending and confirmed reservations can still change
+var statusTransitions = map[ReservationStatus][]ReservationStatus{
+	StatusPending:          {StatusConfirmed, StatusDeclined, StatusExpired, StatusCancelledByGuest},
+	StatusConfirmed:        {StatusCancelledByGuest, StatusCancelledByHost, StatusCompleted, StatusNoShow},
+	StatusCancelledByGuest: {},
+	StatusCancelledByHost:  {},
+	StatusCompleted:        {},
+	StatusNoShow:           {},
+	StatusDeclined:         {},
+	StatusExpired:          {},
+}
+
+// Statuses hosts can move confirmed reservations of their accommodations to.
+// Reservation requests are answered by accepting or declining them.
+var hostStatuses = []ReservationStatus{StatusCancelledByHost, StatusCompleted, StatusNoShow}
+
+type StatusChange struct {
+	Status ReservationStatus `json:"status"`
+}
+
+// Entry of reservation status history
+type StatusHistoryEntry struct {
+	Status    ReservationStatus `json:"status"`
+	ChangedAt time.Time         `json:"changedAt"`
+	ChangedBy string            `json:"changedBy"`
+}
+
+func (s ReservationStatus) IsValid() bool {
+	_, ok := statusTransitions[s]
+	return ok
+}
+
+func (s ReservationStatus) CanTransitionTo(target ReservationStatus) bool {
+	for _, allowed := range statusTransitions[s] {
+		if allowed == target {
+			return true
+		}
+	}
+	return false
+}
+
+func (s ReservationStatus) HoldsInventory() bool {
+	return s == StatusPending || s == StatusConfirmed
+}
+
+func (s ReservationStatus) IsHostStatus() bool {
+	for _, status := range hostStatuses {
+		if status == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Reservations made before statuses were introduced have none and count as confirmed
+func (r *ReservationByAvailablePeriod) CurrentStatus() ReservationStatus {
+	if r.Status == "" {
+		return StatusConfirmed
+	}
+	return r.Status
+}
+
+// Checks that reservation can be moved to target status
+func (r *ReservationByAvailablePeriod) ValidateTransition(target ReservationStatus) error {
+	if !target.IsValid() {
+		return fmt.Errorf("unknown status '%s'", target)
+	}
+	current := r.CurrentStatus()
+	if !current.CanTransitionTo(target) {
+		return fmt.Errorf("%w from '%s' to '%s'", ErrInvalidStatusTransition, current, target)
+	}
+	if (target == StatusCompleted || target == StatusNoShow) && time.Now().Before(r.StartDate) {
+		return fmt.Errorf("reservation can be marked %s only after its start date", target)
+	}
+	return nil
+}
+
+// Returns reservations whose status holds their nights
+func (r Reservations) HoldingInventory() Reservations {
+	var holding Reservations
+	for _, reservation := range r {
+		if reservation.CurrentStatus().HoldsInventory() {
+			holding = append(holding, reservation)
+		}
+	}
+	return holding
+}
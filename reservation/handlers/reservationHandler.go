@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -11,6 +13,7 @@ import (
 
 	"reservation/clients"
 	"reservation/data"
+	"reservation/pricing"
 
 	"github.com/gorilla/mux"
 	log "github.com/sirupsen/logrus"
@@ -28,13 +31,15 @@ type ReservationHandler struct {
 	notification  clients.NotificationClient
 	profile       clients.ProfileClient
 	accommodation clients.AccommodationClient
+	// Signs price quotes, kept apart from secretKey so quote tokens can't pass as access tokens
+	quoteSecretKey []byte
 }
 
 var secretKey = []byte("stayinn_secret")
 
 func NewReservationHandler(r *data.ReservationRepo, n clients.NotificationClient,
-	p clients.ProfileClient, a clients.AccommodationClient) *ReservationHandler {
-	return &ReservationHandler{r, n, p, a}
+	p clients.ProfileClient, a clients.AccommodationClient, quoteSecretKey []byte) *ReservationHandler {
+	return &ReservationHandler{r, n, p, a, quoteSecretKey}
 }
 
 func (r *ReservationHandler) GetAllAvailablePeriodsByAccommodation(rw http.ResponseWriter, h *http.Request) {
@@ -211,21 +216,60 @@ func (r *ReservationHandler) CreateReservation(rw http.ResponseWriter, h *http.R
 		return
 	}
 
-	err = r.repo.InsertReservationByAvailablePeriod(reservation)
-	if err != nil {
-		log.Error(fmt.Sprintf("[rese-handler]rh#20 Error while inserting in database: %v", err))
-		http.Error(rw, fmt.Sprintf("Failed to create reservation: %v", err), http.StatusBadRequest)
+	// Get accommodation, its unit count limits overlapping reservations
+	accommodation, err := r.accommodation.GetAccommodationByID(h.Context(), reservation.IDAccommodation, tokenStr)
+	if accommodationNotFound(err) {
+		http.Error(rw, "Accommodation is not bookable", http.StatusConflict)
 		return
 	}
-
-	// Get accommodation
-	accommodation, err := r.accommodation.GetAccommodationByID(h.Context(), reservation.IDAccommodation, tokenStr)
 	if err != nil {
 		log.Error(fmt.Sprintf("[rese-handler]rh#21 Error while finding accommodation by id: %v", err))
 		http.Error(rw, "failed to get accommodation by ID", http.StatusInternalServerError)
 		return
 	}
 
+	// Reservation made with a quote is charged the quoted price
+	var quote *pricing.Quote
+	if reservation.QuoteToken != "" {
+		quote, err = pricing.ParseQuote(reservation.QuoteToken, r.quoteSecretKey)
+		if err != nil || quote.Username != username {
+			http.Error(rw, pricing.ErrInvalidQuote.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Guest is told the outcome of a reservation request, so their contact is kept with it
+	var guest *data.User
+	if accommodation.RequiresApproval() {
+		user, err := r.profile.GetUserById(h.Context(), reservation.IDUser, tokenStr)
+		if err != nil {
+			log.Error(fmt.Sprintf("[rese-handler]rh#83 Error while finding guest by id: %v", err))
+			http.Error(rw, "failed to get guest by ID", http.StatusInternalServerError)
+			return
+		}
+		guest = &user
+	}
+
+	err = r.repo.InsertReservationByAvailablePeriod(reservation, &accommodation, guest, quote)
+	if errors.Is(err, data.ErrNoUnitsLeft) {
+		log.Warning(fmt.Sprintf("[rese-handler]rh#73 Requested dates are already reserved: %v", err))
+		http.Error(rw, "The requested dates are no longer available", http.StatusConflict)
+		return
+	}
+	if errors.Is(err, data.ErrHoldExpired) {
+		http.Error(rw, "The hold on the requested dates has expired", http.StatusConflict)
+		return
+	}
+	if errors.Is(err, data.ErrQuoteMismatch) {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Error(fmt.Sprintf("[rese-handler]rh#20 Error while inserting in database: %v", err))
+		http.Error(rw, fmt.Sprintf("Failed to create reservation: %v", err), http.StatusBadRequest)
+		return
+	}
+
 	// Get host
 	host, err := r.profile.GetUserById(h.Context(), accommodation.HostID, tokenStr)
 	if err != nil {
@@ -235,12 +279,23 @@ func (r *ReservationHandler) CreateReservation(rw http.ResponseWriter, h *http.R
 	}
 
 	// Notify host
+	text := fmt.Sprintf("Reservation created for %s, by user %s", accommodation.Name, username)
+	intent := data.IntentReservationNew
+	if reservation.Status == data.StatusPending {
+		text = fmt.Sprintf("Reservation requested for %s, by user %s, answer it before %s",
+			accommodation.Name, username, reservation.ExpiresAt.Format(time.RFC1123))
+		intent = data.IntentReservationRequested
+	}
 	notification := data.Notification{
-		HostID:       host.ID,
-		HostUsername: host.Username,
-		HostEmail:    host.Email,
-		Text:         fmt.Sprintf("Reservation created for %s, by user %s", accommodation.Name, username),
-		Time:         time.Now(),
+		HostID:            host.ID,
+		HostUsername:      host.Username,
+		HostEmail:         host.Email,
+		Text:              text,
+		Intent:            intent,
+		Time:              time.Now(),
+		RecipientID:       host.ID,
+		RecipientUsername: host.Username,
+		RecipientEmail:    host.Email,
 	}
 
 	notified, err := r.notification.NotifyReservation(h.Context(), notification, tokenStr)
@@ -253,6 +308,123 @@ func (r *ReservationHandler) CreateReservation(rw http.ResponseWriter, h *http.R
 	log.Info(fmt.Sprintf("[rese-handler]rh#52 User id:'%s' successfuly created reservation", userID))
 
 	rw.WriteHeader(http.StatusCreated)
+	if err := reservation.ToJSON(rw); err != nil {
+		log.Error(fmt.Sprintf("[rese-handler]rh#84 Error while encoding reservation: %v", err))
+	}
+}
+
+// Prices the requested stay without reserving it, the returned token lets the guest reserve at the quoted price
+func (r *ReservationHandler) QuoteReservation(rw http.ResponseWriter, h *http.Request) {
+	reservation := h.Context().Value(KeyProduct{}).(*data.ReservationByAvailablePeriod)
+
+	tokenStr := r.extractTokenFromHeader(h)
+	username, err := r.getUsername(tokenStr)
+	if err != nil {
+		log.Warning(fmt.Sprintf("[rese-handler]rh#119 Error while reading username from token: %v", err))
+		http.Error(rw, FailedToReadUsernameFromToken, http.StatusBadRequest)
+		return
+	}
+
+	log.Info(fmt.Sprintf("[rese-handler]rh#120 Received request from '%s' for price quote", h.RemoteAddr))
+
+	if reservation.GuestNumber < 1 {
+		http.Error(rw, "Number of guests must be at least 1", http.StatusBadRequest)
+		return
+	}
+
+	breakdown, err := r.repo.QuoteStay(reservation)
+	if errors.Is(err, data.ErrNoAvailablePeriod) {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Error(fmt.Sprintf("[rese-handler]rh#121 Error while quoting stay: %v", err))
+		http.Error(rw, fmt.Sprintf("Failed to quote stay: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	quote, err := pricing.SignQuote(pricing.Quote{
+		Username:          username,
+		IDAccommodation:   reservation.IDAccommodation.Hex(),
+		IDAvailablePeriod: reservation.IDAvailablePeriod.String(),
+		StartDate:         reservation.StartDate,
+		EndDate:           reservation.EndDate,
+		Guests:            int(reservation.GuestNumber),
+		Breakdown:         *breakdown,
+		ExpiresAt:         time.Now().Add(pricing.QuoteDuration),
+	}, r.quoteSecretKey)
+	if err != nil {
+		log.Error(fmt.Sprintf("[rese-handler]rh#122 Error while signing quote: %v", err))
+		http.Error(rw, "Failed to quote stay", http.StatusInternalServerError)
+		return
+	}
+
+	log.Info(fmt.Sprintf("[rese-handler]rh#123 Quoted %.2f to user '%s'", quote.Breakdown.Total, username))
+
+	if err := json.NewEncoder(rw).Encode(quote); err != nil {
+		log.Error(fmt.Sprintf("[rese-handler]rh#124 Error while encoding quote: %v", err))
+		http.Error(rw, UnableToConvertToJson, http.StatusInternalServerError)
+	}
+}
+
+// Holds the requested dates for the guest while they finish the checkout
+func (r *ReservationHandler) HoldDates(rw http.ResponseWriter, h *http.Request) {
+	reservation := h.Context().Value(KeyProduct{}).(*data.ReservationByAvailablePeriod)
+
+	tokenStr := r.extractTokenFromHeader(h)
+	username, err := r.getUsername(tokenStr)
+	if err != nil {
+		log.Warning(fmt.Sprintf("[rese-handler]rh#99 Error while reading username from token: %v", err))
+		http.Error(rw, FailedToReadUsernameFromToken, http.StatusBadRequest)
+		return
+	}
+
+	log.Info(fmt.Sprintf("[rese-handler]rh#100 Received request from '%s' to hold dates", h.RemoteAddr))
+
+	userID, err := r.profile.GetUserId(h.Context(), username, tokenStr)
+	if err != nil {
+		log.Error(fmt.Sprintf("[rese-handler]rh#101 Error while getting guestId for username: %v", err))
+		http.Error(rw, "Failed to get guestID from username", http.StatusBadRequest)
+		return
+	}
+
+	reservation.IDUser, err = primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		log.Error(fmt.Sprintf("[rese-handler]rh#102 Error while parsing guest id: %v", err))
+		http.Error(rw, "Failed to set guest ID", http.StatusBadRequest)
+		return
+	}
+
+	// Get accommodation, its unit count limits overlapping holds and reservations
+	accommodation, err := r.accommodation.GetAccommodationByID(h.Context(), reservation.IDAccommodation, tokenStr)
+	if accommodationNotFound(err) {
+		http.Error(rw, "Accommodation is not bookable", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Error(fmt.Sprintf("[rese-handler]rh#103 Error while finding accommodation by id: %v", err))
+		http.Error(rw, "failed to get accommodation by ID", http.StatusInternalServerError)
+		return
+	}
+
+	hold, err := r.repo.HoldDates(reservation, accommodation.UnitCount())
+	if errors.Is(err, data.ErrNoUnitsLeft) {
+		log.Warning(fmt.Sprintf("[rese-handler]rh#104 Requested dates are already reserved: %v", err))
+		http.Error(rw, "The requested dates are no longer available", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Error(fmt.Sprintf("[rese-handler]rh#105 Error while holding dates: %v", err))
+		http.Error(rw, fmt.Sprintf("Failed to hold dates: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	log.Info(fmt.Sprintf("[rese-handler]rh#106 User id:'%s' holds dates until %s", userID, hold.ExpiresAt))
+
+	rw.WriteHeader(http.StatusCreated)
+	if err := hold.ToJSON(rw); err != nil {
+		log.Error(fmt.Sprintf("[rese-handler]rh#107 Error while encoding date hold: %v", err))
+	}
 }
 
 func (r *ReservationHandler) FindAccommodationIdsByDates(rw http.ResponseWriter, h *http.Request) {
@@ -409,14 +581,16 @@ func (r *ReservationHandler) CheckAndDeleteReservationsForUser(rw http.ResponseW
 		return
 	}
 
-	log.Info(fmt.Sprintf("[rese-handler]rh#69 Received request from '%s' to check and delete reservations for user '%s'", h.RemoteAddr, userID.Hex()))
+	log.Info(fmt.Sprintf("[rese-handler]rh#69 Received request from '%s' to check reservations for user '%s'", h.RemoteAddr, userID.Hex()))
 
-	err = r.repo.CheckAndDeleteReservationsByUserID(userID)
+	// Reservations are kept as history, only active ones prevent deleting the user
+	err = r.repo.CheckActiveReservationsByUserID(userID)
 	if err != nil {
-		log.Error(fmt.Sprintf("[rese-handler]rh#38 Error while checking and deleting reservations by user id : %v", err))
+		log.Error(fmt.Sprintf("[rese-handler]rh#38 Error while checking reservations by user id : %v", err))
 		rw.WriteHeader(http.StatusBadRequest)
+		return
 	}
-	log.Info(fmt.Sprintf("[rese-handler]rh#57 Successfully deleted reservtions for user id:'%s'", userID))
+	log.Info(fmt.Sprintf("[rese-handler]rh#57 User id:'%s' has no active reservations", userID))
 
 	rw.WriteHeader(http.StatusNoContent)
 }
@@ -433,7 +607,7 @@ func (r *ReservationHandler) DeleteReservation(rw http.ResponseWriter, h *http.R
 		return
 	}
 
-	log.Info(fmt.Sprintf("[rese-handler]rh#70 Received request from '%s' to delete reservation '%s'", h.RemoteAddr, reservationID))
+	log.Info(fmt.Sprintf("[rese-handler]rh#70 Received request from '%s' to cancel reservation '%s'", h.RemoteAddr, reservationID))
 
 	userID, err := r.profile.GetUserId(h.Context(), username, tokenStr)
 	if err != nil {
@@ -442,17 +616,14 @@ func (r *ReservationHandler) DeleteReservation(rw http.ResponseWriter, h *http.R
 		return
 	}
 
-	// Get reservation
-	reservation, err := r.repo.FindReservationByIdAndAvailablePeriod(reservationID, periodID)
-	if err != nil {
-		log.Error(fmt.Sprintf("[rese-handler]rh#41 Error while finding reservation by id and period: %v", err))
-		http.Error(rw, "failed to get reservation by ID", http.StatusInternalServerError)
+	reservation, err := r.repo.CancelReservationByGuest(reservationID, periodID, userID)
+	if errors.Is(err, data.ErrInvalidStatusTransition) || errors.Is(err, data.ErrStatusChanged) {
+		log.Warning(fmt.Sprintf("[rese-handler]rh#41 Reservation '%s' can't be cancelled: %v", reservationID, err))
+		http.Error(rw, err.Error(), http.StatusConflict)
 		return
 	}
-
-	err = r.repo.DeleteReservationByIdAndAvailablePeriodID(reservationID, periodID, userID)
 	if err != nil {
-		log.Error(fmt.Sprintf("[rese-handler]rh#42 Error while reading host id from usenrame: %v", err))
+		log.Error(fmt.Sprintf("[rese-handler]rh#42 Error while cancelling reservation: %v", err))
 		rw.WriteHeader(http.StatusNotFound)
 		return
 	}
@@ -478,11 +649,15 @@ func (r *ReservationHandler) DeleteReservation(rw http.ResponseWriter, h *http.R
 
 	// Notify host
 	notification := data.Notification{
-		HostID:       host.ID,
-		HostUsername: host.Username,
-		HostEmail:    host.Email,
-		Text:         fmt.Sprintf("Reservation from %s to %s deleted by user %s", startDate, endDate, username),
-		Time:         time.Now(),
+		HostID:            host.ID,
+		HostUsername:      host.Username,
+		HostEmail:         host.Email,
+		Text:              fmt.Sprintf("Reservation from %s to %s cancelled by user %s", startDate, endDate, username),
+		Intent:            data.IntentReservationDeleted,
+		Time:              time.Now(),
+		RecipientID:       host.ID,
+		RecipientUsername: host.Username,
+		RecipientEmail:    host.Email,
 	}
 
 	notified, err := r.notification.NotifyReservation(h.Context(), notification, tokenStr)
@@ -491,11 +666,359 @@ func (r *ReservationHandler) DeleteReservation(rw http.ResponseWriter, h *http.R
 		http.Error(rw, "failed to notify host", http.StatusInternalServerError)
 		return
 	}
-	log.Info(fmt.Sprintf("[rese-handler]rh#58 Successfully deleted reservation '%s'", reservationID))
+	log.Info(fmt.Sprintf("[rese-handler]rh#58 Successfully cancelled reservation '%s'", reservationID))
 
 	rw.WriteHeader(http.StatusAccepted)
 }
 
+// Moves reservation to a status set by host (cancelled_by_host, completed, no_show), only host of the
+// reserved accommodation can do it. Pending requests are confirmed through AcceptReservationRequest.
+func (r *ReservationHandler) ChangeReservationStatus(rw http.ResponseWriter, h *http.Request) {
+	vars := mux.Vars(h)
+	periodID := vars["periodID"]
+	reservationID := vars["reservationID"]
+	tokenStr := r.extractTokenFromHeader(h)
+	username, err := r.getUsername(tokenStr)
+	if err != nil {
+		log.Warning(fmt.Sprintf("[rese-handler]rh#74 Error while reading username from token: %v", err))
+		http.Error(rw, FailedToReadUsernameFromToken, http.StatusBadRequest)
+		return
+	}
+
+	log.Info(fmt.Sprintf("[rese-handler]rh#75 Received request from '%s' to change status of reservation '%s'", h.RemoteAddr, reservationID))
+
+	var change data.StatusChange
+	if err := json.NewDecoder(h.Body).Decode(&change); err != nil {
+		log.Error(fmt.Sprintf("[rese-handler]rh#76 Error while decoding status change: %v", err))
+		http.Error(rw, UnableToDecodeJson, http.StatusBadRequest)
+		return
+	}
+	if !change.Status.IsHostStatus() {
+		http.Error(rw, fmt.Sprintf("Host can't change reservation status to '%s'", change.Status), http.StatusBadRequest)
+		return
+	}
+
+	userID, err := r.profile.GetUserId(h.Context(), username, tokenStr)
+	if err != nil {
+		log.Error(fmt.Sprintf("[rese-handler]rh#77 Error while getting hostId for username: %v", err))
+		http.Error(rw, FailedToGetHostIDFromUsername, http.StatusBadRequest)
+		return
+	}
+
+	reservation, err := r.repo.FindReservationByIdAndAvailablePeriod(reservationID, periodID)
+	if err != nil {
+		log.Error(fmt.Sprintf("[rese-handler]rh#78 Error while finding reservation by id and period: %v", err))
+		http.Error(rw, "Reservation not found", http.StatusNotFound)
+		return
+	}
+
+	accommodation, err := r.accommodation.GetAccommodationByID(h.Context(), reservation.IDAccommodation, tokenStr)
+	if accommodationNotFound(err) {
+		http.Error(rw, "Accommodation not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Error(fmt.Sprintf("[rese-handler]rh#79 Error while finding accommodation by id: %v", err))
+		http.Error(rw, "failed to get accommodation by ID", http.StatusInternalServerError)
+		return
+	}
+	if accommodation.HostID.Hex() != userID {
+		http.Error(rw, "You are not the host of reserved accommodation", http.StatusForbidden)
+		return
+	}
+
+	if err := reservation.ValidateTransition(change.Status); err != nil {
+		if errors.Is(err, data.ErrInvalidStatusTransition) {
+			http.Error(rw, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = r.repo.ChangeReservationStatus(reservation, change.Status, userID)
+	if errors.Is(err, data.ErrStatusChanged) {
+		http.Error(rw, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Error(fmt.Sprintf("[rese-handler]rh#80 Error while changing status of reservation '%s': %v", reservationID, err))
+		http.Error(rw, "Failed to change reservation status", http.StatusInternalServerError)
+		return
+	}
+
+	log.Info(fmt.Sprintf("[rese-handler]rh#81 Reservation '%s' is now %s", reservationID, change.Status))
+
+	rw.WriteHeader(http.StatusOK)
+	if err := reservation.ToJSON(rw); err != nil {
+		log.Error(fmt.Sprintf("[rese-handler]rh#82 Error while encoding reservation: %v", err))
+	}
+}
+
+func (r *ReservationHandler) AcceptReservationRequest(rw http.ResponseWriter, h *http.Request) {
+	r.answerReservationRequest(rw, h, data.StatusConfirmed)
+}
+
+func (r *ReservationHandler) DeclineReservationRequest(rw http.ResponseWriter, h *http.Request) {
+	r.answerReservationRequest(rw, h, data.StatusDeclined)
+}
+
+// Moves pending reservation request to target status on behalf of the host and notifies the guest
+func (r *ReservationHandler) answerReservationRequest(rw http.ResponseWriter, h *http.Request, target data.ReservationStatus) {
+	vars := mux.Vars(h)
+	periodID := vars["periodID"]
+	reservationID := vars["reservationID"]
+	tokenStr := r.extractTokenFromHeader(h)
+	username, err := r.getUsername(tokenStr)
+	if err != nil {
+		log.Warning(fmt.Sprintf("[rese-handler]rh#85 Error while reading username from token: %v", err))
+		http.Error(rw, FailedToReadUsernameFromToken, http.StatusBadRequest)
+		return
+	}
+
+	log.Info(fmt.Sprintf("[rese-handler]rh#86 Received request from '%s' to answer reservation request '%s'", h.RemoteAddr, reservationID))
+
+	userID, err := r.profile.GetUserId(h.Context(), username, tokenStr)
+	if err != nil {
+		log.Error(fmt.Sprintf("[rese-handler]rh#87 Error while getting hostId for username: %v", err))
+		http.Error(rw, FailedToGetHostIDFromUsername, http.StatusBadRequest)
+		return
+	}
+
+	reservation, err := r.repo.FindReservationByIdAndAvailablePeriod(reservationID, periodID)
+	if err != nil {
+		log.Error(fmt.Sprintf("[rese-handler]rh#88 Error while finding reservation by id and period: %v", err))
+		http.Error(rw, "Reservation not found", http.StatusNotFound)
+		return
+	}
+
+	accommodation, err := r.accommodation.GetAccommodationByID(h.Context(), reservation.IDAccommodation, tokenStr)
+	if accommodationNotFound(err) {
+		http.Error(rw, "Accommodation not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Error(fmt.Sprintf("[rese-handler]rh#89 Error while finding accommodation by id: %v", err))
+		http.Error(rw, "failed to get accommodation by ID", http.StatusInternalServerError)
+		return
+	}
+	if accommodation.HostID.Hex() != userID {
+		http.Error(rw, "You are not the host of reserved accommodation", http.StatusForbidden)
+		return
+	}
+
+	if reservation.CurrentStatus() != data.StatusPending {
+		http.Error(rw, "Reservation is not waiting for an answer", http.StatusConflict)
+		return
+	}
+	// Requests not yet picked up by the expirer can't be answered anymore either
+	if time.Now().After(reservation.ExpiresAt) {
+		http.Error(rw, "Reservation request has expired", http.StatusConflict)
+		return
+	}
+
+	request, err := r.repo.FindReservationRequest(reservation.ID)
+	if err != nil {
+		log.Error(fmt.Sprintf("[rese-handler]rh#90 Error while finding reservation request: %v", err))
+		http.Error(rw, "Failed to find reservation request", http.StatusInternalServerError)
+		return
+	}
+
+	err = r.repo.ChangeReservationStatus(reservation, target, userID)
+	if errors.Is(err, data.ErrStatusChanged) || errors.Is(err, data.ErrInvalidStatusTransition) {
+		http.Error(rw, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Error(fmt.Sprintf("[rese-handler]rh#91 Error while changing status of reservation '%s': %v", reservationID, err))
+		http.Error(rw, "Failed to answer reservation request", http.StatusInternalServerError)
+		return
+	}
+
+	answer, intent := "accepted", data.IntentRequestAccepted
+	if target == data.StatusDeclined {
+		answer, intent = "declined", data.IntentRequestDeclined
+	}
+	r.notifyGuestOfRequest(h.Context(), reservation, request, answer, intent, tokenStr)
+
+	log.Info(fmt.Sprintf("[rese-handler]rh#92 Reservation request '%s' was %s", reservationID, answer))
+
+	rw.WriteHeader(http.StatusOK)
+	if err := reservation.ToJSON(rw); err != nil {
+		log.Error(fmt.Sprintf("[rese-handler]rh#93 Error while encoding reservation: %v", err))
+	}
+}
+
+// Notifies guest that their reservation request was accepted, declined or has expired.
+// The outcome is already stored, so failing to notify is only logged.
+func (r *ReservationHandler) notifyGuestOfRequest(ctx context.Context, reservation *data.ReservationByAvailablePeriod, request *data.ReservationRequest, outcome, intent, token string) {
+	if request.GuestUsername == "" {
+		return
+	}
+
+	notification := data.Notification{
+		RecipientID:       reservation.IDUser,
+		RecipientUsername: request.GuestUsername,
+		RecipientEmail:    request.GuestEmail,
+		Text:              fmt.Sprintf("Your reservation request for %s was %s", request.AccommodationName, outcome),
+		Intent:            intent,
+		Time:              time.Now(),
+	}
+	if notified, err := r.notification.NotifyReservation(ctx, notification, token); !notified {
+		log.Error(fmt.Sprintf("[rese-handler]rh#94 Error while trying to notify guest '%s': %v", request.GuestUsername, err))
+	}
+}
+
+// Expires reservation requests the host didn't answer in time, every interval until ctx is done
+func (r *ReservationHandler) RunRequestExpirer(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.expireReservationRequests(ctx)
+		}
+	}
+}
+
+func (r *ReservationHandler) expireReservationRequests(ctx context.Context) {
+	requests, err := r.repo.FindExpiredReservationRequests(time.Now())
+	if err != nil {
+		log.Error(fmt.Sprintf("[rese-handler]rh#95 Error while finding expired reservation requests: %v", err))
+		return
+	}
+
+	for _, request := range requests {
+		reservation, err := r.repo.FindReservationByIdAndAvailablePeriod(request.ID.String(), request.IDAvailablePeriod.String())
+		if err != nil {
+			log.Error(fmt.Sprintf("[rese-handler]rh#96 Error while finding reservation of request '%s': %v", request.ID, err))
+			continue
+		}
+
+		err = r.repo.ChangeReservationStatus(reservation, data.StatusExpired, data.SystemActor)
+		if errors.Is(err, data.ErrStatusChanged) || errors.Is(err, data.ErrInvalidStatusTransition) {
+			// Host answered the request in the meantime
+			continue
+		}
+		if err != nil {
+			log.Error(fmt.Sprintf("[rese-handler]rh#97 Error while expiring reservation request '%s': %v", request.ID, err))
+			continue
+		}
+
+		// Notification service doesn't require a token
+		r.notifyGuestOfRequest(ctx, reservation, request, "expired", data.IntentRequestExpired, "")
+
+		log.Info(fmt.Sprintf("[rese-handler]rh#98 Reservation request '%s' has expired", request.ID))
+	}
+}
+
+// Completes status changes whose follow-up writes failed, every interval until ctx is done
+func (r *ReservationHandler) RunStatusSync(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			synced, err := r.repo.SyncReservationStatusChanges(time.Now())
+			if err != nil {
+				log.Error(fmt.Sprintf("[rese-handler]rh#125 Error while syncing reservation status changes: %v", err))
+				continue
+			}
+			if synced > 0 {
+				log.Info(fmt.Sprintf("[rese-handler]rh#126 Synced %d reservation status changes", synced))
+			}
+		}
+	}
+}
+
+func (r *ReservationHandler) GetPricingRules(rw http.ResponseWriter, h *http.Request) {
+	accommodationID := mux.Vars(h)["id"]
+
+	log.Info(fmt.Sprintf("[rese-handler]rh#108 Received request from '%s' for pricing rules of accommodation '%s'", h.RemoteAddr, accommodationID))
+
+	rules, err := r.repo.FindPricingRules(accommodationID)
+	if err != nil {
+		log.Error(fmt.Sprintf("[rese-handler]rh#109 Error while finding pricing rules: %v", err))
+		http.Error(rw, "Failed to get pricing rules", http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(rw).Encode(rules); err != nil {
+		log.Error(fmt.Sprintf("[rese-handler]rh#110 Error while encoding pricing rules: %v", err))
+		http.Error(rw, UnableToConvertToJson, http.StatusInternalServerError)
+	}
+}
+
+// Replaces pricing rules of the accommodation, only its host can change them
+func (r *ReservationHandler) UpdatePricingRules(rw http.ResponseWriter, h *http.Request) {
+	accommodationID := mux.Vars(h)["id"]
+	tokenStr := r.extractTokenFromHeader(h)
+	username, err := r.getUsername(tokenStr)
+	if err != nil {
+		log.Warning(fmt.Sprintf("[rese-handler]rh#111 Error while reading username from token: %v", err))
+		http.Error(rw, FailedToReadUsernameFromToken, http.StatusBadRequest)
+		return
+	}
+
+	log.Info(fmt.Sprintf("[rese-handler]rh#112 Received request from '%s' to update pricing rules of accommodation '%s'", h.RemoteAddr, accommodationID))
+
+	var rules pricing.Rules
+	if err := json.NewDecoder(h.Body).Decode(&rules); err != nil {
+		log.Error(fmt.Sprintf("[rese-handler]rh#113 Error while decoding pricing rules: %v", err))
+		http.Error(rw, UnableToDecodeJson, http.StatusBadRequest)
+		return
+	}
+	if err := rules.Validate(); err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID, err := r.profile.GetUserId(h.Context(), username, tokenStr)
+	if err != nil {
+		log.Error(fmt.Sprintf("[rese-handler]rh#114 Error while getting hostId for username: %v", err))
+		http.Error(rw, FailedToGetHostIDFromUsername, http.StatusBadRequest)
+		return
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(accommodationID)
+	if err != nil {
+		http.Error(rw, "Invalid accommodation ID", http.StatusBadRequest)
+		return
+	}
+	accommodation, err := r.accommodation.GetAccommodationByID(h.Context(), objectID, tokenStr)
+	if accommodationNotFound(err) {
+		http.Error(rw, "Accommodation not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Error(fmt.Sprintf("[rese-handler]rh#115 Error while finding accommodation by id: %v", err))
+		http.Error(rw, "failed to get accommodation by ID", http.StatusInternalServerError)
+		return
+	}
+	if accommodation.HostID.Hex() != userID {
+		http.Error(rw, "You are not the host of the accommodation", http.StatusForbidden)
+		return
+	}
+
+	if err := r.repo.ReplacePricingRules(accommodationID, rules); err != nil {
+		log.Error(fmt.Sprintf("[rese-handler]rh#116 Error while saving pricing rules: %v", err))
+		http.Error(rw, "Failed to save pricing rules", http.StatusInternalServerError)
+		return
+	}
+
+	log.Info(fmt.Sprintf("[rese-handler]rh#117 Host id:'%s' updated pricing rules of accommodation '%s'", userID, accommodationID))
+
+	if err := json.NewEncoder(rw).Encode(rules); err != nil {
+		log.Error(fmt.Sprintf("[rese-handler]rh#118 Error while encoding pricing rules: %v", err))
+	}
+}
+
 func (r *ReservationHandler) MiddlewareAvailablePeriodDeserialization(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(rw http.ResponseWriter, h *http.Request) {
 		availablePeriod := &data.AvailablePeriodByAccommodation{}
@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"reservation/domain"
 )
 
 func WriteResp(resp any, statusCode int, w http.ResponseWriter) {
@@ -18,3 +20,10 @@ func WriteResp(resp any, statusCode int, w http.ResponseWriter) {
 	w.Header().Add("Content-Type", "application/json")
 	w.Write(respBytes)
 }
+
+// Accommodation service doesn't find accommodations which were deleted, nor those the user can't see,
+// e.g. paused ones when the user isn't their host
+func accommodationNotFound(err error) bool {
+	var errResp domain.ErrResp
+	return errors.As(err, &errResp) && errResp.StatusCode == http.StatusNotFound
+}
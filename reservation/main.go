@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"net/http"
@@ -12,6 +13,7 @@ import (
 	"strconv"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"gopkg.in/natefinch/lumberjack.v2"
 
 	"reservation/clients"
@@ -28,6 +30,12 @@ import (
 )
 
 func main() {
+	// One-off maintenance, copies reservations stored before lookup tables were introduced and exits
+	backfill := flag.Bool("backfill-reservation-lookups", false, "copy existing reservations to reservations_by_user and reservations_by_accommodation, then exit")
+	// One-off maintenance, reserves nights of upcoming reservations made before nights were reserved with them and exits
+	reserveNights := flag.Bool("reserve-upcoming-nights", false, "reserve nights of upcoming reservations made before nights were reserved, then exit")
+	flag.Parse()
+
 	//Reading from environment, if not set we will default it to 8080.
 	//This allows flexibility in different environments (for eg. when running multiple docker api's and want to override the default port)
 	port := os.Getenv("PORT")
@@ -110,6 +118,15 @@ func main() {
 		log.Fatal(fmt.Sprintf("[rese-service]rs#7 Failed to create Cassandra tables: %v", err))
 	}
 
+	if *backfill {
+		copied, err := store.BackfillReservationLookupTables()
+		if err != nil {
+			log.Fatal(fmt.Sprintf("[rese-service]rs#20 Failed to backfill reservation lookup tables: %v", err))
+		}
+		log.Info(fmt.Sprintf("[rese-service]rs#21 Copied %d reservations to reservation lookup tables", copied))
+		return
+	}
+
 	defer store.CloseSession()
 
 	notificationClient := &http.Client{
@@ -206,8 +223,27 @@ func main() {
 	profile := clients.NewProfileClient(profileClient, os.Getenv("PROFILE_SERVICE_URI"), profileBreaker)
 	accommodation := clients.NewAccommodationClient(accommodationClient, os.Getenv("ACCOMMODATION_SERVICE_URI"), accommodationBreaker)
 
+	if *reserveNights {
+		reserved, err := store.ReserveNightsOfUpcomingReservations(func(accommodationID primitive.ObjectID) (int, error) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			return accommodation.GetAccommodationUnits(ctx, accommodationID)
+		})
+		if err != nil {
+			log.Fatal(fmt.Sprintf("[rese-service]rs#19 Failed to reserve nights of upcoming reservations, %d hold their nights: %v", reserved, err))
+		}
+		log.Info(fmt.Sprintf("[rese-service]rs#22 %d upcoming reservations hold their nights", reserved))
+		return
+	}
+
+	// Quote tokens carry the price guests are charged, so they must never be signed with a known key
+	quoteSecret := os.Getenv("QUOTE_SECRET")
+	if quoteSecret == "" {
+		log.Fatal("[rese-service]rs#23 QUOTE_SECRET is not set")
+	}
+
 	//Initialize the handler and inject said logger
-	reservationHandler := handlers.NewReservationHandler(store, notification, profile, accommodation)
+	reservationHandler := handlers.NewReservationHandler(store, notification, profile, accommodation, []byte(quoteSecret))
 
 	//Initialize the router and add a middleware for all the requests
 	router := mux.NewRouter()
@@ -225,6 +261,14 @@ func main() {
 	findAccommodationIdsByDates.HandleFunc("", reservationHandler.FindAccommodationIdsByDates)
 	findAccommodationIdsByDates.Use(reservationHandler.MiddlewareDatesDeserialization)
 
+	getPricingRulesRouter := router.Methods(http.MethodGet).Path("/{id}/pricing-rules").Subrouter()
+	getPricingRulesRouter.HandleFunc("", reservationHandler.GetPricingRules)
+	getPricingRulesRouter.Use(reservationHandler.AuthorizeRoles("HOST", "GUEST"))
+
+	updatePricingRulesRouter := router.Methods(http.MethodPut).Path("/{id}/pricing-rules").Subrouter()
+	updatePricingRulesRouter.HandleFunc("", reservationHandler.UpdatePricingRules)
+	updatePricingRulesRouter.Use(reservationHandler.AuthorizeRoles("HOST"))
+
 	findAvailablePeriodByIdAndByAccommodationId := router.Methods(http.MethodGet).Path("/{accommodationID}/{periodID}").Subrouter()
 	findAvailablePeriodByIdAndByAccommodationId.HandleFunc("", reservationHandler.FindAvailablePeriodByIdAndByAccommodationId)
 	findAvailablePeriodByIdAndByAccommodationId.Use(reservationHandler.AuthorizeRoles("HOST", "GUEST"))
@@ -242,6 +286,16 @@ func main() {
 	postReservationRouter.Use(reservationHandler.AuthorizeRoles("GUEST"))
 	postReservationRouter.Use(reservationHandler.MiddlewareReservationDeserialization)
 
+	quoteReservationRouter := router.Methods(http.MethodPost).Path("/quote").Subrouter()
+	quoteReservationRouter.HandleFunc("", reservationHandler.QuoteReservation)
+	quoteReservationRouter.Use(reservationHandler.AuthorizeRoles("GUEST"))
+	quoteReservationRouter.Use(reservationHandler.MiddlewareReservationDeserialization)
+
+	holdDatesRouter := router.Methods(http.MethodPost).Path("/hold").Subrouter()
+	holdDatesRouter.HandleFunc("", reservationHandler.HoldDates)
+	holdDatesRouter.Use(reservationHandler.AuthorizeRoles("GUEST"))
+	holdDatesRouter.Use(reservationHandler.MiddlewareReservationDeserialization)
+
 	updateAvailablePeriodsByAccommodationRouter := router.Methods(http.MethodPatch).Path("/period").Subrouter()
 	updateAvailablePeriodsByAccommodationRouter.HandleFunc("", reservationHandler.UpdateAvailablePeriodByAccommodation)
 	updateAvailablePeriodsByAccommodationRouter.Use(reservationHandler.MiddlewareAvailablePeriodDeserialization)
@@ -251,6 +305,18 @@ func main() {
 	deleteReservation.HandleFunc("", reservationHandler.DeleteReservation)
 	deleteReservation.Use(reservationHandler.AuthorizeRoles("GUEST"))
 
+	changeReservationStatusRouter := router.Methods(http.MethodPatch).Path("/{periodID}/{reservationID}/status").Subrouter()
+	changeReservationStatusRouter.HandleFunc("", reservationHandler.ChangeReservationStatus)
+	changeReservationStatusRouter.Use(reservationHandler.AuthorizeRoles("HOST"))
+
+	acceptReservationRequestRouter := router.Methods(http.MethodPost).Path("/{periodID}/{reservationID}/accept").Subrouter()
+	acceptReservationRequestRouter.HandleFunc("", reservationHandler.AcceptReservationRequest)
+	acceptReservationRequestRouter.Use(reservationHandler.AuthorizeRoles("HOST"))
+
+	declineReservationRequestRouter := router.Methods(http.MethodPost).Path("/{periodID}/{reservationID}/decline").Subrouter()
+	declineReservationRequestRouter.HandleFunc("", reservationHandler.DeclineReservationRequest)
+	declineReservationRequestRouter.Use(reservationHandler.AuthorizeRoles("HOST"))
+
 	deletePeriodsByAccommodationRouter := router.Methods(http.MethodPost).Path("/check-acc").Subrouter()
 	deletePeriodsByAccommodationRouter.HandleFunc("", reservationHandler.DeletePeriodsForAccommodations)
 	deletePeriodsByAccommodationRouter.Use(reservationHandler.AuthorizeRoles("HOST"))
@@ -284,6 +350,13 @@ func main() {
 		}
 	}()
 
+	// Expire reservation requests hosts didn't answer in time
+	jobsContext, cancelJobs := context.WithCancel(context.Background())
+	defer cancelJobs()
+	go reservationHandler.RunRequestExpirer(jobsContext, time.Minute)
+	// Complete status changes whose lookup tables, history or reserved nights weren't updated
+	go reservationHandler.RunStatusSync(jobsContext, time.Minute)
+
 	// Protecting logs from unauthorized access and modification
 	dirPath := "/logger/logs"
 	err = protectLogs(dirPath)
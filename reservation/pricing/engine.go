@@ -0,0 +1,164 @@
+package pricing
+
+import (
+	"math"
+	"time"
+)
+
+// Stay being priced
+type Stay struct {
+	StartDate time.Time
+	EndDate   time.Time
+	Guests    int
+	// Nightly price of the available period, used on nights no weekday or season rule prices
+	BasePrice     float64
+	PricePerGuest bool
+	BookedAt      time.Time
+}
+
+// Price of one night of the stay, with names of rules that set or changed it
+type NightPrice struct {
+	Night time.Time `json:"night"`
+	Price float64   `json:"price"`
+	Rules []string  `json:"rules,omitempty"`
+}
+
+// Change of the whole stay price made by length of stay, last-minute or early-bird rule
+type Adjustment struct {
+	Rule    string   `json:"rule"`
+	Kind    RuleKind `json:"kind"`
+	Percent float64  `json:"percent"`
+	Amount  float64  `json:"amount"`
+}
+
+// Amount charged once per stay by a fee rule
+type Fee struct {
+	Name   string  `json:"name"`
+	Amount float64 `json:"amount"`
+}
+
+// Itemized price of a stay
+type Breakdown struct {
+	Nights      []NightPrice `json:"nights"`
+	Subtotal    float64      `json:"subtotal"`
+	Adjustments []Adjustment `json:"adjustments,omitempty"`
+	Fees        []Fee        `json:"fees,omitempty"`
+	Total       float64      `json:"total"`
+}
+
+// Prices stay night by night and applies stay-wide adjustments to the sum of nightly prices.
+// Nightly price is the price of the latest starting season covering the night, otherwise of the
+// weekday rule matching it, otherwise the base price. Holiday surcharges are added on top.
+// Of each stay-wide kind only the most specific matching rule applies. Fees are added last.
+func Evaluate(stay Stay, rules Rules) Breakdown {
+	var breakdown Breakdown
+	for night := stay.StartDate; night.Before(stay.EndDate); night = night.Add(24 * time.Hour) {
+		price := priceNight(night, stay, rules)
+		breakdown.Nights = append(breakdown.Nights, price)
+		breakdown.Subtotal += price.Price
+	}
+	breakdown.Subtotal = round(breakdown.Subtotal)
+
+	breakdown.Total = breakdown.Subtotal
+	for _, rule := range stayRules(stay, len(breakdown.Nights), rules) {
+		adjustment := Adjustment{
+			Rule:    rule.label(),
+			Kind:    rule.Kind,
+			Percent: rule.Percent,
+			Amount:  round(breakdown.Subtotal * rule.Percent / 100),
+		}
+		breakdown.Adjustments = append(breakdown.Adjustments, adjustment)
+		breakdown.Total += adjustment.Amount
+	}
+	breakdown.Total = math.Max(breakdown.Total, 0)
+
+	for i := range rules {
+		if rules[i].Kind == KindFee {
+			fee := Fee{Name: rules[i].label(), Amount: round(rules[i].Price)}
+			breakdown.Fees = append(breakdown.Fees, fee)
+			breakdown.Total += fee.Amount
+		}
+	}
+	breakdown.Total = round(breakdown.Total)
+
+	return breakdown
+}
+
+func priceNight(night time.Time, stay Stay, rules Rules) NightPrice {
+	price := NightPrice{Night: night, Price: stay.BasePrice}
+
+	var season, weekday *Rule
+	for i := range rules {
+		rule := &rules[i]
+		switch rule.Kind {
+		case KindSeason:
+			if rule.covers(night) && (season == nil || rule.StartDate.After(season.StartDate)) {
+				season = rule
+			}
+		case KindWeekday:
+			if weekday == nil && rule.onWeekday(night) {
+				weekday = rule
+			}
+		}
+	}
+	if season != nil {
+		price.Price = season.Price
+		price.Rules = append(price.Rules, season.label())
+	} else if weekday != nil {
+		price.Price = weekday.Price
+		price.Rules = append(price.Rules, weekday.label())
+	}
+
+	nightly := price.Price
+	for i := range rules {
+		rule := &rules[i]
+		if rule.Kind == KindHoliday && rule.covers(night) {
+			price.Price += nightly * rule.Percent / 100
+			price.Rules = append(price.Rules, rule.label())
+		}
+	}
+
+	if stay.PricePerGuest {
+		price.Price *= float64(stay.Guests)
+	}
+	price.Price = round(price.Price)
+
+	return price
+}
+
+// Returns the stay-wide rules applying to the stay, at most one of each kind
+func stayRules(stay Stay, nights int, rules Rules) []*Rule {
+	daysBefore := int(stay.StartDate.Sub(stay.BookedAt).Hours() / 24)
+
+	var lengthOfStay, lastMinute, earlyBird *Rule
+	for i := range rules {
+		rule := &rules[i]
+		switch rule.Kind {
+		case KindLengthOfStay:
+			if nights >= rule.MinNights && (lengthOfStay == nil || rule.MinNights > lengthOfStay.MinNights) {
+				lengthOfStay = rule
+			}
+		case KindLastMinute:
+			if daysBefore <= rule.DaysBefore && (lastMinute == nil || rule.DaysBefore < lastMinute.DaysBefore) {
+				lastMinute = rule
+			}
+		case KindEarlyBird:
+			if daysBefore >= rule.DaysBefore && (earlyBird == nil || rule.DaysBefore > earlyBird.DaysBefore) {
+				earlyBird = rule
+			}
+		}
+	}
+
+	var applied []*Rule
+	for _, rule := range []*Rule{lengthOfStay, lastMinute, earlyBird} {
+		if rule != nil {
+			applied = append(applied, rule)
+		}
+	}
+	return applied
+}
+
+// Rounds amount to cents
+func round(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,242 @@
+package pricing
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Day of July 2030, the 1st is a Monday
+func day(d int) time.Time {
+	return time.Date(2030, 7, d, 0, 0, 0, 0, time.UTC)
+}
+
+func weekend(price float64) Rule {
+	return Rule{Kind: KindWeekday, Name: "weekend", Price: price, Weekdays: []time.Weekday{time.Friday, time.Saturday}}
+}
+
+func season(name string, price float64, start, end int) Rule {
+	return Rule{Kind: KindSeason, Name: name, Price: price, StartDate: day(start), EndDate: day(end)}
+}
+
+func holiday(name string, percent float64, start, end int) Rule {
+	return Rule{Kind: KindHoliday, Name: name, Percent: percent, StartDate: day(start), EndDate: day(end)}
+}
+
+func TestEvaluateNightlyPrices(t *testing.T) {
+	tests := []struct {
+		name     string
+		stay     Stay
+		rules    Rules
+		want     []float64
+		subtotal float64
+	}{
+		{
+			name:     "base price without rules",
+			stay:     Stay{StartDate: day(5), EndDate: day(7), Guests: 2, BasePrice: 100},
+			want:     []float64{100, 100},
+			subtotal: 200,
+		},
+		{
+			name:     "weekend rule prices friday and saturday",
+			stay:     Stay{StartDate: day(4), EndDate: day(7), Guests: 2, BasePrice: 100},
+			rules:    Rules{weekend(150)},
+			want:     []float64{100, 150, 150},
+			subtotal: 400,
+		},
+		{
+			name:     "season overrides weekend",
+			stay:     Stay{StartDate: day(4), EndDate: day(7), Guests: 2, BasePrice: 100},
+			rules:    Rules{season("peak", 200, 6, 6), weekend(150)},
+			want:     []float64{100, 150, 200},
+			subtotal: 450,
+		},
+		{
+			name:     "latest starting season wins",
+			stay:     Stay{StartDate: day(4), EndDate: day(7), Guests: 2, BasePrice: 100},
+			rules:    Rules{season("short", 180, 5, 6), season("summer", 120, 1, 31)},
+			want:     []float64{120, 180, 180},
+			subtotal: 480,
+		},
+		{
+			name:     "holidays stack additively on nightly price",
+			stay:     Stay{StartDate: day(4), EndDate: day(6), Guests: 2, BasePrice: 100},
+			rules:    Rules{season("peak", 200, 5, 5), holiday("festival", 10, 5, 5), holiday("fireworks", 20, 4, 5)},
+			want:     []float64{120, 260},
+			subtotal: 380,
+		},
+		{
+			name:     "price per guest multiplies nightly price with surcharges",
+			stay:     Stay{StartDate: day(1), EndDate: day(3), Guests: 3, BasePrice: 100, PricePerGuest: true},
+			rules:    Rules{holiday("festival", 10, 2, 2)},
+			want:     []float64{300, 330},
+			subtotal: 630,
+		},
+		{
+			name:     "nightly prices are rounded to cents",
+			stay:     Stay{StartDate: day(1), EndDate: day(4), Guests: 1, BasePrice: 33.333},
+			rules:    Rules{holiday("festival", 12.5, 3, 3)},
+			want:     []float64{33.33, 33.33, 37.5},
+			subtotal: 104.16,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breakdown := Evaluate(tt.stay, tt.rules)
+
+			var got []float64
+			for _, night := range breakdown.Nights {
+				got = append(got, night.Price)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("nightly prices %v, want %v", got, tt.want)
+			}
+			if breakdown.Subtotal != tt.subtotal {
+				t.Errorf("subtotal %v, want %v", breakdown.Subtotal, tt.subtotal)
+			}
+		})
+	}
+}
+
+func TestEvaluateNightRules(t *testing.T) {
+	stay := Stay{StartDate: day(5), EndDate: day(6), Guests: 1, BasePrice: 100}
+	rules := Rules{weekend(150), season("peak", 200, 5, 5), holiday("festival", 10, 5, 5)}
+
+	breakdown := Evaluate(stay, rules)
+
+	want := []string{"peak", "festival"}
+	if len(breakdown.Nights) != 1 || !reflect.DeepEqual(breakdown.Nights[0].Rules, want) {
+		t.Errorf("night rules %+v, want %v", breakdown.Nights, want)
+	}
+}
+
+func TestEvaluateStayAdjustments(t *testing.T) {
+	weekly := Rule{Kind: KindLengthOfStay, Name: "weekly", MinNights: 7, Percent: -10}
+	monthly := Rule{Kind: KindLengthOfStay, Name: "monthly", MinNights: 28, Percent: -20}
+	lastMinute := Rule{Kind: KindLastMinute, Name: "last minute", DaysBefore: 3, Percent: -15}
+	lastWeek := Rule{Kind: KindLastMinute, Name: "last week", DaysBefore: 7, Percent: -5}
+	earlyBird := Rule{Kind: KindEarlyBird, Name: "early bird", DaysBefore: 30, Percent: -5}
+	veryEarly := Rule{Kind: KindEarlyBird, Name: "very early", DaysBefore: 90, Percent: -10}
+	cleaning := Rule{Kind: KindFee, Name: "cleaning", Price: 25}
+
+	stay := func(nights, bookedDaysBefore int, basePrice float64) Stay {
+		start := day(1)
+		return Stay{
+			StartDate: start,
+			EndDate:   start.AddDate(0, 0, nights),
+			Guests:    1,
+			BasePrice: basePrice,
+			BookedAt:  start.AddDate(0, 0, -bookedDaysBefore),
+		}
+	}
+
+	tests := []struct {
+		name        string
+		stay        Stay
+		rules       Rules
+		adjustments []string
+		amounts     []float64
+		total       float64
+	}{
+		{
+			name:  "short stay has no length of stay discount",
+			stay:  stay(5, 10, 100),
+			rules: Rules{weekly, monthly},
+			total: 500,
+		},
+		{
+			name:        "weekly discount below a month",
+			stay:        stay(7, 10, 100),
+			rules:       Rules{monthly, weekly},
+			adjustments: []string{"weekly"},
+			amounts:     []float64{-70},
+			total:       630,
+		},
+		{
+			name:        "monthly discount replaces weekly",
+			stay:        stay(28, 10, 100),
+			rules:       Rules{weekly, monthly},
+			adjustments: []string{"monthly"},
+			amounts:     []float64{-560},
+			total:       2240,
+		},
+		{
+			name:        "closest last minute rule",
+			stay:        stay(2, 2, 100),
+			rules:       Rules{lastWeek, lastMinute, earlyBird},
+			adjustments: []string{"last minute"},
+			amounts:     []float64{-30},
+			total:       170,
+		},
+		{
+			name:        "furthest early bird rule",
+			stay:        stay(2, 100, 100),
+			rules:       Rules{earlyBird, veryEarly, lastMinute},
+			adjustments: []string{"very early"},
+			amounts:     []float64{-20},
+			total:       180,
+		},
+		{
+			name:  "neither last minute nor early bird",
+			stay:  stay(2, 10, 100),
+			rules: Rules{lastWeek, earlyBird},
+			total: 200,
+		},
+		{
+			name:        "length of stay and booking time discounts combine",
+			stay:        stay(7, 40, 100),
+			rules:       Rules{earlyBird, weekly},
+			adjustments: []string{"weekly", "early bird"},
+			amounts:     []float64{-70, -35},
+			total:       595,
+		},
+		{
+			name:        "fees are added after discounts",
+			stay:        stay(2, 2, 100),
+			rules:       Rules{cleaning, lastMinute},
+			adjustments: []string{"last minute"},
+			amounts:     []float64{-30},
+			total:       195,
+		},
+		{
+			name: "discounts don't take total below zero",
+			stay: stay(7, 2, 100),
+			rules: Rules{
+				{Kind: KindLengthOfStay, Name: "weekly", MinNights: 7, Percent: -60},
+				{Kind: KindLastMinute, Name: "last minute", DaysBefore: 3, Percent: -60},
+				cleaning,
+			},
+			adjustments: []string{"weekly", "last minute"},
+			amounts:     []float64{-420, -420},
+			total:       25,
+		},
+		{
+			name:        "adjustments are rounded to cents",
+			stay:        stay(7, 10, 14.29),
+			rules:       Rules{weekly},
+			adjustments: []string{"weekly"},
+			amounts:     []float64{-10},
+			total:       90.03,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breakdown := Evaluate(tt.stay, tt.rules)
+
+			var adjustments []string
+			var amounts []float64
+			for _, adjustment := range breakdown.Adjustments {
+				adjustments = append(adjustments, adjustment.Rule)
+				amounts = append(amounts, adjustment.Amount)
+			}
+			if !reflect.DeepEqual(adjustments, tt.adjustments) || !reflect.DeepEqual(amounts, tt.amounts) {
+				t.Errorf("adjustments %v %v, want %v %v", adjustments, amounts, tt.adjustments, tt.amounts)
+			}
+			if breakdown.Total != tt.total {
+				t.Errorf("total %v, want %v", breakdown.Total, tt.total)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package pricing
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// How long a quoted price can be booked for
+const QuoteDuration = 15 * time.Minute
+
+var ErrInvalidQuote = errors.New("quote is invalid or has expired")
+
+// Price quoted to a guest for a stay. Signed quotes are handed to the guest as tokens,
+// so the quoted price can be charged later without being stored.
+type Quote struct {
+	Username          string    `json:"username"`
+	IDAccommodation   string    `json:"accommodationId"`
+	IDAvailablePeriod string    `json:"availablePeriodId"`
+	StartDate         time.Time `json:"startDate"`
+	EndDate           time.Time `json:"endDate"`
+	Guests            int       `json:"guests"`
+	Breakdown         Breakdown `json:"breakdown"`
+	ExpiresAt         time.Time `json:"expiresAt"`
+}
+
+// Quote along with the token guest reserves the quoted price with
+type SignedQuote struct {
+	Quote
+	Token string `json:"token"`
+}
+
+type quoteClaims struct {
+	Quote
+	jwt.StandardClaims
+}
+
+// Signs quote with key, the token expires at ExpiresAt of the quote
+func SignQuote(quote Quote, key []byte) (*SignedQuote, error) {
+	claims := quoteClaims{
+		Quote:          quote,
+		StandardClaims: jwt.StandardClaims{ExpiresAt: quote.ExpiresAt.Unix()},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SignedQuote{Quote: quote, Token: token}, nil
+}
+
+// Returns quote signed with key, or ErrInvalidQuote if token was not signed with key or has expired
+func ParseQuote(tokenString string, key []byte) (*Quote, error) {
+	var claims quoteClaims
+	token, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		return key, nil
+	})
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidQuote
+	}
+
+	return &claims.Quote, nil
+}
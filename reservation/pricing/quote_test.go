@@ -0,0 +1,103 @@
+package pricing
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testKey = []byte("test-quote-key")
+
+func testQuote(expiresAt time.Time) Quote {
+	start := time.Date(2030, 7, 1, 0, 0, 0, 0, time.UTC)
+	return Quote{
+		Username:          "guest",
+		IDAccommodation:   "655e33ae4b3f315471824211",
+		IDAvailablePeriod: "123e4567-e89b-12d3-a456-426614174022",
+		StartDate:         start,
+		EndDate:           start.Add(48 * time.Hour),
+		Guests:            2,
+		Breakdown:         Breakdown{Subtotal: 200, Total: 200},
+		ExpiresAt:         expiresAt,
+	}
+}
+
+func TestParseQuoteReturnsSignedQuote(t *testing.T) {
+	quote := testQuote(time.Now().Add(QuoteDuration).Truncate(time.Second))
+
+	signed, err := SignQuote(quote, testKey)
+	if err != nil {
+		t.Fatalf("SignQuote: %v", err)
+	}
+	parsed, err := ParseQuote(signed.Token, testKey)
+	if err != nil {
+		t.Fatalf("ParseQuote: %v", err)
+	}
+
+	if parsed.Username != quote.Username || parsed.Breakdown.Total != quote.Breakdown.Total ||
+		!parsed.StartDate.Equal(quote.StartDate) || !parsed.EndDate.Equal(quote.EndDate) || parsed.Guests != quote.Guests {
+		t.Errorf("parsed quote %+v, want %+v", parsed, quote)
+	}
+}
+
+func TestParseQuoteRejectsInvalidTokens(t *testing.T) {
+	signed, err := SignQuote(testQuote(time.Now().Add(QuoteDuration)), testKey)
+	if err != nil {
+		t.Fatalf("SignQuote: %v", err)
+	}
+	expired, err := SignQuote(testQuote(time.Now().Add(-time.Minute)), testKey)
+	if err != nil {
+		t.Fatalf("SignQuote: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		key   []byte
+	}{
+		{"tampered total", tamperTotal(t, signed.Token), testKey},
+		{"signed with another key", signed.Token, []byte("another-key")},
+		{"expired", expired.Token, testKey},
+		{"unsigned", unsigned(signed.Token), testKey},
+		{"malformed", "not-a-token", testKey},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := ParseQuote(test.token, test.key); !errors.Is(err, ErrInvalidQuote) {
+				t.Errorf("ParseQuote error = %v, want ErrInvalidQuote", err)
+			}
+		})
+	}
+}
+
+// Rewrites the total in the payload of token, keeping the original signature
+func tamperTotal(t *testing.T, token string) string {
+	parts := strings.Split(token, ".")
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatalf("unmarshal payload: %v", err)
+	}
+	claims["breakdown"].(map[string]interface{})["total"] = 0.01
+
+	payload, err = json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("marshal payload: %v", err)
+	}
+	parts[1] = base64.RawURLEncoding.EncodeToString(payload)
+	return strings.Join(parts, ".")
+}
+
+// Replaces the signing algorithm of token with none and drops the signature
+func unsigned(token string) string {
+	parts := strings.Split(token, ".")
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+	return header + "." + parts[1] + "."
+}
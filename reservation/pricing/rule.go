@@ -0,0 +1,130 @@
+package pricing
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// RuleKind decides how a pricing rule changes the price of a stay
+type RuleKind string
+
+const (
+	// Nightly price on given days of the week, e.g. weekend price
+	KindWeekday RuleKind = "weekday"
+	// Nightly price within a date range, overrides weekday prices
+	KindSeason RuleKind = "season"
+	// Percent added to nightly price within a date range
+	KindHoliday RuleKind = "holiday"
+	// Percent added to stays of at least MinNights nights, negative for weekly and monthly discounts
+	KindLengthOfStay RuleKind = "length_of_stay"
+	// Percent added to stays booked at most DaysBefore days before check-in
+	KindLastMinute RuleKind = "last_minute"
+	// Percent added to stays booked at least DaysBefore days before check-in
+	KindEarlyBird RuleKind = "early_bird"
+	// Fixed amount charged once per stay, e.g. cleaning fee
+	KindFee RuleKind = "fee"
+)
+
+var ErrInvalidRule = errors.New("invalid pricing rule")
+
+// Pricing rule of an accommodation. Which fields are used depends on the kind of the rule.
+type Rule struct {
+	Kind RuleKind `json:"kind"`
+	Name string   `json:"name,omitempty"`
+	// Nightly price set by weekday and season rules, amount of fee rules
+	Price float64 `json:"price,omitempty"`
+	// Percent added to the price by all other rules, negative for discounts
+	Percent   float64        `json:"percent,omitempty"`
+	Weekdays  []time.Weekday `json:"weekdays,omitempty"`
+	StartDate time.Time      `json:"startDate,omitempty"`
+	// Last day of the date range, inclusive
+	EndDate    time.Time `json:"endDate,omitempty"`
+	MinNights  int       `json:"minNights,omitempty"`
+	DaysBefore int       `json:"daysBefore,omitempty"`
+}
+
+type Rules []Rule
+
+func (r *Rule) Validate() error {
+	if r.Percent <= -100 {
+		return fmt.Errorf("%w: percent must be above -100", ErrInvalidRule)
+	}
+
+	switch r.Kind {
+	case KindWeekday:
+		if r.Price <= 0 || len(r.Weekdays) == 0 {
+			return fmt.Errorf("%w: weekday rule needs a price and days of the week", ErrInvalidRule)
+		}
+		for _, day := range r.Weekdays {
+			if day < time.Sunday || day > time.Saturday {
+				return fmt.Errorf("%w: unknown day of the week %d", ErrInvalidRule, day)
+			}
+		}
+	case KindSeason:
+		if r.Price <= 0 {
+			return fmt.Errorf("%w: season rule needs a price", ErrInvalidRule)
+		}
+		return r.validateDateRange()
+	case KindHoliday:
+		if r.Percent == 0 {
+			return fmt.Errorf("%w: holiday rule needs a percent", ErrInvalidRule)
+		}
+		return r.validateDateRange()
+	case KindLengthOfStay:
+		if r.MinNights < 2 || r.Percent == 0 {
+			return fmt.Errorf("%w: length of stay rule needs a percent and at least 2 nights", ErrInvalidRule)
+		}
+	case KindLastMinute, KindEarlyBird:
+		if r.DaysBefore < 0 || r.Percent == 0 {
+			return fmt.Errorf("%w: %s rule needs a percent and days before check-in", ErrInvalidRule, r.Kind)
+		}
+	case KindFee:
+		if r.Price <= 0 {
+			return fmt.Errorf("%w: fee rule needs an amount", ErrInvalidRule)
+		}
+	default:
+		return fmt.Errorf("%w: unknown kind '%s'", ErrInvalidRule, r.Kind)
+	}
+
+	return nil
+}
+
+func (r *Rule) validateDateRange() error {
+	if r.StartDate.IsZero() || r.EndDate.IsZero() || r.EndDate.Before(r.StartDate) {
+		return fmt.Errorf("%w: %s rule needs start date before end date", ErrInvalidRule, r.Kind)
+	}
+	return nil
+}
+
+func (r Rules) Validate() error {
+	for i := range r {
+		if err := r[i].Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Name shown in price breakdown
+func (r *Rule) label() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return string(r.Kind)
+}
+
+// Checks if night falls within the date range of the rule, days are compared in UTC
+func (r *Rule) covers(night time.Time) bool {
+	day := night.UTC().Truncate(24 * time.Hour)
+	return !day.Before(r.StartDate.UTC().Truncate(24*time.Hour)) && !day.After(r.EndDate.UTC().Truncate(24*time.Hour))
+}
+
+func (r *Rule) onWeekday(night time.Time) bool {
+	for _, day := range r.Weekdays {
+		if night.UTC().Weekday() == day {
+			return true
+		}
+	}
+	return false
+}